@@ -0,0 +1,66 @@
+package algo
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzEditDistance(f *testing.F) {
+	f.Add("", "")
+	f.Add("kitten", "sitting")
+	f.Add("shingeki no kyojin", "shingeki no kyoujin")
+	f.Add("進撃の巨人", "進撃の小人")
+	f.Add("pokémon", "pokemon")
+
+	f.Fuzz(func(t *testing.T, word1, word2 string) {
+		if len(word1) > 64 || len(word2) > 64 {
+			return
+		}
+
+		result := EditDistance(word1, word2)
+		if reverse := EditDistance(word2, word1); result != reverse {
+			t.Fatalf("EditDistance(%q, %q) = %d, reversed %d", word1, word2, result, reverse)
+		}
+
+		len1 := utf8.RuneCountInString(word1)
+		len2 := utf8.RuneCountInString(word2)
+		if result > max(len1, len2) {
+			t.Fatalf("EditDistance(%q, %q) = %d, above max length %d", word1, word2, result, max(len1, len2))
+		}
+		if result < max(len1-len2, len2-len1) {
+			t.Fatalf("EditDistance(%q, %q) = %d, below length difference", word1, word2, result)
+		}
+
+		if isASCII(word1) && isASCII(word2) {
+			if bytes := editDistanceBytes(word1, word2); result != bytes {
+				t.Fatalf("EditDistance(%q, %q) = %d, editDistanceBytes %d", word1, word2, result, bytes)
+			}
+		}
+	})
+}
+
+func BenchmarkEditDistance(b *testing.B) {
+	word1 := strings.Repeat("shingeki no kyojin ", 3)
+	word2 := strings.Repeat("shingeki no kyoujin ", 3)
+
+	b.Run("runes", func(b *testing.B) {
+		for range b.N {
+			EditDistance(word1, word2)
+		}
+	})
+	b.Run("bytes", func(b *testing.B) {
+		for range b.N {
+			editDistanceBytes(word1, word2)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package algo
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// Span is the rune range [Start, End) of the original string a folded rune
+// came from.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Fold normalizes s for matching. Each base rune and the marks after it are
+// decomposed with NFKD, the diacritics dropped, full and half width forms
+// folded, the result recomposed with NFC and case folded. Kana voicing marks
+// are kept so が and か stay distinct. Names and search input must both go
+// through Fold before they are compared. Fold is idempotent.
+func Fold(s string) string {
+	folded, _ := FoldSpans(s)
+	return folded
+}
+
+// FoldSpans is Fold that also returns, for each rune of the folded string,
+// the runes of s it came from, so a match in the folded string can be mapped
+// back onto s.
+func FoldSpans(s string) (string, []Span) {
+	caser := cases.Fold()
+
+	var b strings.Builder
+	spans := make([]Span, 0, len(s))
+
+	runes := []rune(s)
+	for start := 0; start < len(runes); {
+		// a base rune and the marks after it fold together
+		end := start + 1
+		for end < len(runes) && isMark(runes[end]) {
+			end++
+		}
+
+		segment := foldSegment(caser, string(runes[start:end]))
+		for range []rune(segment) {
+			spans = append(spans, Span{Start: start, End: end})
+		}
+		b.WriteString(segment)
+
+		start = end
+	}
+
+	return b.String(), spans
+}
+
+func foldSegment(caser cases.Caser, segment string) string {
+	decomposed := norm.NFKD.String(segment)
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) && !isKanaVoicing(r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+	composed := norm.NFC.String(width.Fold.String(stripped))
+	// case folding maps Cherokee small letters to capitals and capitals back,
+	// lowering first makes both end on the capital
+	caser.Reset()
+	return caser.String(strings.ToLower(composed))
+}
+
+// isMark also counts the half width voicing marks, which are not combining
+// but become combining marks once folded.
+func isMark(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || r == 'ﾞ' || r == 'ﾟ'
+}
+
+func isKanaVoicing(r rune) bool {
+	return r == '゙' || r == '゚'
+}
@@ -0,0 +1,50 @@
+package algo
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFoldIdempotent(t *testing.T) {
+	tests := []string{
+		"",
+		"Pokémon",
+		"ＡＢＣ１２３",
+		"ｼﾝｹﾞｷ",
+		"シンゲキ",
+		"が",
+		"Straße",
+		"İstanbul",
+		"ꮩᏙ",
+		"ﬁnal",
+		"Ⓐⓑ",
+		"進撃の巨人",
+	}
+
+	for _, v := range tests {
+		folded := Fold(v)
+		if again := Fold(folded); again != folded {
+			t.Errorf("Fold(%q) = %q, Fold of that %q", v, folded, again)
+		}
+	}
+}
+
+func FuzzFold(f *testing.F) {
+	f.Add("Pokémon")
+	f.Add("ｼﾝｹﾞｷ")
+	f.Add("İstanbul")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			return
+		}
+
+		folded, spans := FoldSpans(s)
+		if again := Fold(folded); again != folded {
+			t.Fatalf("Fold(%q) = %q, Fold of that %q", s, folded, again)
+		}
+		if len(spans) != utf8.RuneCountInString(folded) {
+			t.Fatalf("FoldSpans(%q) = %d spans for %d runes", s, len(spans), utf8.RuneCountInString(folded))
+		}
+	})
+}
@@ -1,8 +1,59 @@
 package algo
 
+// Kmp returns the rune index of the first occurrence of needle in haystack,
+// or -1 when there is none.
 func Kmp(haystack string, needle string) int {
+	hay := []rune(haystack)
+	pattern := []rune(needle)
+	hayLen := len(hay)
+	needleLen := len(pattern)
+	if needleLen == 0 {
+		return 0
+	}
+	lps := make([]int, needleLen)
+
+	needleIdx := 1
+	prevLps := 0
+	for needleIdx < needleLen {
+		if pattern[needleIdx] == pattern[prevLps] {
+			lps[needleIdx] = prevLps + 1
+			needleIdx++
+			prevLps++
+		} else if prevLps == 0 {
+			needleIdx++
+		} else {
+			prevLps = lps[prevLps-1]
+		}
+	}
+
+	needleIdx = 0
+	hayIdx := 0
+	for hayIdx < hayLen {
+		if hay[hayIdx] == pattern[needleIdx] {
+			needleIdx++
+			hayIdx++
+		} else if needleIdx == 0 {
+			hayIdx++
+		} else {
+			needleIdx = lps[needleIdx-1]
+		}
+
+		if needleIdx >= needleLen {
+			return hayIdx - needleLen
+		}
+	}
+
+	return -1
+}
+
+// kmpBytes is Kmp over bytes. It returns a byte index and only agrees with
+// Kmp on ASCII input.
+func kmpBytes(haystack string, needle string) int {
 	hayLen := len(haystack)
 	needleLen := len(needle)
+	if needleLen == 0 {
+		return 0
+	}
 	lps := make([]int, needleLen)
 
 	needleIdx := 1
@@ -0,0 +1,50 @@
+package algo
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzKmp(f *testing.F) {
+	f.Add("", "")
+	f.Add("abc", "")
+	f.Add("abababca", "ababca")
+	f.Add("attack on titan", "titan")
+	f.Add("進撃の巨人", "巨人")
+
+	f.Fuzz(func(t *testing.T, haystack, needle string) {
+		result := Kmp(haystack, needle)
+
+		hay := []rune(haystack)
+		pattern := []rune(needle)
+		if result == -1 {
+			if strings.Contains(string(hay), string(pattern)) {
+				t.Fatalf("Kmp(%q, %q) = -1, but it contains it", haystack, needle)
+			}
+		} else if result < 0 || result+len(pattern) > len(hay) || string(hay[result:result+len(pattern)]) != string(pattern) {
+			t.Fatalf("Kmp(%q, %q) = %d, not a match", haystack, needle, result)
+		}
+
+		if isASCII(haystack) && isASCII(needle) {
+			if bytes := kmpBytes(haystack, needle); result != bytes {
+				t.Fatalf("Kmp(%q, %q) = %d, kmpBytes %d", haystack, needle, result, bytes)
+			}
+		}
+	})
+}
+
+func BenchmarkKmp(b *testing.B) {
+	haystack := strings.Repeat("shingeki no kyojin ", 20) + "the final season"
+	needle := "final season"
+
+	b.Run("runes", func(b *testing.B) {
+		for range b.N {
+			Kmp(haystack, needle)
+		}
+	})
+	b.Run("bytes", func(b *testing.B) {
+		for range b.N {
+			kmpBytes(haystack, needle)
+		}
+	})
+}
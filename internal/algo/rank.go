@@ -0,0 +1,92 @@
+package algo
+
+import "strings"
+
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// RankBetween returns a key that sorts strictly between lo and hi in byte
+// order. An empty lo or hi leaves that side unbounded. Keys never end in the
+// zero digit, so there is always room for another key on either side without
+// rewriting existing ones.
+//
+// Against an open end the key steps one digit away from the bound instead of
+// halving the gap, so appending or prepending grows keys by one digit every
+// 35 keys rather than every few.
+func RankBetween(lo, hi string) string {
+	if hi == "" {
+		return rankAfter(lo)
+	}
+	if lo == "" {
+		return rankBefore(hi)
+	}
+
+	if hi != "" {
+		n := 0
+		for n < len(hi) && rankDigitAt(lo, n) == hi[n] {
+			n++
+		}
+		if n > 0 {
+			return hi[:n] + RankBetween(suffix(lo, n), hi[n:])
+		}
+	}
+
+	digitLo := 0
+	if lo != "" {
+		digitLo = strings.IndexByte(rankDigits, lo[0])
+	}
+	digitHi := len(rankDigits)
+	if hi != "" {
+		digitHi = strings.IndexByte(rankDigits, hi[0])
+	}
+
+	if digitHi-digitLo > 1 {
+		return string(rankDigits[(digitLo+digitHi+1)/2])
+	}
+	if hi != "" && len(hi) > 1 {
+		return hi[:1]
+	}
+	return string(rankDigits[digitLo]) + RankBetween(suffix(lo, 1), "")
+}
+
+// rankAfter increments the first digit of lo that is not the largest and
+// drops the digits after it. A lo made only of the largest digit is
+// extended by the smallest digit that is not zero.
+func rankAfter(lo string) string {
+	if lo == "" {
+		return string(rankDigits[len(rankDigits)/2])
+	}
+	for i := 0; i < len(lo); i++ {
+		digit := strings.IndexByte(rankDigits, lo[i])
+		if digit < len(rankDigits)-1 {
+			return lo[:i] + string(rankDigits[digit+1])
+		}
+	}
+	return lo + string(rankDigits[1])
+}
+
+// rankBefore decrements the first digit of hi above the smallest non-zero
+// digit and drops the digits after it. A hi made only of zero and that digit
+// ends in it, which is replaced by zero and followed by the largest digit.
+func rankBefore(hi string) string {
+	for i := 0; i < len(hi); i++ {
+		digit := strings.IndexByte(rankDigits, hi[i])
+		if digit > 1 {
+			return hi[:i] + string(rankDigits[digit-1])
+		}
+	}
+	return hi[:len(hi)-1] + string(rankDigits[0]) + string(rankDigits[len(rankDigits)-1])
+}
+
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+func suffix(s string, i int) string {
+	if i < len(s) {
+		return s[i:]
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package algo
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func checkRank(t *testing.T, lo, hi, rank string) {
+	t.Helper()
+	if rank == "" {
+		t.Fatalf("RankBetween(%q, %q) is empty", lo, hi)
+	}
+	if lo != "" && rank <= lo {
+		t.Fatalf("RankBetween(%q, %q) = %q, not after lo", lo, hi, rank)
+	}
+	if hi != "" && rank >= hi {
+		t.Fatalf("RankBetween(%q, %q) = %q, not before hi", lo, hi, rank)
+	}
+	if rank[len(rank)-1] == rankDigits[0] {
+		t.Fatalf("RankBetween(%q, %q) = %q, ends in the zero digit", lo, hi, rank)
+	}
+	for i := 0; i < len(rank); i++ {
+		if strings.IndexByte(rankDigits, rank[i]) == -1 {
+			t.Fatalf("RankBetween(%q, %q) = %q, not a rank digit", lo, hi, rank)
+		}
+	}
+}
+
+func TestRankBetweenOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 20; round++ {
+		ranks := make([]string, 0)
+		for i := 0; i < 500; i++ {
+			// ends are picked often, appending and prepending are the usual
+			// moves
+			pos := r.Intn(len(ranks) + 1)
+			switch r.Intn(4) {
+			case 0:
+				pos = 0
+			case 1:
+				pos = len(ranks)
+			}
+
+			lo, hi := "", ""
+			if pos > 0 {
+				lo = ranks[pos-1]
+			}
+			if pos < len(ranks) {
+				hi = ranks[pos]
+			}
+
+			rank := RankBetween(lo, hi)
+			checkRank(t, lo, hi, rank)
+
+			ranks = append(ranks, "")
+			copy(ranks[pos+1:], ranks[pos:])
+			ranks[pos] = rank
+		}
+
+		if !sort.StringsAreSorted(ranks) {
+			t.Fatalf("round %d: ranks out of order", round)
+		}
+	}
+}
+
+func TestRankBetweenEnds(t *testing.T) {
+	const n = 2000
+
+	last := ""
+	for i := 0; i < n; i++ {
+		rank := RankBetween(last, "")
+		checkRank(t, last, "", rank)
+		last = rank
+	}
+	if len(last) > n/30 {
+		t.Errorf("%d appends: key grew to %d digits", n, len(last))
+	}
+
+	first := ""
+	for i := 0; i < n; i++ {
+		rank := RankBetween("", first)
+		checkRank(t, "", first, rank)
+		first = rank
+	}
+	if len(first) > n/30 {
+		t.Errorf("%d prepends: key grew to %d digits", n, len(first))
+	}
+}
+
+func TestRankBetweenAdjacent(t *testing.T) {
+	tests := [][2]string{
+		{"", ""},
+		{"a", "b"},
+		{"a", "a1"},
+		{"a", "a01"},
+		{"az", "b"},
+		{"zz", ""},
+		{"", "1"},
+		{"", "01"},
+		{"0z", "1"},
+	}
+
+	for _, v := range tests {
+		checkRank(t, v[0], v[1], RankBetween(v[0], v[1]))
+	}
+}
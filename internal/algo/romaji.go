@@ -0,0 +1,137 @@
+package algo
+
+import (
+	"strings"
+	"unicode"
+)
+
+var kanaRomaji = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n",
+	'ゎ': "wa", 'ゔ': "vu", 'ゕ': "ka", 'ゖ': "ke",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo",
+}
+
+var smallYRomaji = map[rune]string{
+	'ゃ': "a", 'ゅ': "u", 'ょ': "o",
+}
+
+var smallVowelRomaji = map[rune]string{
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+// Romanize transliterates hiragana and katakana in s to Hepburn romaji and
+// leaves every other rune as is. Long vowel marks are dropped, so ラーメン
+// reads "ramen" the way people type it, and so are spaces and punctuation,
+// since kana has no word breaks to match "shingeki no kyojin" against. Kanji
+// have no reading without a dictionary and are kept.
+func Romanize(s string) string {
+	romaji, _ := RomanizeSpans(s)
+	return romaji
+}
+
+// RomanizeSpans is Romanize that also returns, for each rune of the romaji,
+// the runes of s it came from.
+func RomanizeSpans(s string) (string, []Span) {
+	runes := []rune(s)
+	for k, v := range runes {
+		runes[k] = toHiragana(v)
+	}
+
+	var b strings.Builder
+	spans := make([]Span, 0, len(runes))
+	write := func(romaji string, start, end int) {
+		for _, r := range romaji {
+			b.WriteRune(r)
+			spans = append(spans, Span{Start: start, End: end})
+		}
+	}
+
+	geminate := -1
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch r {
+		case 'っ':
+			geminate = i
+			i++
+			continue
+		case 'ー', '゙', '゚':
+			i++
+			continue
+		}
+
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			i++
+			continue
+		}
+
+		romaji, ok := kanaRomaji[r]
+		if !ok {
+			geminate = -1
+			write(string(r), i, i+1)
+			i++
+			continue
+		}
+
+		end := i + 1
+		if end < len(runes) {
+			if vowel, ok := smallYRomaji[runes[end]]; ok && len(romaji) > 1 && strings.HasSuffix(romaji, "i") {
+				romaji = yoon(romaji, vowel)
+				end++
+			} else if vowel, ok := smallVowelRomaji[runes[end]]; ok && len(romaji) > 1 {
+				romaji = strings.TrimRight(romaji, "aiueo") + vowel
+				end++
+			} else if ok && r == 'う' {
+				romaji = "w" + vowel
+				end++
+			}
+		}
+
+		if geminate != -1 {
+			if consonant := romaji[0]; strings.IndexByte("aiueon", consonant) == -1 {
+				if strings.HasPrefix(romaji, "ch") {
+					consonant = 't'
+				}
+				write(string(consonant), geminate, geminate+1)
+			}
+			geminate = -1
+		}
+
+		write(romaji, i, end)
+		i = end
+	}
+
+	return b.String(), spans
+}
+
+// yoon joins a kana ending in i with a small ya, yu or yo: き+ゃ is "kya",
+// し+ゃ is "sha".
+func yoon(romaji string, vowel string) string {
+	base := strings.TrimSuffix(romaji, "i")
+	switch base {
+	case "sh", "ch", "j":
+		return base + vowel
+	}
+	return base + "y" + vowel
+}
+
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
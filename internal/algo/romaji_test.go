@@ -0,0 +1,128 @@
+package algo
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRomanize(t *testing.T) {
+	tests := []struct {
+		kana   string
+		romaji string
+	}{
+		{"", ""},
+		{"しんげきのきょじん", "shingekinokyojin"},
+		{"シンゲキ ノ キョジン", "shingekinokyojin"},
+		{"こんにちは", "konnichiha"},
+		// sokuon
+		{"がっこう", "gakkou"},
+		{"ちょっと", "chotto"},
+		{"マッチャ", "matcha"},
+		{"っか", "kka"},
+		{"あっ", "a"},
+		{"あっあ", "aa"},
+		// yoon
+		{"きゃ", "kya"},
+		{"しゅ", "shu"},
+		{"ちょ", "cho"},
+		{"じゃ", "ja"},
+		{"りょ", "ryo"},
+		{"ぎゅう", "gyuu"},
+		// small vowels
+		{"ティ", "ti"},
+		{"ファ", "fa"},
+		{"ウィ", "wi"},
+		{"ゔ", "vu"},
+		{"ヴ", "vu"},
+		{"ヴァイオリン", "vaiorin"},
+		// lone small kana
+		{"ゃ", "ya"},
+		{"ュ", "yu"},
+		{"ぁ", "a"},
+		{"ヵ", "ka"},
+		// long vowel marks and punctuation
+		{"ラーメン", "ramen"},
+		{"ハイ・スコア", "haisukoa"},
+		// other scripts are kept
+		{"進撃の巨人", "進撃no巨人"},
+		{"abc", "abc"},
+	}
+
+	for _, v := range tests {
+		if romaji := Romanize(v.kana); romaji != v.romaji {
+			t.Errorf("Romanize(%q) = %q, want %q", v.kana, romaji, v.romaji)
+		}
+	}
+}
+
+func TestRomanizeHalfWidth(t *testing.T) {
+	tests := []struct {
+		kana   string
+		romaji string
+	}{
+		{"ｼﾝｹﾞｷ", "shingeki"},
+		{"ｶﾞｯｺｳ", "gakkou"},
+		{"ﾊﾟｰﾃｨｰ", "pati"},
+		{"ｷｬ", "kya"},
+	}
+
+	for _, v := range tests {
+		if romaji := Romanize(Fold(v.kana)); romaji != v.romaji {
+			t.Errorf("Romanize(Fold(%q)) = %q, want %q", v.kana, romaji, v.romaji)
+		}
+	}
+}
+
+func TestRomanizeSpans(t *testing.T) {
+	tests := []struct {
+		kana   string
+		romaji string
+		spans  []Span
+	}{
+		{"きゃっと", "kyatto", []Span{{0, 2}, {0, 2}, {0, 2}, {2, 3}, {3, 4}, {3, 4}}},
+		{"ラーメン", "ramen", []Span{{0, 1}, {0, 1}, {2, 3}, {2, 3}, {3, 4}}},
+		{"の巨人", "no巨人", []Span{{0, 1}, {0, 1}, {1, 2}, {2, 3}}},
+		{"ゃ", "ya", []Span{{0, 1}, {0, 1}}},
+	}
+
+	for _, v := range tests {
+		romaji, spans := RomanizeSpans(v.kana)
+		if romaji != v.romaji || !slices.Equal(spans, v.spans) {
+			t.Errorf("RomanizeSpans(%q) = %q %v, want %q %v", v.kana, romaji, spans, v.romaji, v.spans)
+		}
+	}
+}
+
+func FuzzRomanizeSpans(f *testing.F) {
+	f.Add("しんげきのきょじん")
+	f.Add("がっこう")
+	f.Add("ヴァイオリン")
+	f.Add("ｼﾝｹﾞｷ")
+	f.Add("っっゃー")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			return
+		}
+
+		romaji, spans := RomanizeSpans(s)
+		if len(spans) != utf8.RuneCountInString(romaji) {
+			t.Fatalf("RomanizeSpans(%q) = %q with %d spans", s, romaji, len(spans))
+		}
+
+		length := utf8.RuneCountInString(s)
+		for k, v := range spans {
+			if v.Start < 0 || v.Start >= v.End || v.End > length {
+				t.Fatalf("RomanizeSpans(%q) span %d = %v, outside %d runes", s, k, v, length)
+			}
+			if k > 0 && v.Start < spans[k-1].Start {
+				t.Fatalf("RomanizeSpans(%q) span %d = %v, before span %v", s, k, v, spans[k-1])
+			}
+		}
+
+		if romaji != Romanize(s) {
+			t.Fatalf("RomanizeSpans(%q) = %q, Romanize %q", s, romaji, Romanize(s))
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package algo
+
+import (
+	"unicode"
+)
+
+const (
+	SCRIPT_LATIN    = "latin"
+	SCRIPT_HIRAGANA = "hiragana"
+	SCRIPT_KATAKANA = "katakana"
+	SCRIPT_HAN      = "han"
+	SCRIPT_JAPANESE = "japanese"
+	SCRIPT_HANGUL   = "hangul"
+	SCRIPT_MIXED    = "mixed"
+	SCRIPT_OTHER    = "other"
+)
+
+// Script names the writing system of s, ignoring digits, spaces, punctuation
+// and marks shared by every script. Kana mixed with kanji is "japanese", any
+// other mix is "mixed".
+func Script(s string) string {
+	seen := make(map[string]bool)
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			seen[SCRIPT_LATIN] = true
+		case unicode.Is(unicode.Hiragana, r):
+			seen[SCRIPT_HIRAGANA] = true
+		case unicode.Is(unicode.Katakana, r):
+			seen[SCRIPT_KATAKANA] = true
+		case unicode.Is(unicode.Han, r):
+			seen[SCRIPT_HAN] = true
+		case unicode.Is(unicode.Hangul, r):
+			seen[SCRIPT_HANGUL] = true
+		case unicode.In(r, unicode.Common, unicode.Inherited):
+		default:
+			seen[SCRIPT_OTHER] = true
+		}
+	}
+
+	switch len(seen) {
+	case 0:
+		return SCRIPT_OTHER
+	case 1:
+		for script := range seen {
+			return script
+		}
+	}
+
+	japanese := seen[SCRIPT_HIRAGANA] || seen[SCRIPT_KATAKANA] || seen[SCRIPT_HAN]
+	if japanese && !seen[SCRIPT_LATIN] && !seen[SCRIPT_HANGUL] && !seen[SCRIPT_OTHER] {
+		return SCRIPT_JAPANESE
+	}
+	return SCRIPT_MIXED
+}
+
+// IsReading reports whether name, written in kana alone, can be the reading
+// of title, written with kanji: the kana title keeps, such as the の of
+// 進撃の巨人, must appear in name in the same order. Kanji are not looked up,
+// so this cannot tell a reading from other kana with the same okurigana.
+func IsReading(name, title string) bool {
+	switch Script(name) {
+	case SCRIPT_HIRAGANA, SCRIPT_KATAKANA:
+	default:
+		return false
+	}
+	switch Script(title) {
+	case SCRIPT_HAN, SCRIPT_JAPANESE:
+	default:
+		return false
+	}
+
+	reading := []rune(Fold(name))
+	idx := 0
+	for _, r := range Fold(title) {
+		if !unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			continue
+		}
+		r = toHiragana(r)
+		for idx < len(reading) && toHiragana(reading[idx]) != r {
+			idx++
+		}
+		if idx == len(reading) {
+			return false
+		}
+		idx++
+	}
+
+	return true
+}
@@ -0,0 +1,25 @@
+package algo
+
+import "testing"
+
+func TestIsReading(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		result bool
+	}{
+		{"しんげきのきょじん", "進撃の巨人", true},
+		{"シンゲキノキョジン", "進撃の巨人", true},
+		{"ｼﾝｹﾞｷﾉｷｮｼﾞﾝ", "進撃の巨人", true},
+		{"しんげききょじん", "進撃の巨人", false},
+		{"しんげきのきょじん", "Shingeki no Kyojin", false},
+		{"Shingeki no Kyojin", "進撃の巨人", false},
+		{"ちんぷんかんぷん", "珍紛漢紛", true},
+	}
+
+	for _, v := range tests {
+		if result := IsReading(v.name, v.title); result != v.result {
+			t.Errorf("IsReading(%q, %q) = %v, want %v", v.name, v.title, result, v.result)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package algo
+
+import (
+	"slices"
+)
+
+// Trie maps string keys to values and walks them by prefix. It is not safe
+// for concurrent use.
+type Trie[T any] struct {
+	root *trieNode[T]
+}
+
+type trieNode[T any] struct {
+	children map[rune]*trieNode[T]
+	values   []T
+}
+
+func NewTrie[T any]() *Trie[T] {
+	return &Trie[T]{
+		root: &trieNode[T]{},
+	}
+}
+
+func (t *Trie[T]) Insert(key string, value T) {
+	node := t.root
+	for _, r := range key {
+		if node.children == nil {
+			node.children = make(map[rune]*trieNode[T])
+		}
+		child, ok := node.children[r]
+		if !ok {
+			child = &trieNode[T]{}
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.values = append(node.values, value)
+}
+
+// Remove drops the values under key that match, and any nodes left empty.
+func (t *Trie[T]) Remove(key string, match func(T) bool) {
+	path := []*trieNode[T]{t.root}
+	runes := []rune(key)
+	for _, r := range runes {
+		child, ok := path[len(path)-1].children[r]
+		if !ok {
+			return
+		}
+		path = append(path, child)
+	}
+
+	node := path[len(path)-1]
+	node.values = slices.DeleteFunc(node.values, match)
+
+	for i := len(path) - 1; i > 0; i-- {
+		if len(path[i].values) > 0 || len(path[i].children) > 0 {
+			break
+		}
+		delete(path[i-1].children, runes[i-1])
+	}
+}
+
+// WalkPrefix calls fn with the values of every key starting with prefix,
+// shortest keys first and keys of the same length in rune order, until fn
+// returns false.
+func (t *Trie[T]) WalkPrefix(prefix string, fn func(T) bool) {
+	node := t.root
+	for _, r := range prefix {
+		child, ok := node.children[r]
+		if !ok {
+			return
+		}
+		node = child
+	}
+
+	level := []*trieNode[T]{node}
+	for len(level) > 0 {
+		next := make([]*trieNode[T], 0)
+		for _, v := range level {
+			for _, value := range v.values {
+				if !fn(value) {
+					return
+				}
+			}
+
+			keys := make([]rune, 0, len(v.children))
+			for r := range v.children {
+				keys = append(keys, r)
+			}
+			slices.Sort(keys)
+			for _, r := range keys {
+				next = append(next, v.children[r])
+			}
+		}
+		level = next
+	}
+}
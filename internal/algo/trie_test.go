@@ -0,0 +1,93 @@
+package algo
+
+import (
+	"slices"
+	"testing"
+)
+
+func walkAll(trie *Trie[string], prefix string) []string {
+	result := make([]string, 0)
+	trie.WalkPrefix(prefix, func(s string) bool {
+		result = append(result, s)
+		return true
+	})
+	return result
+}
+
+func TestTrieWalkPrefix(t *testing.T) {
+	trie := NewTrie[string]()
+	for _, v := range []string{"shingeki", "shin", "shi", "sh", "shiro", "shingekinokyojin", "shio", "akira", "進撃"} {
+		trie.Insert(v, v)
+	}
+
+	tests := []struct {
+		prefix string
+		result []string
+	}{
+		{"shi", []string{"shi", "shin", "shio", "shiro", "shingeki", "shingekinokyojin"}},
+		{"", []string{"sh", "進撃", "shi", "shin", "shio", "akira", "shiro", "shingeki", "shingekinokyojin"}},
+		{"shingeki", []string{"shingeki", "shingekinokyojin"}},
+		{"進", []string{"進撃"}},
+		{"x", []string{}},
+	}
+
+	for _, v := range tests {
+		if result := walkAll(trie, v.prefix); !slices.Equal(result, v.result) {
+			t.Errorf("WalkPrefix(%q) = %q, want %q", v.prefix, result, v.result)
+		}
+	}
+}
+
+func TestTrieWalkPrefixStop(t *testing.T) {
+	trie := NewTrie[string]()
+	for _, v := range []string{"abc", "ab", "abcd", "abd"} {
+		trie.Insert(v, v)
+	}
+
+	result := make([]string, 0)
+	trie.WalkPrefix("ab", func(s string) bool {
+		result = append(result, s)
+		return len(result) < 2
+	})
+	if want := []string{"ab", "abc"}; !slices.Equal(result, want) {
+		t.Errorf("WalkPrefix stopped at %q, want %q", result, want)
+	}
+}
+
+func TestTrieRemove(t *testing.T) {
+	trie := NewTrie[string]()
+	trie.Insert("shingeki", "a")
+	trie.Insert("shingeki", "b")
+	trie.Insert("shinobi", "c")
+
+	trie.Remove("shingeki", func(s string) bool { return s == "a" })
+	if result := walkAll(trie, "shingeki"); !slices.Equal(result, []string{"b"}) {
+		t.Errorf("after removing one value WalkPrefix = %q, want [b]", result)
+	}
+
+	trie.Remove("shingeki", func(s string) bool { return s == "b" })
+	if result := walkAll(trie, "shin"); !slices.Equal(result, []string{"c"}) {
+		t.Errorf("after removing shingeki WalkPrefix = %q, want the sibling [c]", result)
+	}
+
+	// the branch below shin is pruned, the sibling's is kept
+	shin := trie.root
+	for _, r := range "shin" {
+		shin = shin.children[r]
+	}
+	if len(shin.children) != 1 || shin.children['o'] == nil {
+		t.Errorf("shin has children %v, want only the sibling branch", shin.children)
+	}
+
+	// a missing key or a prefix without values is left alone
+	trie.Remove("shinx", func(string) bool { return true })
+	trie.Remove("shi", func(string) bool { return true })
+	if result := walkAll(trie, ""); !slices.Equal(result, []string{"c"}) {
+		t.Errorf("after removing missing keys WalkPrefix = %q, want [c]", result)
+	}
+
+	trie.Remove("shinobi", func(string) bool { return true })
+	if len(trie.root.children) != 0 {
+		t.Errorf("root has children %v after removing every key", trie.root.children)
+	}
+}
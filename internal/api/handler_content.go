@@ -0,0 +1,619 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerContent interface {
+	NewContent(w http.ResponseWriter, r *http.Request)
+	GetContent(w http.ResponseWriter, r *http.Request)
+	GetAllContent(w http.ResponseWriter, r *http.Request)
+	UpdateContent(w http.ResponseWriter, r *http.Request)
+	DeleteContent(w http.ResponseWriter, r *http.Request)
+	ClaimContent(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerContent struct {
+	dbsContent         database.DbsContent
+	dbsRelUsersContent database.DbsRelUsersContent
+}
+
+var handlerContentInstance *handlerContent
+
+func NewHandlerContent(dbsContent database.DbsContent, dbsRelUsersContent database.DbsRelUsersContent) HandlerContent {
+	if handlerContentInstance != nil {
+		return handlerContentInstance
+	}
+
+	newHandlerContent := &handlerContent{
+		dbsContent:         dbsContent,
+		dbsRelUsersContent: dbsRelUsersContent,
+	}
+	handlerContentInstance = newHandlerContent
+
+	return handlerContentInstance
+}
+
+func (h *handlerContent) NewContent(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Content: NewContent: GetUser: user is nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type ContentRequest struct {
+		Kind        *string `json:"kind"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		ImageUrl    *string `json:"image_url"`
+		Unit        *string `json:"unit"`
+		Total       *int    `json:"total"`
+		Seasons     *int    `json:"seasons"`
+		UnitMinutes *int    `json:"unit_minutes"`
+		// Force skips the duplicate check after the user has seen the warning.
+		Force bool `json:"force"`
+	}
+
+	var req ContentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("error: Handler: Content: NewContent: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.Name == nil {
+		log.Printf("error: Handler: Content: NewContent: missing name: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: missing name: WriteJson: %v", err)
+		}
+		return
+	}
+	if req.ImageUrl == nil {
+		log.Printf("error: Handler: Content: NewContent: missing image url: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: missing image url: WriteJson: %v", err)
+		}
+		return
+	}
+	if req.Description == nil {
+		log.Printf("error: Handler: Content: NewContent: missing description: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: missing description: WriteJson: %v", err)
+		}
+		return
+	}
+
+	kind := database.KIND_ANIME
+	if req.Kind != nil {
+		kind = strings.ToLower(strings.TrimSpace(*req.Kind))
+	}
+	if !database.ValidKind(kind) {
+		log.Printf("error: Handler: Content: NewContent: invalid kind: %v", kind)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid kind",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: invalid kind: WriteJson: %v", err)
+		}
+		return
+	}
+
+	unit := ""
+	if req.Unit != nil {
+		unit = strings.ToLower(strings.TrimSpace(*req.Unit))
+	}
+
+	// submissions from regular users wait for a moderator
+	status := database.CONTENT_PENDING
+	if auth.Can(user.Role, auth.PermContentReview) {
+		status = database.CONTENT_APPROVED
+	}
+
+	reqContent := &database.Content{
+		Status:      status,
+		Kind:        kind,
+		Unit:        unit,
+		Total:       req.Total,
+		Seasons:     req.Seasons,
+		UnitMinutes: req.UnitMinutes,
+		Description: req.Description,
+		ImageUrl:    req.ImageUrl,
+		ContentName: database.ContentName{
+			Name: *req.Name,
+		},
+	}
+
+	if !req.Force {
+		dbDuplicates, err := h.dbsContent.GetDuplicates(user, reqContent)
+		if err != nil {
+			log.Printf("error: Handler: Content: NewContent: GetDuplicates: %v", err)
+			if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+				"error": "internal server error",
+			}); err != nil {
+				log.Printf("error: Handler: Content: NewContent: GetDuplicates: WriteJson: %v", err)
+			}
+			return
+		}
+		if len(dbDuplicates) > 0 {
+			if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+				"error":      "possible duplicates, resend with force to add anyway",
+				"duplicates": dbDuplicates,
+			}); err != nil {
+				log.Printf("error: Handler: Content: NewContent: GetDuplicates: WriteJson: %v", err)
+			}
+			return
+		}
+	}
+
+	dbContent, err := h.dbsContent.InsertContent(user, reqContent)
+	if errors.Is(err, database.ErrDuplicateName) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "name already in use",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: InsertContent: WriteJson: %v", err)
+		}
+		return
+	} else if errors.Is(err, database.ErrInvalidUnit) {
+		log.Printf("error: Handler: Content: NewContent: InsertContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid unit or missing total",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: InsertContent: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Content: NewContent: InsertContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: NewContent: InsertContent: WriteJson: %v", err)
+		}
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"content": dbContent,
+	})
+}
+
+func (h *handlerContent) GetContent(w http.ResponseWriter, r *http.Request) {
+	contentIdStr := r.PathValue("contentId")
+
+	if contentIdStr == "" {
+		log.Printf("error: handler content GetContent: missing content id")
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	err := uuid.Validate(contentIdStr)
+	if err != nil {
+		log.Printf("error: handler content GetContent: validate uuid: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	id, err := uuid.Parse(contentIdStr)
+	if err != nil {
+		log.Printf("error: handler content GetContent: parse uuid: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	user := utils.GetUser(r)
+	if user == nil {
+		user = database.AnonymousUser
+	}
+
+	content := &database.Content{
+		Id: id,
+	}
+	dbContent, err := h.dbsContent.GetContentById(user, content)
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("error: handler content GetContentById: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"content": dbContent,
+	})
+}
+
+func (h *handlerContent) GetAllContent(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Content: GetAllContent: GetUser: user nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: GetAllContent: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	opts := make([]database.OptionsFunc, 0)
+	queries := r.URL.Query()
+
+	search := queries.Get("search")
+	sort := queries.Get("sort")
+	ignore := queries.Get("ignore")
+	kind := queries.Get("kind")
+	if kind != "" && !database.ValidKind(kind) {
+		log.Printf("error: Handler: Content: GetAllContent: invalid kind: %v", kind)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid kind",
+		}); err != nil {
+			log.Printf("error: Handler: Content: GetAllContent: invalid kind: WriteJson: %v", err)
+		}
+		return
+	}
+	opts = append(opts, database.WithKind(kind))
+	opts = append(opts, database.WithSearch(search))
+	opts = append(opts, database.WithSort(sort))
+	opts = append(opts, database.WithIgnore(ignore))
+	opts = append(opts, database.WithGenres(strings.Split(queries.Get("genres"), ","), queries.Get("genres_mode")))
+	opts = append(opts, database.WithTags(strings.Split(queries.Get("tags"), ","), queries.Get("tags_mode")))
+
+	dbContentList, err := h.dbsContent.GetAllContent(user, opts...)
+	if err != nil {
+		log.Printf("error: Handler: Content: GetAllContent: GetAllContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: GetAllContent: GetAllContent: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err = utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"content": dbContentList,
+		"facets":  database.ContentFacets(dbContentList),
+	}); err != nil {
+		log.Printf("error: Handler: Content: GetAllContent: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerContent) UpdateContent(w http.ResponseWriter, r *http.Request) {
+	type ContentRequest struct {
+		ContentId      *string `json:"content_id"`
+		ContentNamesId *string `json:"content_names_id"`
+		Description    *string `json:"description"`
+		ImageUrl       *string `json:"image_url"`
+		Unit           *string `json:"unit"`
+		Total          *int    `json:"total"`
+		Seasons        *int    `json:"seasons"`
+		UnitMinutes    *int    `json:"unit_minutes"`
+	}
+
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: handler content UpdateContent GetUser: user is nil")
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	var req ContentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("error: handler content UpdateContent req decode: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: handler content UpdateContent: missing content id")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	if req.ContentNamesId == nil {
+		log.Printf("error: handler content UpdateContent: missing content name id")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	err = uuid.Validate(*req.ContentId)
+	if err != nil {
+		log.Printf("error: handler content UpdateContent: validate content id")
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+	contentId, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: handler content UpdateContent: parse content id")
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	err = uuid.Validate(*req.ContentNamesId)
+	if err != nil {
+		log.Printf("error: handler content UpdateContent: validate content name id")
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+	contentNamesId, err := uuid.Parse(*req.ContentNamesId)
+	if err != nil {
+		log.Printf("error: handler content UpdateContent: parse content name id")
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	allowed, err := canManageContent(h.dbsRelUsersContent, user, contentId, auth.PermContentEdit)
+	if err != nil {
+		log.Printf("error: handler content UpdateContent: canManageContent: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+	if !allowed {
+		log.Printf("error: handler content UpdateContent: not owner: %v", contentId)
+		utils.WriteJson(w, http.StatusForbidden, utils.Envelope{
+			"error": "forbidden",
+		})
+		return
+	}
+
+	unit := ""
+	if req.Unit != nil {
+		unit = strings.ToLower(strings.TrimSpace(*req.Unit))
+	}
+
+	content := &database.Content{
+		Id:          contentId,
+		Unit:        unit,
+		Total:       req.Total,
+		Seasons:     req.Seasons,
+		UnitMinutes: req.UnitMinutes,
+		Description: req.Description,
+		ImageUrl:    req.ImageUrl,
+		ContentName: database.ContentName{
+			Id: contentNamesId,
+		},
+	}
+
+	err = h.dbsContent.UpdateContent(user, content)
+	if errors.Is(err, database.ErrInvalidUnit) {
+		log.Printf("error: handler content UpdateContent: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid unit or missing total",
+		})
+		return
+	} else if err != nil {
+		log.Printf("error: handler content UpdateContent: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{})
+}
+
+func (h *handlerContent) DeleteContent(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: handler content DeleteContent GetUser: user is nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type DeleteContentRequest struct {
+		ContentId *string `json:"content_id"`
+	}
+
+	var req DeleteContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: handler content DeleteContent Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: handler content DeleteContent: missing content id")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent: missing content id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := uuid.Validate(*req.ContentId); err != nil {
+		log.Printf("error: handler content DeleteContent: Validate: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent: Validate: WriteJson: %v", err)
+		}
+		return
+	}
+
+	id, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: handler content DeleteContent: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	allowed, err := canManageContent(h.dbsRelUsersContent, user, id, auth.PermContentDelete)
+	if err != nil {
+		log.Printf("error: handler content DeleteContent: canManageContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent: canManageContent: WriteJson: %v", err)
+		}
+		return
+	}
+	if !allowed {
+		log.Printf("error: handler content DeleteContent: not owner: %v", id)
+		if err := utils.WriteJson(w, http.StatusForbidden, utils.Envelope{
+			"error": "forbidden",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent: not owner: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqContent := &database.Content{
+		Id: id,
+	}
+	if err := h.dbsContent.DeleteContent(user, reqContent); err != nil {
+		log.Printf("error: handler content DeleteContent: DeleteContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: handler content DeleteContent: DeleteContent: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: handler content DeleteContent: Payload: WriteJson: %v", err)
+	}
+}
+
+// ClaimContent makes the calling admin the owner of content created before
+// owners were recorded.
+func (h *handlerContent) ClaimContent(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Content: ClaimContent: GetUser: user is nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: ClaimContent: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(r.PathValue("contentId"))
+	if err != nil {
+		log.Printf("error: Handler: Content: ClaimContent: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Content: ClaimContent: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	dbRel, err := h.dbsRelUsersContent.ClaimContent(user, reqContent)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Content: ClaimContent: ClaimContent: WriteJson: %v", err)
+		}
+		return
+	} else if errors.Is(err, database.ErrContentOwned) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "content already has an owner",
+		}); err != nil {
+			log.Printf("error: Handler: Content: ClaimContent: ClaimContent: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Content: ClaimContent: ClaimContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Content: ClaimContent: ClaimContent: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"owner": dbRel,
+	}); err != nil {
+		log.Printf("error: Handler: Content: ClaimContent: payload: WriteJson: %v", err)
+	}
+}
+
+// canManageContent allows roles granted perm and the owner of the content.
+func canManageContent(dbs database.DbsRelUsersContent, user *database.User, contentId uuid.UUID, perm auth.Permission) (bool, error) {
+	if auth.Can(user.Role, perm) {
+		return true, nil
+	}
+
+	dbRel, err := dbs.GetOwner(&database.Content{
+		Id: contentId,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return dbRel.UserId == user.Id, nil
+}
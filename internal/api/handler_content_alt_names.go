@@ -0,0 +1,284 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerContentAltNames interface {
+	AddAltName(w http.ResponseWriter, r *http.Request)
+	DeleteAltNames(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerContentAltNames struct {
+	dbsContentAltNames database.DbsContentAltNames
+	dbsRelUsersContent database.DbsRelUsersContent
+}
+
+var handlerContentAltNamesInstance *handlerContentAltNames
+
+func NewHandlerContentAltNames(dbsContentAltNames database.DbsContentAltNames, dbsRelUsersContent database.DbsRelUsersContent) HandlerContentAltNames {
+	if handlerContentAltNamesInstance != nil {
+		return handlerContentAltNamesInstance
+	}
+
+	newHandlerContentAltNames := &handlerContentAltNames{
+		dbsContentAltNames: dbsContentAltNames,
+		dbsRelUsersContent: dbsRelUsersContent,
+	}
+	handlerContentAltNamesInstance = newHandlerContentAltNames
+
+	return handlerContentAltNamesInstance
+}
+
+func (h *handlerContentAltNames) AddAltName(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: GetUser: user is nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type AddAltNameRequest struct {
+		ContentId       *string `json:"content_id"`
+		AlternativeName *string `json:"alternative_name"`
+		Language        *string `json:"language"`
+	}
+
+	req := AddAltNameRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Missing Content Id")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: Missing Content Id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.AlternativeName == nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Missing Alt Name")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: Missing Alt Name: WriteJson: %v", err)
+		}
+		return
+	}
+
+	newAltName := strings.TrimSpace(*req.AlternativeName)
+	if newAltName == "" {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Alt Name Blank")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: Alt Name Blank: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := uuid.Validate(*req.ContentId); err != nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Validate: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: Validate: WriteJson: %v", err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	var language *string
+	if req.Language != nil && strings.TrimSpace(*req.Language) != "" {
+		newLanguage := strings.ToLower(strings.TrimSpace(*req.Language))
+		if !database.ValidLanguage(newLanguage) {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: invalid language: %v", newLanguage)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid language",
+			}); err != nil {
+				log.Printf("error: Handler: ContentAltNames: AddAltName: invalid language: WriteJson: %v", err)
+			}
+			return
+		}
+		language = &newLanguage
+	}
+
+	reqContentAltName := database.ContentAltName{
+		ContentId: contentId,
+		ContentName: database.ContentName{
+			Name:     newAltName,
+			Language: language,
+		},
+	}
+	if err := h.dbsContentAltNames.AddAltName(user, &reqContentAltName); err != nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: AddAltName: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: AddAltName: AddAltName: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: ContentAltNames: AddAltName: Decode: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerContentAltNames) DeleteAltNames(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: GetUser: user is nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type DeleteAltNamesRequest struct {
+		ContentId       *string  `json:"content_id"`
+		ContentNamesIds []string `json:"content_names_ids"`
+	}
+
+	var req DeleteAltNamesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Missing Content Id")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Missing Content Id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := uuid.Validate(*req.ContentId); err != nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Validate: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Validate: WriteJson: %v", err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	allowed, err := canManageContent(h.dbsRelUsersContent, user, contentId, auth.PermAltNameDelete)
+	if err != nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: canManageContent: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: canManageContent: WriteJson: %v", err)
+		}
+		return
+	}
+	if !allowed {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: not owner: %v", contentId)
+		if err := utils.WriteJson(w, http.StatusForbidden, utils.Envelope{
+			"error": "forbidden",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: not owner: WriteJson: %v", err)
+		}
+		return
+	}
+
+	badIds := make([]string, 0)
+	reqAltNames := make([]*database.ContentAltName, 0)
+	for _, v := range req.ContentNamesIds {
+		if err := uuid.Validate(v); err != nil {
+			badIds = append(badIds, v)
+			continue
+		}
+
+		id, err := uuid.Parse(v)
+		if err != nil {
+			badIds = append(badIds, v)
+			continue
+		}
+
+		reqAltNames = append(reqAltNames, &database.ContentAltName{
+			ContentId: contentId,
+			ContentName: database.ContentName{
+				Id: id,
+			},
+		})
+	}
+
+	if err := h.dbsContentAltNames.DeleteAltNames(user, reqAltNames); err != nil && err != sql.ErrNoRows {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: DeleteAltNames: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: DeleteAltNames: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if len(badIds) > 0 {
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": fmt.Sprintf("failed to delete the following alternative names: %v", strings.Join(badIds, ", ")),
+		}); err != nil {
+			log.Printf("error: Handler: ContentAltNames: DeleteAltNames: DeleteAltNames: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: ContentAltNames: DeleteAltNames: DeleteAltNames: WriteJson: %v", err)
+	}
+}
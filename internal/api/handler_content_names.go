@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+)
+
+// TODO: maybe delete
+type HandlerContentNames interface {
+}
+
+type handlerContentNames struct {
+	dbsContentNames           database.DbsContentNames
+	dbsRelContentContentNames database.DbsContentAltNames
+}
+
+var handlerContentNamesInstance *handlerContentNames
+
+func NewHandlerContentNames(dbsContentNames database.DbsContentNames, dbsRelContentContentNames database.DbsContentAltNames) HandlerContentNames {
+	if handlerContentNamesInstance != nil {
+		return handlerContentNamesInstance
+	}
+
+	newHandlerContentNames := &handlerContentNames{
+		dbsContentNames:           dbsContentNames,
+		dbsRelContentContentNames: dbsRelContentContentNames,
+	}
+	handlerContentNamesInstance = newHandlerContentNames
+
+	return handlerContentNamesInstance
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerContentReviews interface {
+	GetQueue(w http.ResponseWriter, r *http.Request)
+	Approve(w http.ResponseWriter, r *http.Request)
+	Reject(w http.ResponseWriter, r *http.Request)
+	Merge(w http.ResponseWriter, r *http.Request)
+	MergeDuplicate(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerContentReviews struct {
+	dbsContentReviews database.DbsContentReviews
+}
+
+var handlerContentReviewsInstance *handlerContentReviews
+
+func NewHandlerContentReviews(dbsContentReviews database.DbsContentReviews) HandlerContentReviews {
+	if handlerContentReviewsInstance != nil {
+		return handlerContentReviewsInstance
+	}
+
+	newHandlerContentReviews := &handlerContentReviews{
+		dbsContentReviews: dbsContentReviews,
+	}
+	handlerContentReviewsInstance = newHandlerContentReviews
+
+	return handlerContentReviewsInstance
+}
+
+type reviewRequest struct {
+	Reason   *string `json:"reason"`
+	TargetId *string `json:"target_id"`
+}
+
+func (h *handlerContentReviews) GetQueue(w http.ResponseWriter, r *http.Request) {
+	dbSubmissions, err := h.dbsContentReviews.GetPending()
+	if err != nil {
+		log.Printf("error: Handler: ContentReviews: GetQueue: GetPending: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: GetQueue: GetPending: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"submissions": dbSubmissions,
+	}); err != nil {
+		log.Printf("error: Handler: ContentReviews: GetQueue: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerContentReviews) Approve(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "Approve")
+}
+
+func (h *handlerContentReviews) Reject(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "Reject")
+}
+
+func (h *handlerContentReviews) Merge(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "Merge")
+}
+
+// MergeDuplicate folds any content, not only pending submissions, into
+// target_id.
+func (h *handlerContentReviews) MergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "MergeDuplicate")
+}
+
+// review decodes the shared request of the queue actions and runs action.
+func (h *handlerContentReviews) review(w http.ResponseWriter, r *http.Request, action string) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: ContentReviews: %v: GetUser: user is nil", action)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: GetUser: WriteJson: %v", action, err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(r.PathValue("contentId"))
+	if err != nil {
+		log.Printf("error: Handler: ContentReviews: %v: Parse: %v", action, err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: Parse: WriteJson: %v", action, err)
+		}
+		return
+	}
+
+	var req reviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: Decode: %v", action, err)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "bad request",
+			}); err != nil {
+				log.Printf("error: Handler: ContentReviews: %v: Decode: WriteJson: %v", action, err)
+			}
+			return
+		}
+	}
+
+	reason := ""
+	if req.Reason != nil {
+		reason = strings.TrimSpace(*req.Reason)
+	}
+
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	switch action {
+	case "Approve":
+		var approveReason *string
+		if reason != "" {
+			approveReason = &reason
+		}
+		err = h.dbsContentReviews.Approve(user, reqContent, approveReason)
+	case "Reject":
+		err = h.dbsContentReviews.Reject(user, reqContent, reason)
+	case "Merge", "MergeDuplicate":
+		if req.TargetId == nil {
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "missing target_id",
+			}); err != nil {
+				log.Printf("error: Handler: ContentReviews: %v: missing target: WriteJson: %v", action, err)
+			}
+			return
+		}
+		targetId, parseErr := uuid.Parse(*req.TargetId)
+		if parseErr != nil {
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid target_id",
+			}); err != nil {
+				log.Printf("error: Handler: ContentReviews: %v: invalid target: WriteJson: %v", action, err)
+			}
+			return
+		}
+		reqTarget := &database.Content{
+			Id: targetId,
+		}
+		if action == "Merge" {
+			err = h.dbsContentReviews.Merge(user, reqContent, reqTarget, reason)
+		} else {
+			err = h.dbsContentReviews.MergeDuplicate(user, reqContent, reqTarget)
+		}
+	}
+
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: WriteJson: %v", action, err)
+		}
+		return
+	} else if errors.Is(err, database.ErrNotPending) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "content is not pending review",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: WriteJson: %v", action, err)
+		}
+		return
+	} else if errors.Is(err, database.ErrMissingReason) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "reason is required",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: WriteJson: %v", action, err)
+		}
+		return
+	} else if errors.Is(err, database.ErrInvalidMergeTarget) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "target must be other approved content",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: WriteJson: %v", action, err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: ContentReviews: %v: %v", action, err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentReviews: %v: WriteJson: %v", action, err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: ContentReviews: %v: payload: WriteJson: %v", action, err)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerContentRevisions interface {
+	GetRevisions(w http.ResponseWriter, r *http.Request)
+	Revert(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerContentRevisions struct {
+	dbsContentRevisions database.DbsContentRevisions
+}
+
+var handlerContentRevisionsInstance *handlerContentRevisions
+
+func NewHandlerContentRevisions(dbsContentRevisions database.DbsContentRevisions) HandlerContentRevisions {
+	if handlerContentRevisionsInstance != nil {
+		return handlerContentRevisionsInstance
+	}
+
+	newHandlerContentRevisions := &handlerContentRevisions{
+		dbsContentRevisions: dbsContentRevisions,
+	}
+	handlerContentRevisionsInstance = newHandlerContentRevisions
+
+	return handlerContentRevisionsInstance
+}
+
+func (h *handlerContentRevisions) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	contentId, err := uuid.Parse(r.PathValue("contentId"))
+	if err != nil {
+		log.Printf("error: Handler: ContentRevisions: GetRevisions: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: GetRevisions: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	user := utils.GetUser(r)
+	if user == nil {
+		user = database.AnonymousUser
+	}
+
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	dbRevisions, err := h.dbsContentRevisions.GetRevisions(user, reqContent)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: GetRevisions: GetRevisions: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: ContentRevisions: GetRevisions: GetRevisions: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: GetRevisions: GetRevisions: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"revisions": dbRevisions,
+	}); err != nil {
+		log.Printf("error: Handler: ContentRevisions: GetRevisions: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerContentRevisions) Revert(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: ContentRevisions: Revert: GetUser: user is nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: Revert: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(r.PathValue("contentId"))
+	if err != nil {
+		log.Printf("error: Handler: ContentRevisions: Revert: Parse content id: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: Revert: Parse content id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	revisionId, err := uuid.Parse(r.PathValue("revisionId"))
+	if err != nil {
+		log.Printf("error: Handler: ContentRevisions: Revert: Parse revision id: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: Revert: Parse revision id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqRevision := &database.ContentRevision{
+		Id:        revisionId,
+		ContentId: contentId,
+	}
+	dbRevision, err := h.dbsContentRevisions.Revert(user, reqRevision)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: Revert: Revert: WriteJson: %v", err)
+		}
+		return
+	} else if errors.Is(err, database.ErrNothingToRevert) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "cannot revert the revision that created the content",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: Revert: Revert: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: ContentRevisions: Revert: Revert: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: ContentRevisions: Revert: Revert: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"revision": dbRevision,
+	}); err != nil {
+		log.Printf("error: Handler: ContentRevisions: Revert: payload: WriteJson: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/tokens"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+)
+
+const (
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// EnvVerificationUri is the page of the web client where users enter the
+	// code shown by the device.
+	EnvVerificationUri     = "WHATDOING_DEVICE_VERIFICATION_URI"
+	defaultVerificationUri = "http://localhost:5173/device"
+)
+
+type HandlerDevice interface {
+	RequestCode(w http.ResponseWriter, r *http.Request)
+	Approve(w http.ResponseWriter, r *http.Request)
+	Token(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerDevice struct {
+	dbsDeviceCodes database.DbsDeviceCodes
+	dbsJwt         database.DbsJwt
+}
+
+var handlerDeviceInstance *handlerDevice
+
+func NewHandlerDevice(dbsDeviceCodes database.DbsDeviceCodes, dbsJwt database.DbsJwt) HandlerDevice {
+	if handlerDeviceInstance != nil {
+		return handlerDeviceInstance
+	}
+
+	newHandlerDevice := &handlerDevice{
+		dbsDeviceCodes: dbsDeviceCodes,
+		dbsJwt:         dbsJwt,
+	}
+	handlerDeviceInstance = newHandlerDevice
+
+	return handlerDeviceInstance
+}
+
+// RequestCode starts the device authorization grant (RFC 8628).
+func (h *handlerDevice) RequestCode(w http.ResponseWriter, r *http.Request) {
+	type CodeRequest struct {
+		ClientName *string `json:"client_name"`
+	}
+
+	var req CodeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Printf("error: Handler: Device: RequestCode: Decode: %v", err)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid_request",
+			}); err != nil {
+				log.Printf("error: Handler: Device: RequestCode: Decode: WriteJson: %v", err)
+			}
+			return
+		}
+	}
+
+	reqCode := &database.DeviceCode{
+		ClientName: req.ClientName,
+	}
+	dbCode, err := h.dbsDeviceCodes.CreateCode(reqCode)
+	if err != nil {
+		log.Printf("error: Handler: Device: RequestCode: CreateCode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Device: RequestCode: CreateCode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	verificationUri := os.Getenv(EnvVerificationUri)
+	if verificationUri == "" {
+		verificationUri = defaultVerificationUri
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"device_code":               dbCode.DeviceCode,
+		"user_code":                 dbCode.UserCode,
+		"verification_uri":          verificationUri,
+		"verification_uri_complete": verificationUri + "?user_code=" + dbCode.UserCode,
+		"expires_in":                int(time.Until(dbCode.ExpiresAt).Seconds()),
+		"interval":                  dbCode.PollInterval,
+	}); err != nil {
+		log.Printf("error: Handler: Device: RequestCode: payload: WriteJson: %v", err)
+	}
+}
+
+// Approve lets the signed in user approve or deny the code their device shows.
+func (h *handlerDevice) Approve(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Device: Approve: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Approve: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type ApproveRequest struct {
+		UserCode *string `json:"user_code"`
+		Approve  *bool   `json:"approve"`
+	}
+
+	var req ApproveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Device: Approve: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Approve: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.UserCode == nil {
+		log.Printf("error: Handler: Device: Approve: missing user code")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Approve: missing user code: WriteJson: %v", err)
+		}
+		return
+	}
+
+	approve := true
+	if req.Approve != nil {
+		approve = *req.Approve
+	}
+
+	reqCode := &database.DeviceCode{
+		UserCode: *req.UserCode,
+	}
+	err := h.dbsDeviceCodes.ApproveCode(user, reqCode, approve)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "unknown or expired code",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Approve: ApproveCode: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Device: Approve: ApproveCode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Approve: ApproveCode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: Device: Approve: payload: WriteJson: %v", err)
+	}
+}
+
+// Token is polled by the device. Errors use the OAuth 2.0 error codes the
+// RFC asks for, so standard clients know whether to keep polling.
+func (h *handlerDevice) Token(w http.ResponseWriter, r *http.Request) {
+	type TokenRequest struct {
+		GrantType  string `json:"grant_type"`
+		DeviceCode string `json:"device_code"`
+	}
+
+	var req TokenRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Printf("error: Handler: Device: Token: Decode: %v", err)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid_request",
+			}); err != nil {
+				log.Printf("error: Handler: Device: Token: Decode: WriteJson: %v", err)
+			}
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			log.Printf("error: Handler: Device: Token: ParseForm: %v", err)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid_request",
+			}); err != nil {
+				log.Printf("error: Handler: Device: Token: ParseForm: WriteJson: %v", err)
+			}
+			return
+		}
+		req.GrantType = r.PostForm.Get("grant_type")
+		req.DeviceCode = r.PostForm.Get("device_code")
+	}
+
+	if req.GrantType != GrantTypeDeviceCode {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "unsupported_grant_type",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Token: grant type: WriteJson: %v", err)
+		}
+		return
+	}
+	if req.DeviceCode == "" {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid_request",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Token: missing device code: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqCode := &database.DeviceCode{
+		DeviceCode: req.DeviceCode,
+	}
+	dbUser, dbCode, err := h.dbsDeviceCodes.ExchangeCode(reqCode)
+	if err != nil {
+		oauthErr := ""
+		switch {
+		case errors.Is(err, database.ErrAuthorizationPending):
+			oauthErr = "authorization_pending"
+		case errors.Is(err, database.ErrSlowDown):
+			oauthErr = "slow_down"
+		case errors.Is(err, database.ErrAccessDenied):
+			oauthErr = "access_denied"
+		case errors.Is(err, database.ErrDeviceCodeExpired):
+			oauthErr = "expired_token"
+		case err == sql.ErrNoRows:
+			oauthErr = "invalid_grant"
+		}
+		if oauthErr != "" {
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": oauthErr,
+			}); err != nil {
+				log.Printf("error: Handler: Device: Token: ExchangeCode: WriteJson: %v", err)
+			}
+			return
+		}
+
+		log.Printf("error: Handler: Device: Token: ExchangeCode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Token: ExchangeCode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqSession := &database.Session{
+		DeviceName: dbCode.ClientName,
+		UserAgent:  r.UserAgent(),
+		IpAddress:  utils.ClientIp(r),
+	}
+	token, err := h.dbsJwt.Insert(dbUser, reqSession, tokens.TtlToken, tokens.TtlRefresh, tokens.ScopeAuthenticate)
+	if err != nil {
+		log.Printf("error: Handler: Device: Token: Insert: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Device: Token: Insert: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, tokenResponse(token)); err != nil {
+		log.Printf("error: Handler: Device: Token: payload: WriteJson: %v", err)
+	}
+}
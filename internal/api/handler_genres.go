@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerGenres interface {
+	GetAllGenres(w http.ResponseWriter, r *http.Request)
+	SetContentGenres(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerGenres struct {
+	dbsGenres database.DbsGenres
+}
+
+var handlerGenresInstance *handlerGenres
+
+func NewHandlerGenres(dbsGenres database.DbsGenres) HandlerGenres {
+	if handlerGenresInstance != nil {
+		return handlerGenresInstance
+	}
+
+	newHandlerGenres := &handlerGenres{
+		dbsGenres: dbsGenres,
+	}
+	handlerGenresInstance = newHandlerGenres
+
+	return handlerGenresInstance
+}
+
+func (h *handlerGenres) GetAllGenres(w http.ResponseWriter, r *http.Request) {
+	dbGenres, err := h.dbsGenres.GetAllGenres()
+	if err != nil {
+		log.Printf("error: Handler: Genres: GetAllGenres: GetAllGenres: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Genres: GetAllGenres: GetAllGenres: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"genres": dbGenres,
+	}); err != nil {
+		log.Printf("error: Handler: Genres: GetAllGenres: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerGenres) SetContentGenres(w http.ResponseWriter, r *http.Request) {
+	contentIdStr := r.PathValue("contentId")
+	contentId, err := uuid.Parse(contentIdStr)
+	if err != nil {
+		log.Printf("error: Handler: Genres: SetContentGenres: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Genres: SetContentGenres: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type GenresRequest struct {
+		Genres []string `json:"genres"`
+	}
+
+	var req GenresRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Genres: SetContentGenres: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Genres: SetContentGenres: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	dbGenres, err := h.dbsGenres.SetContentGenres(reqContent, req.Genres)
+	if errors.Is(err, database.ErrUnknownGenre) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "unknown genre",
+		}); err != nil {
+			log.Printf("error: Handler: Genres: SetContentGenres: SetContentGenres: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Genres: SetContentGenres: SetContentGenres: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Genres: SetContentGenres: SetContentGenres: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"genres": dbGenres,
+	}); err != nil {
+		log.Printf("error: Handler: Genres: SetContentGenres: payload: WriteJson: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -34,43 +37,81 @@ func NewHandlerJwt(dbs database.DbsJwt) HandlerJwt {
 }
 
 func (h *handlerJwt) RefreshJwt(w http.ResponseWriter, r *http.Request) {
-	user := utils.GetUser(r)
-	if user == nil {
-		log.Printf("error: Handler: Jwt: RefreshJwt: GetUser: user nil")
-		err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
-			"error": "bad request",
-		})
-		log.Printf("error: Handler: Jwt: RefreshJwt: GetUser: WriteJson: %v", err)
-		return
+	// the access token may already be expired, the refresh token is what
+	// counts. Browsers send it as a cookie, the cli in the body.
+	type RefreshRequest struct {
+		RefreshToken *string `json:"refresh_token"`
 	}
 
-	dbJwt, err := h.dbsJwt.Get(user)
-	if err != nil {
-		log.Printf("error: Handler: Jwt: RefreshJwt: Get: %v", err)
-		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
-			"error": "internal server error",
-		})
-		log.Printf("error: Handler: Jwt: RefreshJwt: Get: WriteJson: %v", err)
-		return
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Printf("error: Handler: Jwt: RefreshJwt: Decode: %v", err)
+			err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "bad request",
+			})
+			log.Printf("error: Handler: Jwt: RefreshJwt: Decode: WriteJson: %v", err)
+			return
+		}
+	}
+
+	fromBody := req.RefreshToken != nil
+	reqJwt := &tokens.Jwt{
+		RefreshToken: &tokens.Token{},
+	}
+	if fromBody {
+		reqJwt.RefreshToken.PlainText = *req.RefreshToken
+	} else {
+		cookie, err := r.Cookie("whatdoing-jwt-refresh")
+		if err != nil {
+			log.Printf("error: Handler: Jwt: RefreshJwt: Cookie: %v", err)
+			err := utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{
+				"error": "must be logged in",
+			})
+			log.Printf("error: Handler: Jwt: RefreshJwt: Cookie: WriteJson: %v", err)
+			return
+		}
+
+		err = cookie.Valid()
+		if err != nil {
+			log.Printf("error: Handler: Jwt: RefreshJwt: Cookie: Valid: %v", err)
+			err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "bad request",
+			})
+			log.Printf("error: Handler: Jwt: RefreshJwt: Cookie: Valid: WriteJson: %v", err)
+			return
+		}
+		reqJwt.RefreshToken.PlainText = cookie.Value
 	}
 
-	expired := time.Now().After(dbJwt.RefreshToken.Expiry)
-	if expired {
-		log.Printf("error: Handler: Jwt: RefreshJwt: token expired")
+	reqSession := &database.Session{
+		UserAgent: r.UserAgent(),
+		IpAddress: utils.ClientIp(r),
+	}
+	newJwt, err := h.dbsJwt.Refresh(reqJwt, reqSession, tokens.TtlToken, tokens.TtlRefresh)
+	if err == sql.ErrNoRows || errors.Is(err, tokens.ErrExpiredToken) || errors.Is(err, database.ErrRefreshReused) {
+		log.Printf("error: Handler: Jwt: RefreshJwt: Refresh: %v", err)
+		utils.DeleteCookie(w, "whatdoing-jwt")
+		utils.DeleteCookie(w, "whatdoing-jwt-refresh")
+		err := utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{
+			"error": "must be logged in",
+		})
+		log.Printf("error: Handler: Jwt: RefreshJwt: Refresh: WriteJson: %v", err)
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Jwt: RefreshJwt: Refresh: %v", err)
 		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
 			"error": "internal server error",
 		})
-		log.Printf("error: Handler: Jwt: RefreshJwt: token expired: WriteJson: %v", err)
+		log.Printf("error: Handler: Jwt: RefreshJwt: Refresh: WriteJson: %v", err)
 		return
 	}
 
-	newJwt, err := h.dbsJwt.Insert(user.Id, time.Hour*12, time.Hour*24, tokens.ScopeAuthenticate)
-	if err != nil {
-		log.Printf("error: Handler: Jwt: RefreshJwt: Insert: %v", err)
-		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
-			"error": "internal server error",
-		})
-		log.Printf("error: Handler: Jwt: RefreshJwt: Insert: WriteJson: %v", err)
+	if fromBody {
+		err = utils.WriteJson(w, http.StatusOK, tokenResponse(newJwt))
+		if err != nil {
+			log.Printf("error: Handler: Jwt: RefreshJwt: payload: WriteJson: %v", err)
+		}
 		return
 	}
 
@@ -81,3 +122,13 @@ func (h *handlerJwt) RefreshJwt(w http.ResponseWriter, r *http.Request) {
 		log.Printf("error: Handler: Jwt: RefreshJwt: payload: WriteJson: %v", err)
 	}
 }
+
+// tokenResponse is the OAuth 2.0 token response, for clients without cookies.
+func tokenResponse(jwt *tokens.Jwt) utils.Envelope {
+	return utils.Envelope{
+		"access_token":  jwt.Token.PlainText,
+		"token_type":    "Bearer",
+		"expires_in":    int(time.Until(jwt.Token.Expiry).Seconds()),
+		"refresh_token": jwt.RefreshToken.PlainText,
+	}
+}
@@ -0,0 +1,576 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerLists interface {
+	GetLists(w http.ResponseWriter, r *http.Request)
+	GetList(w http.ResponseWriter, r *http.Request)
+	CreateList(w http.ResponseWriter, r *http.Request)
+	UpdateList(w http.ResponseWriter, r *http.Request)
+	DeleteList(w http.ResponseWriter, r *http.Request)
+	AddItem(w http.ResponseWriter, r *http.Request)
+	MoveItem(w http.ResponseWriter, r *http.Request)
+	RemoveItem(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerLists struct {
+	dbsLists database.DbsLists
+}
+
+var handlerListsInstance *handlerLists
+
+func NewHandlerLists(dbsLists database.DbsLists) HandlerLists {
+	if handlerListsInstance != nil {
+		return handlerListsInstance
+	}
+
+	newHandlerLists := &handlerLists{
+		dbsLists: dbsLists,
+	}
+	handlerListsInstance = newHandlerLists
+
+	return handlerListsInstance
+}
+
+type listRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Public      bool    `json:"public"`
+}
+
+func (h *handlerLists) GetLists(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: GetLists: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: GetLists: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	dbLists, err := h.dbsLists.GetLists(user)
+	if err != nil {
+		log.Printf("error: Handler: Lists: GetLists: GetLists: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: GetLists: GetLists: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"lists": dbLists,
+	}); err != nil {
+		log.Printf("error: Handler: Lists: GetLists: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) GetList(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: GetList: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: GetList: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	listId, err := uuid.Parse(r.PathValue("listId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: GetList: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: GetList: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqList := &database.List{
+		Id: listId,
+	}
+	dbList, err := h.dbsLists.GetList(user, reqList)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: GetList: GetList: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: GetList: GetList: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: GetList: GetList: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"list": dbList,
+	}); err != nil {
+		log.Printf("error: Handler: Lists: GetList: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) CreateList(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: CreateList: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: CreateList: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	var req listRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Lists: CreateList: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: CreateList: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.Name == nil {
+		log.Printf("error: Handler: Lists: CreateList: missing name")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: CreateList: missing name: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqList := &database.List{
+		Name:        *req.Name,
+		Description: req.Description,
+		Public:      req.Public,
+	}
+	dbList, err := h.dbsLists.CreateList(user, reqList)
+	if errors.Is(err, database.ErrInvalidListName) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid list name",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: CreateList: CreateList: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: CreateList: CreateList: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: CreateList: CreateList: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"list": dbList,
+	}); err != nil {
+		log.Printf("error: Handler: Lists: CreateList: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) UpdateList(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: UpdateList: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	listId, err := uuid.Parse(r.PathValue("listId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: UpdateList: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	var req listRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Lists: UpdateList: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.Name == nil {
+		log.Printf("error: Handler: Lists: UpdateList: missing name")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: missing name: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqList := &database.List{
+		Id:          listId,
+		Name:        *req.Name,
+		Description: req.Description,
+		Public:      req.Public,
+	}
+	dbList, err := h.dbsLists.UpdateList(user, reqList)
+	if errors.Is(err, database.ErrInvalidListName) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid list name",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: UpdateList: WriteJson: %v", err)
+		}
+		return
+	} else if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: UpdateList: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: UpdateList: UpdateList: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: UpdateList: UpdateList: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"list": dbList,
+	}); err != nil {
+		log.Printf("error: Handler: Lists: UpdateList: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) DeleteList(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: DeleteList: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: DeleteList: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	listId, err := uuid.Parse(r.PathValue("listId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: DeleteList: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: DeleteList: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqList := &database.List{
+		Id: listId,
+	}
+	err = h.dbsLists.DeleteList(user, reqList)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: DeleteList: DeleteList: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: DeleteList: DeleteList: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: DeleteList: DeleteList: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: Lists: DeleteList: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) AddItem(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: AddItem: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	listId, err := uuid.Parse(r.PathValue("listId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: AddItem: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type AddItemRequest struct {
+		ContentId *string `json:"content_id"`
+	}
+
+	var req AddItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Lists: AddItem: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: Handler: Lists: AddItem: missing content id")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: missing content id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: Handler: Lists: AddItem: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqList := &database.List{
+		Id: listId,
+	}
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	dbItem, err := h.dbsLists.AddItem(user, reqList, reqContent)
+	if errors.Is(err, database.ErrListItemExists) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "content already on list",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: AddItem: WriteJson: %v", err)
+		}
+		return
+	} else if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: AddItem: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: AddItem: AddItem: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: AddItem: AddItem: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"item": dbItem,
+	}); err != nil {
+		log.Printf("error: Handler: Lists: AddItem: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) MoveItem(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: MoveItem: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	listId, err := uuid.Parse(r.PathValue("listId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: MoveItem: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	itemId, err := uuid.Parse(r.PathValue("itemId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: MoveItem: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	// a null after_item_id moves the item to the front of the list
+	type MoveItemRequest struct {
+		AfterItemId *string `json:"after_item_id"`
+	}
+
+	var req MoveItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Lists: MoveItem: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	var after *uuid.UUID
+	if req.AfterItemId != nil {
+		afterId, err := uuid.Parse(*req.AfterItemId)
+		if err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: Parse: %v", err)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "bad request",
+			}); err != nil {
+				log.Printf("error: Handler: Lists: MoveItem: Parse: WriteJson: %v", err)
+			}
+			return
+		}
+		after = &afterId
+	}
+
+	reqItem := &database.ListItem{
+		Id:     itemId,
+		ListId: listId,
+	}
+	dbItem, err := h.dbsLists.MoveItem(user, reqItem, after)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: MoveItem: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: MoveItem: MoveItem: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: MoveItem: MoveItem: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"item": dbItem,
+	}); err != nil {
+		log.Printf("error: Handler: Lists: MoveItem: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerLists) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Lists: RemoveItem: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: RemoveItem: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	listId, err := uuid.Parse(r.PathValue("listId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: RemoveItem: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: RemoveItem: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	itemId, err := uuid.Parse(r.PathValue("itemId"))
+	if err != nil {
+		log.Printf("error: Handler: Lists: RemoveItem: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: RemoveItem: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqItem := &database.ListItem{
+		Id:     itemId,
+		ListId: listId,
+	}
+	err = h.dbsLists.RemoveItem(user, reqItem)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: RemoveItem: RemoveItem: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Lists: RemoveItem: RemoveItem: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Lists: RemoveItem: RemoveItem: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: Lists: RemoveItem: payload: WriteJson: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerPersonalTokens interface {
+	GetTokens(w http.ResponseWriter, r *http.Request)
+	CreateToken(w http.ResponseWriter, r *http.Request)
+	DeleteToken(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerPersonalTokens struct {
+	dbsPersonalTokens database.DbsPersonalTokens
+}
+
+var handlerPersonalTokensInstance *handlerPersonalTokens
+
+func NewHandlerPersonalTokens(dbsPersonalTokens database.DbsPersonalTokens) HandlerPersonalTokens {
+	if handlerPersonalTokensInstance != nil {
+		return handlerPersonalTokensInstance
+	}
+
+	newHandlerPersonalTokens := &handlerPersonalTokens{
+		dbsPersonalTokens: dbsPersonalTokens,
+	}
+	handlerPersonalTokensInstance = newHandlerPersonalTokens
+
+	return handlerPersonalTokensInstance
+}
+
+func (h *handlerPersonalTokens) GetTokens(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: PersonalTokens: GetTokens: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: GetTokens: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	dbTokens, err := h.dbsPersonalTokens.GetTokens(user)
+	if err != nil {
+		log.Printf("error: Handler: PersonalTokens: GetTokens: GetTokens: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: GetTokens: GetTokens: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"tokens": dbTokens,
+	}); err != nil {
+		log.Printf("error: Handler: PersonalTokens: GetTokens: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerPersonalTokens) CreateToken(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: PersonalTokens: CreateToken: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	// without expires_in_days the token never expires
+	type CreateRequest struct {
+		Name          *string  `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays *int     `json:"expires_in_days"`
+	}
+
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: PersonalTokens: CreateToken: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.Name == nil {
+		log.Printf("error: Handler: PersonalTokens: CreateToken: missing name")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: missing name: WriteJson: %v", err)
+		}
+		return
+	}
+
+	var ttl *time.Duration
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays <= 0 {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: invalid expires_in_days: %v", *req.ExpiresInDays)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid expires_in_days",
+			}); err != nil {
+				log.Printf("error: Handler: PersonalTokens: CreateToken: invalid expires_in_days: WriteJson: %v", err)
+			}
+			return
+		}
+		v := time.Hour * 24 * time.Duration(*req.ExpiresInDays)
+		ttl = &v
+	}
+
+	reqToken := &database.PersonalToken{
+		Name:   *req.Name,
+		Scopes: req.Scopes,
+	}
+	dbToken, err := h.dbsPersonalTokens.CreateToken(user, reqToken, ttl)
+	if errors.Is(err, database.ErrInvalidTokenName) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid name",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: CreateToken: WriteJson: %v", err)
+		}
+		return
+	} else if errors.Is(err, database.ErrInvalidScope) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid scopes",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: CreateToken: WriteJson: %v", err)
+		}
+		return
+	} else if errors.Is(err, database.ErrTokenNameExists) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "token name already in use",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: CreateToken: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: PersonalTokens: CreateToken: CreateToken: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: CreateToken: CreateToken: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"token": dbToken,
+	}); err != nil {
+		log.Printf("error: Handler: PersonalTokens: CreateToken: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerPersonalTokens) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: PersonalTokens: DeleteToken: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: DeleteToken: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	tokenId, err := uuid.Parse(r.PathValue("tokenId"))
+	if err != nil {
+		log.Printf("error: Handler: PersonalTokens: DeleteToken: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: DeleteToken: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqToken := &database.PersonalToken{
+		Id: tokenId,
+	}
+	err = h.dbsPersonalTokens.DeleteToken(user, reqToken)
+	if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: DeleteToken: DeleteToken: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: PersonalTokens: DeleteToken: DeleteToken: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: PersonalTokens: DeleteToken: DeleteToken: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: PersonalTokens: DeleteToken: payload: WriteJson: %v", err)
+	}
+}
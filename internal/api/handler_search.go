@@ -0,0 +1,60 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+)
+
+type HandlerSearch interface {
+	Suggest(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerSearch struct {
+	dbsSuggestions database.DbsSuggestions
+}
+
+var handlerSearchInstance *handlerSearch
+
+func NewHandlerSearch(dbsSuggestions database.DbsSuggestions) HandlerSearch {
+	if handlerSearchInstance != nil {
+		return handlerSearchInstance
+	}
+
+	newHandlerSearch := &handlerSearch{
+		dbsSuggestions: dbsSuggestions,
+	}
+	handlerSearchInstance = newHandlerSearch
+
+	return handlerSearchInstance
+}
+
+func (h *handlerSearch) Suggest(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()
+
+	limit := database.SUGGEST_LIMIT_DEFAULT
+	if limitStr := queries.Get("limit"); limitStr != "" {
+		v, err := strconv.Atoi(limitStr)
+		if err != nil || v <= 0 {
+			log.Printf("error: Handler: Search: Suggest: invalid limit: %v", limitStr)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid limit",
+			}); err != nil {
+				log.Printf("error: Handler: Search: Suggest: invalid limit: WriteJson: %v", err)
+			}
+			return
+		}
+		limit = v
+	}
+
+	suggestions := h.dbsSuggestions.Suggest(queries.Get("q"), limit)
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"suggestions": suggestions,
+	}); err != nil {
+		log.Printf("error: Handler: Search: Suggest: payload: WriteJson: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerTags interface {
+	GetAllTags(w http.ResponseWriter, r *http.Request)
+	SetContentTags(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerTags struct {
+	dbsTags database.DbsTags
+}
+
+var handlerTagsInstance *handlerTags
+
+func NewHandlerTags(dbsTags database.DbsTags) HandlerTags {
+	if handlerTagsInstance != nil {
+		return handlerTagsInstance
+	}
+
+	newHandlerTags := &handlerTags{
+		dbsTags: dbsTags,
+	}
+	handlerTagsInstance = newHandlerTags
+
+	return handlerTagsInstance
+}
+
+func (h *handlerTags) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Tags: GetAllTags: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: GetAllTags: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	dbTags, err := h.dbsTags.GetAllTags(user)
+	if err != nil {
+		log.Printf("error: Handler: Tags: GetAllTags: GetAllTags: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: GetAllTags: GetAllTags: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"tags": dbTags,
+	}); err != nil {
+		log.Printf("error: Handler: Tags: GetAllTags: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerTags) SetContentTags(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Tags: SetContentTags: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: SetContentTags: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type TagsRequest struct {
+		ContentId *string  `json:"content_id"`
+		Tags      []string `json:"tags"`
+	}
+
+	var req TagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Tags: SetContentTags: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: SetContentTags: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: Handler: Tags: SetContentTags: missing content id")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: SetContentTags: missing content id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	contentId, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: Handler: Tags: SetContentTags: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: SetContentTags: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	dbTags, err := h.dbsTags.SetContentTags(user, reqContent, req.Tags)
+	if errors.Is(err, database.ErrInvalidTag) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid tag",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: SetContentTags: SetContentTags: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Tags: SetContentTags: SetContentTags: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Tags: SetContentTags: SetContentTags: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"tags": dbTags,
+	}); err != nil {
+		log.Printf("error: Handler: Tags: SetContentTags: payload: WriteJson: %v", err)
+	}
+}
@@ -0,0 +1,858 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
+)
+
+type HandlerProgressContent interface {
+	AddToLibrary(w http.ResponseWriter, r *http.Request)
+	GetProgress(w http.ResponseWriter, r *http.Request)
+	SetProgress(w http.ResponseWriter, r *http.Request)
+	SetStatus(w http.ResponseWriter, r *http.Request)
+	SetReview(w http.ResponseWriter, r *http.Request)
+	SetDates(w http.ResponseWriter, r *http.Request)
+	SetPriority(w http.ResponseWriter, r *http.Request)
+	RemoveProgress(w http.ResponseWriter, r *http.Request)
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	Undo(w http.ResponseWriter, r *http.Request)
+	Next(w http.ResponseWriter, r *http.Request)
+}
+
+type handlerProgressContent struct {
+	dbsUserLibrary     database.DbsUserLibrary
+	dbsProgressContent database.DbsProgressContent
+}
+
+var handlerProgressContentInstance *handlerProgressContent
+
+func NewHandlerProgressContent(dbsUserLibrary database.DbsUserLibrary, dbsRelContentUserLibrary database.DbsProgressContent) HandlerProgressContent {
+	if handlerProgressContentInstance != nil {
+		return handlerProgressContentInstance
+	}
+	newHandlerProgressContent := &handlerProgressContent{
+		dbsUserLibrary:     dbsUserLibrary,
+		dbsProgressContent: dbsRelContentUserLibrary,
+	}
+	handlerProgressContentInstance = newHandlerProgressContent
+
+	return handlerProgressContentInstance
+}
+
+func (h *handlerProgressContent) AddToLibrary(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: AddToLibrary: GetUser: nil")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	type AddToLibraryRequest struct {
+		ContentId *string `json:"content_id"`
+	}
+
+	var req AddToLibraryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: AddToLibrary: Decode: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	if req.ContentId == nil {
+		log.Printf("error: Handler: UserLibraryContent: AddToLibrary: missing content id")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	err = uuid.Validate(*req.ContentId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: AddToLibrary: Validate: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	contentId, err := uuid.Parse(*req.ContentId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: AddToLibrary: Parse: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	reqContent := &database.Content{
+		Id: contentId,
+	}
+	dbRelContentUserLibrary, err := h.dbsProgressContent.AddToLibrary(user, reqContent)
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "content not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: AddToLibrary: AddToLibrary: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"progress": dbRelContentUserLibrary,
+	})
+}
+
+func (h *handlerProgressContent) GetProgress(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: GetProgress: GetUser: nil")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	opts := make([]database.OptionsFunc, 0)
+	queries := r.URL.Query()
+
+	status := queries.Get("status")
+	search := queries.Get("search")
+	sort := queries.Get("sort")
+	kind := queries.Get("kind")
+	if kind != "" && !database.ValidKind(kind) {
+		log.Printf("error: Handler: UserLibraryContent: GetProgress: invalid kind: %v", kind)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid kind",
+		})
+		return
+	}
+	opts = append(opts, database.WithKind(kind))
+	opts = append(opts, database.WithStatus(status))
+	opts = append(opts, database.WithSearch(search))
+	opts = append(opts, database.WithSort(sort))
+	opts = append(opts, database.WithSortBy(queries.Get("sort_by")))
+	opts = append(opts, database.WithGenres(strings.Split(queries.Get("genres"), ","), queries.Get("genres_mode")))
+	opts = append(opts, database.WithTags(strings.Split(queries.Get("tags"), ","), queries.Get("tags_mode")))
+	if minScore := queries.Get("min_score"); minScore != "" {
+		opts = append(opts, database.WithMinScore(minScore))
+	}
+	if maxScore := queries.Get("max_score"); maxScore != "" {
+		opts = append(opts, database.WithMaxScore(maxScore))
+	}
+
+	progressIdStr := queries.Get("progress_id")
+	contentIdStr := queries.Get("content_id")
+	err := uuid.Validate(progressIdStr)
+	if err == nil {
+		id, err := uuid.Parse(progressIdStr)
+		if err == nil {
+			opts = append(opts, database.WithProgressId(id))
+		}
+	}
+	err = uuid.Validate(contentIdStr)
+	if err == nil {
+		id, err := uuid.Parse(contentIdStr)
+		if err == nil {
+			opts = append(opts, database.WithContentId(id))
+		}
+	}
+
+	progress, err := h.dbsProgressContent.GetProgress(user, opts...)
+	if err == sql.ErrNoRows {
+		progress = make([]*database.ProgressContent, 0)
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: GetProgress: GetProgress: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	progressContent := make([]*database.Content, 0)
+	for _, v := range progress {
+		progressContent = append(progressContent, v.Content)
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"progress": progress,
+		"facets":   database.ContentFacets(progressContent),
+	})
+}
+
+func (h *handlerProgressContent) SetProgress(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type UpdateRequest struct {
+		ProgressId *string `json:"progress_id"`
+		Value      *int    `json:"value"`
+		Season     *int    `json:"season"`
+	}
+
+	var req UpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ProgressId == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: missing progress id")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: missing progress id: WriteJson: %v", err)
+		}
+		return
+	}
+	err = uuid.Validate(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: Validate: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: Validate: WriteJson: %v", err)
+		}
+		return
+	}
+	progressId, err := uuid.Parse(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.Value == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: missing value")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: missing value: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqRelContentUserLibrary := &database.ProgressContent{
+		Id:     progressId,
+		Value:  *req.Value,
+		Season: req.Season,
+	}
+	_, err = h.dbsProgressContent.UpdateProgress(user, reqRelContentUserLibrary)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: UpdateProgress: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetProgress: UpdateProgress: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetProgress: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) SetStatus(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetStatus: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type StatusRequest struct {
+		ProgressId *string `json:"progress_id"`
+		Status     *string `json:"status"`
+	}
+
+	var req StatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetStatus: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ProgressId == nil || req.Status == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetStatus: missing progress id or status")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: missing progress id or status: WriteJson: %v", err)
+		}
+		return
+	}
+
+	progressId, err := uuid.Parse(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetStatus: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	status := strings.ToLower(strings.TrimSpace(*req.Status))
+	reqProgress := &database.ProgressContent{
+		Id:           progressId,
+		StoredStatus: &status,
+	}
+	dbProgress, err := h.dbsProgressContent.UpdateStatus(user, reqProgress)
+	if errors.Is(err, database.ErrInvalidStatusTransition) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "invalid status transition",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: UpdateStatus: WriteJson: %v", err)
+		}
+		return
+	} else if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: UpdateStatus: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetStatus: UpdateStatus: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetStatus: UpdateStatus: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"progress": dbProgress,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetStatus: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) SetReview(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetReview: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	// the score, review and notes replace the stored ones, null clears them
+	type ReviewRequest struct {
+		ProgressId   *string `json:"progress_id"`
+		Score        *int    `json:"score"`
+		Review       *string `json:"review"`
+		ReviewPublic bool    `json:"review_public"`
+		Notes        *string `json:"notes"`
+	}
+
+	var req ReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetReview: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ProgressId == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetReview: missing progress id")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: missing progress id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	progressId, err := uuid.Parse(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetReview: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqProgress := &database.ProgressContent{
+		Id:           progressId,
+		Score:        req.Score,
+		Review:       req.Review,
+		ReviewPublic: req.ReviewPublic,
+		Notes:        req.Notes,
+	}
+	dbProgress, err := h.dbsProgressContent.UpdateReview(user, reqProgress)
+	if errors.Is(err, database.ErrInvalidScore) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid score",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: UpdateReview: WriteJson: %v", err)
+		}
+		return
+	} else if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: UpdateReview: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetReview: UpdateReview: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetReview: UpdateReview: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"progress": dbProgress,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetReview: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) SetDates(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetDates: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	// the dates replace the stored ones, null clears them
+	type DatesRequest struct {
+		ProgressId  *string    `json:"progress_id"`
+		StartedAt   *time.Time `json:"started_at"`
+		CompletedAt *time.Time `json:"completed_at"`
+		RepeatCount *int       `json:"repeat_count"`
+	}
+
+	var req DatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetDates: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ProgressId == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetDates: missing progress id")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: missing progress id: WriteJson: %v", err)
+		}
+		return
+	}
+
+	progressId, err := uuid.Parse(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetDates: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqProgress := &database.ProgressContent{
+		Id:          progressId,
+		StartedAt:   req.StartedAt,
+		CompletedAt: req.CompletedAt,
+	}
+	dbProgress, err := h.dbsProgressContent.UpdateDates(user, reqProgress, req.RepeatCount)
+	if errors.Is(err, database.ErrInvalidDates) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid dates",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: UpdateDates: WriteJson: %v", err)
+		}
+		return
+	} else if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: UpdateDates: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetDates: UpdateDates: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetDates: UpdateDates: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"progress": dbProgress,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetDates: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) SetPriority(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetPriority: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type PriorityRequest struct {
+		ProgressId *string `json:"progress_id"`
+		Priority   *int    `json:"priority"`
+	}
+
+	var req PriorityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetPriority: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if req.ProgressId == nil || req.Priority == nil {
+		log.Printf("error: Handler: UserLibraryContent: SetPriority: missing progress id or priority")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: missing progress id or priority: WriteJson: %v", err)
+		}
+		return
+	}
+
+	progressId, err := uuid.Parse(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetPriority: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqProgress := &database.ProgressContent{
+		Id:       progressId,
+		Priority: *req.Priority,
+	}
+	dbProgress, err := h.dbsProgressContent.UpdatePriority(user, reqProgress)
+	if errors.Is(err, database.ErrInvalidPriority) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid priority",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: UpdatePriority: WriteJson: %v", err)
+		}
+		return
+	} else if err == sql.ErrNoRows {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: UpdatePriority: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetPriority: UpdatePriority: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: SetPriority: UpdatePriority: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"progress": dbProgress,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: SetPriority: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) RemoveProgress(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: RemoveProgress: GetUser: nil")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	type UpdateRequest struct {
+		ProgressId *string `json:"progress_id"`
+	}
+
+	var req UpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: RemoveProgress: Decode: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	if req.ProgressId == nil {
+		log.Printf("error: Handler: UserLibraryContent: RemoveProgress: missing progress id")
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		return
+	}
+
+	err = uuid.Validate(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: RemoveProgress: Validate: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	progressId, err := uuid.Parse(*req.ProgressId)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: RemoveProgress: Parse: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	reqRelContentUserLibrary := &database.ProgressContent{
+		Id: progressId,
+	}
+	err = h.dbsProgressContent.RemoveProgress(user, reqRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: RemoveProgress: RemoveProgress: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{})
+}
+
+func (h *handlerProgressContent) GetHistory(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: GetHistory: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: GetHistory: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	progressId, err := uuid.Parse(r.PathValue("progressId"))
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: GetHistory: Parse: %v", err)
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: GetHistory: Parse: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqProgress := &database.ProgressContent{
+		Id: progressId,
+	}
+	dbEvents, err := h.dbsProgressContent.GetHistory(user, reqProgress)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: GetHistory: GetHistory: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: GetHistory: GetHistory: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"history": dbEvents,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: GetHistory: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) Undo(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: Undo: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: Undo: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	dbEvent, err := h.dbsProgressContent.Undo(user)
+	if errors.Is(err, database.ErrNothingToUndo) {
+		if err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "nothing to undo",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: Undo: Undo: WriteJson: %v", err)
+		}
+		return
+	} else if errors.Is(err, database.ErrUndoConflict) {
+		if err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "entry changed since the last recorded change, cannot undo",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: Undo: Undo: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: Undo: Undo: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: Undo: Undo: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"undo": dbEvent,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: Undo: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerProgressContent) Next(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: UserLibraryContent: Next: GetUser: nil")
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: Next: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	queries := r.URL.Query()
+
+	// minutes is the time the user has right now, entries are weighed against it
+	var budgetMinutes *int
+	if minutesStr := queries.Get("minutes"); minutesStr != "" {
+		minutes, err := strconv.Atoi(minutesStr)
+		if err != nil || minutes <= 0 {
+			log.Printf("error: Handler: UserLibraryContent: Next: invalid minutes: %v", minutesStr)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid minutes",
+			}); err != nil {
+				log.Printf("error: Handler: UserLibraryContent: Next: invalid minutes: WriteJson: %v", err)
+			}
+			return
+		}
+		budgetMinutes = &minutes
+	}
+
+	limit := database.NEXT_LIMIT_DEFAULT
+	if limitStr := queries.Get("limit"); limitStr != "" {
+		v, err := strconv.Atoi(limitStr)
+		if err != nil || v <= 0 {
+			log.Printf("error: Handler: UserLibraryContent: Next: invalid limit: %v", limitStr)
+			if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+				"error": "invalid limit",
+			}); err != nil {
+				log.Printf("error: Handler: UserLibraryContent: Next: invalid limit: WriteJson: %v", err)
+			}
+			return
+		}
+		limit = v
+	}
+
+	dbRecommendations, err := h.dbsProgressContent.GetNext(user, budgetMinutes, limit)
+	if err != nil {
+		log.Printf("error: Handler: UserLibraryContent: Next: GetNext: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: UserLibraryContent: Next: GetNext: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"next": dbRecommendations,
+	}); err != nil {
+		log.Printf("error: Handler: UserLibraryContent: Next: payload: WriteJson: %v", err)
+	}
+}
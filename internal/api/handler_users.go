@@ -1,14 +1,16 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/JustinLi007/whatdoing-server/internal/database"
 	"github.com/JustinLi007/whatdoing-server/internal/tokens"
 	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
 )
 
 type HandlerUsers interface {
@@ -16,6 +18,12 @@ type HandlerUsers interface {
 	Login(w http.ResponseWriter, r *http.Request)
 	CheckSession(w http.ResponseWriter, r *http.Request)
 	Logout(w http.ResponseWriter, r *http.Request)
+	GetSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeOtherSessions(w http.ResponseWriter, r *http.Request)
+	GetUsers(w http.ResponseWriter, r *http.Request)
+	SetRole(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
 }
 
 type handlerUsers struct {
@@ -41,9 +49,10 @@ func NewHandlerUsers(dbsUsers database.DbsUsers, dbsJwt database.DbsJwt) Handler
 
 func (h *handlerUsers) SignUp(w http.ResponseWriter, r *http.Request) {
 	type ValidateRequest struct {
-		Email    *string `json:"email"`
-		Username *string `json:"username"`
-		Password *string `json:"password"`
+		Email      *string `json:"email"`
+		Username   *string `json:"username"`
+		Password   *string `json:"password"`
+		DeviceName *string `json:"device_name"`
 	}
 
 	var req ValidateRequest
@@ -94,7 +103,12 @@ func (h *handlerUsers) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.dbsJwt.Insert(createdUser.Id, time.Hour*12, time.Hour*24, tokens.ScopeAuthenticate)
+	reqSession := &database.Session{
+		DeviceName: req.DeviceName,
+		UserAgent:  r.UserAgent(),
+		IpAddress:  utils.ClientIp(r),
+	}
+	token, err := h.dbsJwt.Insert(createdUser, reqSession, tokens.TtlToken, tokens.TtlRefresh, tokens.ScopeAuthenticate)
 	if err != nil {
 		log.Printf("error: handler_users CreateUser dbs.CreateToken: %v", err)
 		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
@@ -114,8 +128,9 @@ func (h *handlerUsers) SignUp(w http.ResponseWriter, r *http.Request) {
 
 func (h *handlerUsers) Login(w http.ResponseWriter, r *http.Request) {
 	type ValidateRequest struct {
-		Email    *string `json:"email"`
-		Password *string `json:"password"`
+		Email      *string `json:"email"`
+		Password   *string `json:"password"`
+		DeviceName *string `json:"device_name"`
 	}
 
 	var req ValidateRequest
@@ -165,7 +180,12 @@ func (h *handlerUsers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.dbsJwt.Insert(existingUser.Id, time.Hour*12, time.Hour*24, tokens.ScopeAuthenticate)
+	reqSession := &database.Session{
+		DeviceName: req.DeviceName,
+		UserAgent:  r.UserAgent(),
+		IpAddress:  utils.ClientIp(r),
+	}
+	token, err := h.dbsJwt.Insert(existingUser, reqSession, tokens.TtlToken, tokens.TtlRefresh, tokens.ScopeAuthenticate)
 	if err != nil {
 		log.Printf("error: handler_users Login dbs.Insert: %v", err)
 		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
@@ -193,8 +213,18 @@ func (h *handlerUsers) CheckSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the jwt only carries the id and role
+	dbUser, err := h.dbsUsers.GetUserById(user.Id)
+	if err != nil {
+		log.Printf("error: handler_users CheckSession GetUserById: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		return
+	}
+
 	utils.WriteJson(w, http.StatusOK, utils.Envelope{
-		"user": user,
+		"user": dbUser,
 	})
 }
 
@@ -209,30 +239,28 @@ func (h *handlerUsers) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cookie, err := r.Cookie("whatdoing-jwt")
-	if err != nil {
-		log.Printf("error: Handler: Users: Logout: Cookie: whatdoing-jwt: cookie nil")
+	claims := utils.GetClaims(r)
+	if claims == nil {
+		log.Printf("error: Handler: Users: Logout: GetClaims: claims nil")
 		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
 			"error": "internal server error",
 		})
-		log.Printf("error: Handler: Users: Logout: Cookie: WriteJson: %v", err)
+		log.Printf("error: Handler: Users: Logout: GetClaims: WriteJson: %v", err)
 		return
 	}
 
-	err = cookie.Valid()
+	jti, err := uuid.Parse(claims.Id)
 	if err != nil {
-		log.Printf("error: Handler: Users: Logout: Cookie: Valid: %v", err)
+		log.Printf("error: Handler: Users: Logout: Parse: %v", err)
 		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
 			"error": "internal server error",
 		})
-		log.Printf("error: Handler: Users: Logout: Cookie: Valid: WriteJson: %v", err)
+		log.Printf("error: Handler: Users: Logout: Parse: WriteJson: %v", err)
 		return
 	}
 
 	reqJwt := &tokens.Jwt{
-		Token: &tokens.Token{
-			PlainText: cookie.Value,
-		},
+		Jti: jti,
 	}
 	err = h.dbsJwt.Delete(user, reqJwt)
 	if err != nil {
@@ -251,3 +279,307 @@ func (h *handlerUsers) Logout(w http.ResponseWriter, r *http.Request) {
 		log.Printf("error: Handler: Users: Logout: payload: WriteJson: %v", err)
 	}
 }
+
+func (h *handlerUsers) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Users: UpdateSettings: GetUser: user nil")
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Users: UpdateSettings: GetUser: WriteJson: %v", err)
+		}
+		return
+	}
+
+	type SettingsRequest struct {
+		ScoreScale *int `json:"score_scale"`
+	}
+
+	var req SettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error: Handler: Users: UpdateSettings: Decode: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Users: UpdateSettings: Decode: WriteJson: %v", err)
+		}
+		return
+	}
+
+	// the jwt only carries the id and role, unset settings keep their
+	// stored value
+	dbUser, err := h.dbsUsers.GetUserById(user.Id)
+	if err != nil {
+		log.Printf("error: Handler: Users: UpdateSettings: GetUserById: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Users: UpdateSettings: GetUserById: WriteJson: %v", err)
+		}
+		return
+	}
+
+	reqUser := &database.User{
+		Id:         user.Id,
+		ScoreScale: dbUser.ScoreScale,
+	}
+	if req.ScoreScale != nil {
+		reqUser.ScoreScale = *req.ScoreScale
+	}
+
+	dbUser, err = h.dbsUsers.UpdateSettings(reqUser)
+	if errors.Is(err, database.ErrInvalidScoreScale) {
+		if err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid score scale",
+		}); err != nil {
+			log.Printf("error: Handler: Users: UpdateSettings: UpdateSettings: WriteJson: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Users: UpdateSettings: UpdateSettings: %v", err)
+		if err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		}); err != nil {
+			log.Printf("error: Handler: Users: UpdateSettings: UpdateSettings: WriteJson: %v", err)
+		}
+		return
+	}
+
+	if err := utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"user": dbUser,
+	}); err != nil {
+		log.Printf("error: Handler: Users: UpdateSettings: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerUsers) GetSessions(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Users: GetSessions: GetUser: user nil")
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: GetSessions: GetUser: WriteJson: %v", err)
+		return
+	}
+
+	dbSessions, err := h.dbsJwt.GetSessions(user)
+	if err != nil {
+		log.Printf("error: Handler: Users: GetSessions: GetSessions: %v", err)
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: GetSessions: GetSessions: WriteJson: %v", err)
+		return
+	}
+
+	if claims := utils.GetClaims(r); claims != nil {
+		for _, v := range dbSessions {
+			v.Current = v.Id.String() == claims.SessionId
+		}
+	}
+
+	err = utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"sessions": dbSessions,
+	})
+	if err != nil {
+		log.Printf("error: Handler: Users: GetSessions: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerUsers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Users: RevokeSession: GetUser: user nil")
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: RevokeSession: GetUser: WriteJson: %v", err)
+		return
+	}
+
+	sessionId, err := uuid.Parse(r.PathValue("sessionId"))
+	if err != nil {
+		log.Printf("error: Handler: Users: RevokeSession: Parse: %v", err)
+		err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		log.Printf("error: Handler: Users: RevokeSession: Parse: WriteJson: %v", err)
+		return
+	}
+
+	reqSession := &database.Session{
+		Id: sessionId,
+	}
+	err = h.dbsJwt.DeleteSession(user, reqSession)
+	if err == sql.ErrNoRows {
+		err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		})
+		log.Printf("error: Handler: Users: RevokeSession: DeleteSession: WriteJson: %v", err)
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Users: RevokeSession: DeleteSession: %v", err)
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: RevokeSession: DeleteSession: WriteJson: %v", err)
+		return
+	}
+
+	if claims := utils.GetClaims(r); claims != nil && claims.SessionId == sessionId.String() {
+		utils.DeleteCookie(w, "whatdoing-jwt")
+		utils.DeleteCookie(w, "whatdoing-jwt-refresh")
+	}
+	err = utils.WriteJson(w, http.StatusOK, utils.Envelope{})
+	if err != nil {
+		log.Printf("error: Handler: Users: RevokeSession: payload: WriteJson: %v", err)
+	}
+}
+
+// RevokeOtherSessions logs the user out everywhere but the calling session.
+// The access tokens of the revoked sessions stop working right away.
+func (h *handlerUsers) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUser(r)
+	if user == nil {
+		log.Printf("error: Handler: Users: RevokeOtherSessions: GetUser: user nil")
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: RevokeOtherSessions: GetUser: WriteJson: %v", err)
+		return
+	}
+
+	claims := utils.GetClaims(r)
+	if claims == nil {
+		log.Printf("error: Handler: Users: RevokeOtherSessions: GetClaims: claims nil")
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: RevokeOtherSessions: GetClaims: WriteJson: %v", err)
+		return
+	}
+
+	sessionId, err := uuid.Parse(claims.SessionId)
+	if err != nil {
+		log.Printf("error: Handler: Users: RevokeOtherSessions: Parse: %v", err)
+		err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		log.Printf("error: Handler: Users: RevokeOtherSessions: Parse: WriteJson: %v", err)
+		return
+	}
+
+	reqSession := &database.Session{
+		Id: sessionId,
+	}
+	err = h.dbsJwt.DeleteOtherSessions(user, reqSession)
+	if err != nil {
+		log.Printf("error: Handler: Users: RevokeOtherSessions: DeleteOtherSessions: %v", err)
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: RevokeOtherSessions: DeleteOtherSessions: WriteJson: %v", err)
+		return
+	}
+
+	err = utils.WriteJson(w, http.StatusOK, utils.Envelope{})
+	if err != nil {
+		log.Printf("error: Handler: Users: RevokeOtherSessions: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerUsers) GetUsers(w http.ResponseWriter, r *http.Request) {
+	dbUsers, err := h.dbsUsers.GetUsers()
+	if err != nil {
+		log.Printf("error: Handler: Users: GetUsers: GetUsers: %v", err)
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: GetUsers: GetUsers: WriteJson: %v", err)
+		return
+	}
+
+	err = utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"users": dbUsers,
+	})
+	if err != nil {
+		log.Printf("error: Handler: Users: GetUsers: payload: WriteJson: %v", err)
+	}
+}
+
+func (h *handlerUsers) SetRole(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		log.Printf("error: Handler: Users: SetRole: Parse: %v", err)
+		err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		log.Printf("error: Handler: Users: SetRole: Parse: WriteJson: %v", err)
+		return
+	}
+
+	type RoleRequest struct {
+		Role *string `json:"role"`
+	}
+
+	var req RoleRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("error: Handler: Users: SetRole: Decode: %v", err)
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: SetRole: Decode: WriteJson: %v", err)
+		return
+	}
+
+	if req.Role == nil {
+		log.Printf("error: Handler: Users: SetRole: missing role")
+		err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "bad request",
+		})
+		log.Printf("error: Handler: Users: SetRole: missing role: WriteJson: %v", err)
+		return
+	}
+
+	reqUser := &database.User{
+		Id:   userId,
+		Role: *req.Role,
+	}
+	dbUser, err := h.dbsUsers.UpdateRole(reqUser)
+	if errors.Is(err, database.ErrInvalidRole) {
+		err := utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{
+			"error": "invalid role",
+		})
+		log.Printf("error: Handler: Users: SetRole: UpdateRole: WriteJson: %v", err)
+		return
+	} else if errors.Is(err, database.ErrLastAdmin) {
+		err := utils.WriteJson(w, http.StatusConflict, utils.Envelope{
+			"error": "cannot remove the last admin",
+		})
+		log.Printf("error: Handler: Users: SetRole: UpdateRole: WriteJson: %v", err)
+		return
+	} else if err == sql.ErrNoRows {
+		err := utils.WriteJson(w, http.StatusNotFound, utils.Envelope{
+			"error": "not found",
+		})
+		log.Printf("error: Handler: Users: SetRole: UpdateRole: WriteJson: %v", err)
+		return
+	} else if err != nil {
+		log.Printf("error: Handler: Users: SetRole: UpdateRole: %v", err)
+		err := utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{
+			"error": "internal server error",
+		})
+		log.Printf("error: Handler: Users: SetRole: UpdateRole: WriteJson: %v", err)
+		return
+	}
+
+	err = utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"user": dbUser,
+	})
+	if err != nil {
+		log.Printf("error: Handler: Users: SetRole: payload: WriteJson: %v", err)
+	}
+}
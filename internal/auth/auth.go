@@ -1 +1,52 @@
 package auth
+
+const (
+	RoleRegular   = "regular"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+
+	// EnvAdminEmail names the account promoted to admin on startup while the
+	// server has no admin yet.
+	EnvAdminEmail = "WHATDOING_ADMIN_EMAIL"
+)
+
+type Permission string
+
+const (
+	PermContentCreate Permission = "content:create"
+	PermContentEdit   Permission = "content:edit"
+	PermContentDelete Permission = "content:delete"
+	PermAltNameDelete Permission = "altname:delete"
+	PermContentClaim  Permission = "content:claim"
+	PermContentRevert Permission = "content:revert"
+	PermContentReview Permission = "content:review"
+	PermContentMerge  Permission = "content:merge"
+	PermUsersManage   Permission = "users:manage"
+)
+
+// permissions is the permission matrix, which roles may do what. Anything
+// not listed is open to every signed in user. Owners of a content row may
+// also edit and delete it, see api.canManageContent.
+var permissions = map[Permission]map[string]bool{
+	PermContentCreate: {RoleRegular: true, RoleModerator: true, RoleAdmin: true},
+	PermContentEdit:   {RoleModerator: true, RoleAdmin: true},
+	PermContentDelete: {RoleModerator: true, RoleAdmin: true},
+	PermAltNameDelete: {RoleModerator: true, RoleAdmin: true},
+	PermContentClaim:  {RoleAdmin: true},
+	PermContentRevert: {RoleModerator: true, RoleAdmin: true},
+	PermContentReview: {RoleModerator: true, RoleAdmin: true},
+	PermContentMerge:  {RoleAdmin: true},
+	PermUsersManage:   {RoleAdmin: true},
+}
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleRegular, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func Can(role string, perm Permission) bool {
+	return permissions[perm][role]
+}
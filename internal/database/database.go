@@ -84,5 +84,62 @@ func Migrate(db *sql.DB, dir string) error {
 		return fmt.Errorf("migrate: %v", err)
 	}
 
+	if err := BackfillSearchNames(db); err != nil {
+		return fmt.Errorf("migrate: BackfillSearchNames: %v", err)
+	}
+
 	return nil
 }
+
+// BackfillSearchNames fills in the search forms of names stored before
+// content_names had them. Folding and romanizing live in Go, so migrations
+// cannot do it.
+func BackfillSearchNames(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: database BackfillSearchNames: Rollback: %v", err)
+		}
+	}()
+
+	rows, err := tx.Query(`SELECT id, name FROM content_names WHERE search_name IS NULL OR romaji IS NULL FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+
+	names := make([]*ContentName, 0)
+	for rows.Next() {
+		name := &ContentName{}
+		if err := rows.Scan(&name.Id, &name.Name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, v := range names {
+		searchName, romaji, script := nameForms(v.Name)
+		if _, err := tx.Exec(
+			`UPDATE content_names SET search_name = $2, romaji = $3, script = $4 WHERE id = $1`,
+			v.Id,
+			searchName,
+			romaji,
+			script,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,969 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JustinLi007/whatdoing-server/internal/algo"
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	KIND_ANIME = "anime"
+	KIND_BOOK  = "book"
+	KIND_GAME  = "game"
+	KIND_SHOW  = "show"
+	KIND_MOVIE = "movie"
+	KIND_MANGA = "manga"
+)
+
+// Content submitted by regular users stays pending, and visible to its
+// submitter only, until a moderator reviews it. Merged content is kept so the
+// submitter can see where it went.
+const (
+	CONTENT_PENDING  = "pending"
+	CONTENT_APPROVED = "approved"
+	CONTENT_REJECTED = "rejected"
+	CONTENT_MERGED   = "merged"
+)
+
+var contentKinds = map[string]bool{
+	KIND_ANIME: true,
+	KIND_BOOK:  true,
+	KIND_GAME:  true,
+	KIND_SHOW:  true,
+	KIND_MOVIE: true,
+	KIND_MANGA: true,
+}
+
+func ValidKind(kind string) bool {
+	return contentKinds[kind]
+}
+
+const (
+	UNIT_EPISODES = "episodes"
+	UNIT_PAGES    = "pages"
+	UNIT_CHAPTERS = "chapters"
+	UNIT_HOURS    = "hours"
+	UNIT_PERCENT  = "percent"
+	UNIT_MINUTES  = "minutes"
+
+	PERCENT_TOTAL = 100
+)
+
+// kindUnits lists the progress units each kind can be tracked in. The first
+// unit is the default. Shows also track a season alongside the episode count.
+var kindUnits = map[string][]string{
+	KIND_ANIME: {UNIT_EPISODES},
+	KIND_SHOW:  {UNIT_EPISODES},
+	KIND_BOOK:  {UNIT_PAGES, UNIT_CHAPTERS},
+	KIND_MANGA: {UNIT_CHAPTERS},
+	KIND_GAME:  {UNIT_HOURS, UNIT_PERCENT},
+	KIND_MOVIE: {UNIT_MINUTES, UNIT_PERCENT},
+}
+
+var ErrInvalidUnit = errors.New("invalid unit for kind")
+var ErrDuplicateName = errors.New("name already used by other content")
+
+// DUPLICATE_LIMIT caps how many likely duplicates are reported for a new entry.
+const DUPLICATE_LIMIT = 5
+
+func DefaultUnit(kind string) string {
+	units, ok := kindUnits[kind]
+	if !ok {
+		return UNIT_EPISODES
+	}
+	return units[0]
+}
+
+func ValidUnit(kind, unit string) bool {
+	for _, v := range kindUnits[kind] {
+		if v == unit {
+			return true
+		}
+	}
+	return false
+}
+
+func KindHasSeasons(kind string) bool {
+	return kind == KIND_SHOW
+}
+
+// UnitRequiresTotal reports whether content tracked in unit must declare a
+// total. Hours are open ended and percent is always out of PERCENT_TOTAL.
+func UnitRequiresTotal(unit string) bool {
+	return unit != UNIT_HOURS && unit != UNIT_PERCENT
+}
+
+// normalizeUnit fills in the default unit for the content kind and validates
+// the unit, total and seasons against it.
+func normalizeUnit(reqContent *Content) error {
+	if reqContent.Unit == "" {
+		reqContent.Unit = DefaultUnit(reqContent.Kind)
+	}
+	if !ValidUnit(reqContent.Kind, reqContent.Unit) {
+		return ErrInvalidUnit
+	}
+
+	if reqContent.Unit == UNIT_PERCENT {
+		total := PERCENT_TOTAL
+		reqContent.Total = &total
+	}
+	if reqContent.Total == nil && UnitRequiresTotal(reqContent.Unit) {
+		return ErrInvalidUnit
+	}
+	if !KindHasSeasons(reqContent.Kind) {
+		reqContent.Seasons = nil
+	}
+
+	// time based units know their own length
+	switch reqContent.Unit {
+	case UNIT_HOURS:
+		minutes := 60
+		reqContent.UnitMinutes = &minutes
+	case UNIT_MINUTES:
+		minutes := 1
+		reqContent.UnitMinutes = &minutes
+	}
+	if reqContent.UnitMinutes != nil && *reqContent.UnitMinutes <= 0 {
+		return ErrInvalidUnit
+	}
+
+	return nil
+}
+
+type Content struct {
+	Id               uuid.UUID      `json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	Kind             string         `json:"kind"`
+	Unit             string         `json:"unit"`
+	Total            *int           `json:"total"`
+	Seasons          *int           `json:"seasons"`
+	Description      *string        `json:"description"`
+	ImageUrl         *string        `json:"image_url"`
+	UnitMinutes      *int           `json:"unit_minutes"`
+	Status           string         `json:"status,omitempty"`
+	ReviewReason     *string        `json:"review_reason,omitempty"`
+	MergedIntoId     *uuid.UUID     `json:"merged_into_id,omitempty"`
+	Score            *float64       `json:"score,omitempty"`
+	MatchedName      *string        `json:"matched_name,omitempty"`
+	Highlights       []*Highlight   `json:"highlights,omitempty"`
+	ContentName      ContentName    `json:"content_name"`
+	AlternativeNames []*ContentName `json:"alternative_names"`
+	Genres           []string       `json:"genres"`
+	Tags             []string       `json:"tags,omitempty"`
+	Reviews          []*Review      `json:"reviews,omitempty"`
+}
+
+type DbsContent interface {
+	InsertContent(reqUser *User, reqContent *Content) (*Content, error)
+	GetContentById(reqUser *User, reqContent *Content) (*Content, error)
+	GetDuplicates(reqUser *User, reqContent *Content) ([]*Content, error)
+	GetAllContent(reqUser *User, opts ...OptionsFunc) ([]*Content, error)
+	UpdateContent(reqUser *User, reqContent *Content) error
+	DeleteContent(reqUser *User, reqContent *Content) error
+}
+
+type PgDbsContent struct {
+	db          DbService
+	suggestions DbsSuggestions
+}
+
+var dbsContentInstance *PgDbsContent
+
+func NewDbsContent(db DbService, suggestions DbsSuggestions) DbsContent {
+	if dbsContentInstance != nil {
+		return dbsContentInstance
+	}
+
+	newDbsContent := &PgDbsContent{
+		db:          db,
+		suggestions: suggestions,
+	}
+	dbsContentInstance = newDbsContent
+
+	return dbsContentInstance
+}
+
+// InsertContent records reqUser as the owner of the new content.
+func (d *PgDbsContent) InsertContent(reqUser *User, reqContent *Content) (*Content, error) {
+	if reqContent.Status == "" {
+		reqContent.Status = CONTENT_PENDING
+	}
+	if err := normalizeUnit(reqContent); err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: normalizeUnit: %v", err)
+		return nil, err
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Content: InsertContent: Rollback: %v", err)
+		}
+	}()
+
+	dbContentName, err := InsertContentNameIfNotExist(tx, &reqContent.ContentName)
+	if dbContentName != nil {
+		log.Printf("error: Dbs: Content: InsertContent: SelectAltNameByContentName: %v", errors.New(fmt.Sprintf("duplicate record found: '%v'", dbContentName.Name)))
+		return nil, ErrDuplicateName
+	} else if err != nil && err != sql.ErrNoRows {
+		log.Printf("error: Dbs: Content: InsertContent: SelectAltNameByContentName: %v", err)
+		return nil, err
+	}
+
+	dbContent, err := InsertContent(tx, reqContent)
+	if err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: InsertContent: %v", err)
+		return nil, err
+	}
+
+	err = InsertRelUsersContent(tx, &RelUsersContent{
+		UserId:    reqUser.Id,
+		ContentId: dbContent.Id,
+	})
+	if err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: InsertRelUsersContent: %v", err)
+		return nil, err
+	}
+
+	err = RecordContentRevision(tx, reqUser, REVISION_CREATED, dbContent.Id, nil)
+	if err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: RecordContentRevision: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: Commit: %v", err)
+		return nil, err
+	}
+	d.suggestions.Refresh(dbContent.Id)
+
+	return dbContent, nil
+}
+
+// GetDuplicates returns content of the same kind whose primary or alternative
+// names are close to the name of reqContent, using the matching of the search
+// in GetAllContent.
+func (d *PgDbsContent) GetDuplicates(reqUser *User, reqContent *Content) ([]*Content, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Content: GetDuplicates: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Content: GetDuplicates: Rollback: %v", err)
+		}
+	}()
+
+	// search_content shortlists the names that contain the name or are within
+	// 2 edits of it, findDuplicates then applies the stricter rules
+	target := algo.Fold(strings.TrimSpace(reqContent.ContentName.Name))
+	if target == "" {
+		return make([]*Content, 0), nil
+	}
+	search := &Search{
+		SearchValue: target,
+	}
+	contentList, err := SelectAllContentJoinName(tx, reqUser, &reqContent.Kind, search, SORT_ASC)
+	if err != nil {
+		log.Printf("error: Dbs: Content: GetDuplicates: SelectAllContentJoinName: %v", err)
+		return nil, err
+	}
+
+	allNames := make([]*ContentAltName, 0)
+	if len(contentList) > 0 {
+		allNames, err = SelectContentAltNames(tx, contentList)
+		if err != nil {
+			log.Printf("error: Dbs: Content: GetDuplicates: SelectContentAltNames: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Content: GetDuplicates: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	for _, v := range contentList {
+		if names, ok := namesMap[v.Id]; ok {
+			v.AlternativeNames = names
+		}
+	}
+
+	return findDuplicates(contentList, reqContent.ContentName.Name), nil
+}
+
+// GetContentById returns sql.ErrNoRows for content under review unless
+// reqUser submitted it or may review it.
+func (d *PgDbsContent) GetContentById(reqUser *User, reqContent *Content) (*Content, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsContent GetContentById: Rollback: %v", err)
+		}
+	}()
+
+	dbContent, err := SelectContentJoinName(tx, reqContent)
+	if err != nil {
+		log.Printf("error: DbsContent GetContentById: SelectContentJoinName: %v", err)
+		return nil, err
+	}
+
+	if dbContent.Status != CONTENT_APPROVED && !auth.Can(reqUser.Role, auth.PermContentReview) {
+		dbOwner, err := SelectContentOwner(tx, dbContent)
+		if err != nil {
+			return nil, err
+		}
+		if dbOwner.UserId != reqUser.Id {
+			return nil, sql.ErrNoRows
+		}
+	}
+
+	temp := []*Content{dbContent}
+	allNames, err := SelectContentAltNames(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsContent GetContentById: SelectAllNamesByContentId: %v", err)
+		return nil, err
+	}
+
+	allGenres, err := SelectContentGenres(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsContent GetContentById: SelectContentGenres: %v", err)
+		return nil, err
+	}
+	dbContent.Genres = buildGenresMap(allGenres)[dbContent.Id]
+
+	dbReviews, err := SelectPublicReviews(tx, dbContent)
+	if err != nil {
+		log.Printf("error: DbsContent GetContentById: SelectPublicReviews: %v", err)
+		return nil, err
+	}
+	dbContent.Reviews = dbReviews
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsContent GetContentById: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	if altNames, ok := namesMap[dbContent.Id]; ok {
+		dbContent.AlternativeNames = altNames
+	} else {
+		dbContent.AlternativeNames = make([]*ContentName, 0)
+	}
+
+	return dbContent, nil
+}
+
+func (d *PgDbsContent) GetAllContent(reqUser *User, opts ...OptionsFunc) ([]*Content, error) {
+	var err error
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsContent GetAllContent: Rollback: %v", err)
+		}
+	}()
+
+	options := NewOptions()
+	for _, v := range opts {
+		v(options)
+	}
+
+	orderBy := SORT_ASC
+	if options.Sort != nil {
+		orderBy = options.Sort.SortValue
+	}
+
+	var kind *string
+	if options.Kind != nil {
+		kind = &options.Kind.KindValue
+	}
+
+	var msg string
+	contentList := make([]*Content, 0)
+	if options.IgnoreInLibrary && reqUser != nil {
+		if contentList, err = SelectContentNotInLibrary(tx, reqUser, kind, options.Search, orderBy); err != nil {
+			msg = fmt.Sprintf("error: Dbs: Content: GetAllContent: SelectContentNotInLibrary: %v", err)
+		}
+	} else {
+		viewer := reqUser
+		if viewer == nil {
+			viewer = AnonymousUser
+		}
+		if contentList, err = SelectAllContentJoinName(tx, viewer, kind, options.Search, orderBy); err != nil {
+			msg = fmt.Sprintf("error: Dbs: Content: GetAllContent: SelectAllContentJoinName: %v", err)
+		}
+	}
+	if err != nil {
+		log.Printf("%v", msg)
+		return nil, err
+	}
+
+	allNames, err := SelectContentAltNames(tx, contentList)
+	if err != nil {
+		log.Printf("error: Dbs: Content: GetAllContent: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	allGenres, err := SelectContentGenres(tx, contentList)
+	if err != nil {
+		log.Printf("error: Dbs: Content: GetAllContent: SelectContentGenres: %v", err)
+		return nil, err
+	}
+
+	allTags := make([]*ContentTag, 0)
+	if reqUser != nil && reqUser != AnonymousUser {
+		allTags, err = SelectContentTags(tx, reqUser, contentList)
+		if err != nil {
+			log.Printf("error: Dbs: Content: GetAllContent: SelectContentTags: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Content: GetAllContent: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	genresMap := buildGenresMap(allGenres)
+	tagsMap := buildTagsMap(allTags)
+	for k, v := range contentList {
+		if names, ok := namesMap[v.Id]; ok {
+			contentList[k].AlternativeNames = names
+		}
+		contentList[k].Genres = genresMap[v.Id]
+		contentList[k].Tags = tagsMap[v.Id]
+	}
+
+	if options.Search != nil {
+		rankContent(contentList, options.Search.SearchValue)
+	}
+
+	if options.Genres != nil || options.Tags != nil {
+		filteredContentList := make([]*Content, 0)
+		for _, v := range contentList {
+			if filterContentByFacets(v, options) {
+				filteredContentList = append(filteredContentList, v)
+			}
+		}
+		contentList = filteredContentList
+	}
+
+	return contentList, nil
+}
+
+func (d *PgDbsContent) UpdateContent(reqUser *User, reqContent *Content) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsContent UpdateContent: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsContent UpdateContent: Rollback: %v", err)
+		}
+	}()
+
+	before, err := SelectContentSnapshot(tx, reqContent.Id)
+	if err != nil {
+		log.Printf("error: DbsContent UpdateContent: SelectContentSnapshot: %v", err)
+		return err
+	}
+
+	dbContent, err := SelectContentJoinName(tx, reqContent)
+	if err != nil {
+		log.Printf("error: DbsContent UpdateContent: SelectContentJoinName: %v", err)
+		return err
+	}
+
+	reqContent.Kind = dbContent.Kind
+	if reqContent.Unit == "" {
+		reqContent.Unit = dbContent.Unit
+	}
+	if err := normalizeUnit(reqContent); err != nil {
+		log.Printf("error: DbsContent UpdateContent: normalizeUnit: %v", err)
+		return err
+	}
+
+	if err := UpdateContentById(tx, reqContent); err != nil {
+		log.Printf("error: DbsContent UpdateContent: UpdateContentById: %v", err)
+		return err
+	}
+
+	if err := RecordContentRevision(tx, reqUser, REVISION_UPDATED, reqContent.Id, before); err != nil {
+		log.Printf("error: DbsContent UpdateContent: RecordContentRevision: %v", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsContent UpdateContent: Commit: %v", err)
+		return err
+	}
+	d.suggestions.Refresh(reqContent.Id)
+
+	return nil
+}
+
+func (d *PgDbsContent) DeleteContent(reqUser *User, reqContent *Content) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Content: DeleteContent: Rollback: %v", err)
+		}
+	}()
+
+	err = DeleteContent(tx, reqUser, reqContent)
+	if err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: DeleteContent: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: Commit: %v", err)
+		return err
+	}
+	d.suggestions.Refresh(reqContent.Id)
+
+	return nil
+}
+
+func InsertContent(tx *sql.Tx, params *Content) (*Content, error) {
+	result := &Content{
+		ContentName:      ContentName{},
+		AlternativeNames: make([]*ContentName, 0),
+	}
+
+	query := `
+	WITH select_name AS (
+		SELECT an.id, an.created_at, an.updated_at, an.name
+		FROM content_names an
+		WHERE an.name = $5
+	), insert_content AS (
+		INSERT INTO content (id, total, description, image_url, content_names_id, kind, unit, seasons, unit_minutes, status)
+		SELECT $1, $2, $3, $4, select_name.id, $7, $8, $9, $10, $11
+		FROM select_name
+		RETURNING id, created_at, updated_at, kind, unit, total, seasons, description, image_url, unit_minutes, status, content_names_id
+	), insert_alt_name AS (
+		INSERT INTO rel_content_content_names (id, content_id, content_names_id)
+		SELECT $6, insert_content.id, insert_content.content_names_id
+		FROM insert_content
+	)
+	SELECT
+	a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes, a.status,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM insert_content a
+	JOIN select_name an ON a.content_names_id = an.id
+	`
+
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		params.Total,
+		params.Description,
+		params.ImageUrl,
+		params.ContentName.Name,
+		uuid.New(),
+		params.Kind,
+		params.Unit,
+		params.Seasons,
+		params.UnitMinutes,
+		params.Status,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Kind,
+		&result.Unit,
+		&result.Total,
+		&result.Seasons,
+		&result.Description,
+		&result.ImageUrl,
+		&result.UnitMinutes,
+		&result.Status,
+		&result.ContentName.Id,
+		&result.ContentName.CreatedAt,
+		&result.ContentName.UpdatedAt,
+		&result.ContentName.Name,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Content: InsertContent: Query: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectContentJoinName(tx *sql.Tx, params *Content) (*Content, error) {
+	existingContent := &Content{
+		ContentName: ContentName{},
+	}
+
+	query := `SELECT a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes, a.status, a.review_reason, a.merged_into_id, an.id, an.created_at, an.updated_at, an.name
+	FROM content a JOIN content_names an ON a.content_names_id = an.id
+	WHERE a.id = $1`
+
+	err := tx.QueryRow(
+		query,
+		params.Id,
+	).Scan(
+		&existingContent.Id,
+		&existingContent.CreatedAt,
+		&existingContent.UpdatedAt,
+		&existingContent.Kind,
+		&existingContent.Unit,
+		&existingContent.Total,
+		&existingContent.Seasons,
+		&existingContent.Description,
+		&existingContent.ImageUrl,
+		&existingContent.UnitMinutes,
+		&existingContent.Status,
+		&existingContent.ReviewReason,
+		&existingContent.MergedIntoId,
+		&existingContent.ContentName.Id,
+		&existingContent.ContentName.CreatedAt,
+		&existingContent.ContentName.UpdatedAt,
+		&existingContent.ContentName.Name,
+	)
+	if err != nil {
+		log.Printf("error: DbsContent SelectContentJoinName: Scan: %v", err)
+		return nil, err
+	}
+
+	return existingContent, nil
+}
+
+// SelectAllContentJoinName lists approved content and the submissions of
+// reqUser. With a search only content matched by search_content is listed,
+// with its score.
+func SelectAllContentJoinName(tx *sql.Tx, reqUser *User, kind *string, search *Search, orderBy string) ([]*Content, error) {
+	contentList := make([]*Content, 0)
+
+	query := fmt.Sprintf(`
+	SELECT a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes, a.status, a.review_reason, sm.score,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM content a
+	JOIN content_names an ON a.content_names_id = an.id
+	LEFT JOIN search_content($3, $4) sm ON sm.content_id = a.id
+	WHERE ($1::text IS NULL OR a.kind = $1)
+	AND ($3::text IS NULL OR sm.content_id IS NOT NULL)
+	AND (
+		a.status = 'approved'
+		OR EXISTS (
+			SELECT 1 FROM rel_users_content owner
+			WHERE owner.content_id = a.id
+			AND owner.user_id = $2
+		)
+	)
+	ORDER BY an.name %s
+	`,
+		orderBy,
+	)
+
+	searchValue, romajiValue := searchArgs(search)
+	rows, err := tx.Query(query, kind, reqUser.Id, searchValue, romajiValue)
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error: DbsContent SelectAllContentJoinName: close rows: %v", err)
+		}
+	}()
+	if err != nil {
+		log.Printf("error: DbsContent SelectAllContentJoinName: Query: %v", err)
+		return nil, err
+	}
+
+	for rows.Next() == true {
+		content := &Content{
+			ContentName: ContentName{},
+		}
+		err := rows.Scan(
+			&content.Id,
+			&content.CreatedAt,
+			&content.UpdatedAt,
+			&content.Kind,
+			&content.Unit,
+			&content.Total,
+			&content.Seasons,
+			&content.Description,
+			&content.ImageUrl,
+			&content.UnitMinutes,
+			&content.Status,
+			&content.ReviewReason,
+			&content.Score,
+			&content.ContentName.Id,
+			&content.ContentName.CreatedAt,
+			&content.ContentName.UpdatedAt,
+			&content.ContentName.Name,
+		)
+		if err != nil {
+			log.Printf("error: DbsContent SelectAllContentJoinName: Scan: %v", err)
+			return nil, err
+		}
+		contentList = append(contentList, content)
+	}
+
+	return contentList, nil
+}
+
+func UpdateContentById(tx *sql.Tx, params *Content) error {
+	query := `UPDATE content
+	SET
+		updated_at = $2,
+		total = $3,
+		description = $4,
+		image_url = $5,
+		content_names_id = $6,
+		unit = $7,
+		seasons = $8,
+		unit_minutes = $9
+	WHERE id = $1`
+
+	queryResult, err := tx.Exec(
+		query,
+		params.Id,
+		time.Now(),
+		params.Total,
+		params.Description,
+		params.ImageUrl,
+		params.ContentName.Id,
+		params.Unit,
+		params.Seasons,
+		params.UnitMinutes,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err == nil {
+		if n == 0 {
+			log.Printf("error: DbsContent UpdateContentById: RowsAffected: 0")
+			return sql.ErrNoRows
+		}
+	}
+
+	return nil
+}
+
+func SelectContentNotInLibrary(tx *sql.Tx, reqUser *User, kind *string, search *Search, orderBy string) ([]*Content, error) {
+	result := make([]*Content, 0)
+
+	query := fmt.Sprintf(`
+	WITH user_lib AS (
+		SELECT user_library.id FROM user_library WHERE user_id = $1
+	)
+	SELECT a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes, a.status, a.review_reason, sm.score,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM content a
+	JOIN content_names an ON a.content_names_id = an.id
+	LEFT JOIN search_content($3, $4) sm ON sm.content_id = a.id
+	WHERE ($3::text IS NULL OR sm.content_id IS NOT NULL)
+	AND (
+		a.status = 'approved'
+		OR EXISTS (
+			SELECT 1 FROM rel_users_content owner
+			WHERE owner.content_id = a.id
+			AND owner.user_id = $1
+		)
+	)
+	AND a.id NOT IN (
+		SELECT progress.content_id
+		FROM progress_content progress,
+		user_lib
+		WHERE progress.user_library_id = user_lib.id
+	)
+	AND ($2::text IS NULL OR a.kind = $2)
+	ORDER BY an.name %s
+	`,
+		orderBy,
+	)
+
+	searchValue, romajiValue := searchArgs(search)
+	queryRows, err := tx.Query(
+		query,
+		reqUser.Id,
+		kind,
+		searchValue,
+		romajiValue,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Content: SelectContentInLibrary: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() == true {
+		temp := &Content{
+			ContentName:      ContentName{},
+			AlternativeNames: make([]*ContentName, 0),
+		}
+
+		err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Kind,
+			&temp.Unit,
+			&temp.Total,
+			&temp.Seasons,
+			&temp.Description,
+			&temp.ImageUrl,
+			&temp.UnitMinutes,
+			&temp.Status,
+			&temp.ReviewReason,
+			&temp.Score,
+			&temp.ContentName.Id,
+			&temp.ContentName.CreatedAt,
+			&temp.ContentName.UpdatedAt,
+			&temp.ContentName.Name,
+		)
+		if err != nil {
+			log.Printf("error: Dbs: Content: SelectContentInLibrary: Scan: %v", err)
+			return nil, err
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+// DeleteContent removes the entry and records its last state as a deleted
+// revision, so its history stays readable.
+func DeleteContent(tx *sql.Tx, reqUser *User, reqContent *Content) error {
+	before, err := SelectContentSnapshot(tx, reqContent.Id)
+	if err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: SelectContentSnapshot: %v", err)
+		return err
+	}
+
+	query := `
+	DELETE FROM content
+	WHERE content.id = $1
+	`
+
+	queryResult, err := tx.Exec(query, reqContent.Id)
+	if err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: Content: DeleteContent: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	if _, err := InsertContentRevision(tx, reqUser, REVISION_DELETED, reqContent.Id, before, nil, nil); err != nil {
+		log.Printf("error: Dbs: Content: DeleteContent: InsertContentRevision: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func foundKmp(content *Content, targetString string) bool {
+	if targetString == "" {
+		return false
+	}
+	idx := algo.Kmp(algo.Fold(content.ContentName.Name), targetString)
+	if idx == -1 {
+		for _, v := range content.AlternativeNames {
+			if idx = algo.Kmp(algo.Fold(v.Name), targetString); idx != -1 {
+				break
+			}
+		}
+	}
+	return idx != -1
+}
+
+func foundEditDistance(content *Content, targetString string, edits int) bool {
+	result := algo.EditDistance(algo.Fold(content.ContentName.Name), targetString)
+	if result > edits {
+		for _, v := range content.AlternativeNames {
+			result = algo.EditDistance(algo.Fold(v.Name), targetString)
+			if result <= edits {
+				break
+			}
+		}
+	}
+	return result <= edits
+}
+
+// findDuplicates flags content with a name within a few edits of name, or
+// with a name that contains it. Short names allow fewer edits and must not
+// be substring matches, or every short title would be flagged.
+func findDuplicates(contentList []*Content, name string) []*Content {
+	result := make([]*Content, 0)
+
+	target := algo.Fold(strings.TrimSpace(name))
+	length := utf8.RuneCountInString(target)
+	edits := min(2, length/4)
+	for _, v := range contentList {
+		if len(result) >= DUPLICATE_LIMIT {
+			break
+		}
+		if foundEditDistance(v, target, edits) || (length >= 4 && foundKmp(v, target)) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
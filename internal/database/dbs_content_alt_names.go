@@ -0,0 +1,367 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ContentAltName struct {
+	Id          uuid.UUID   `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	ContentId   uuid.UUID   `json:"content_id"`
+	ContentName ContentName `json:"content_name"`
+}
+
+// ValidLanguage accepts lowercase language tags such as "ja", "en" or
+// "ja-latn": a 2 or 3 letter language followed by optional subtags.
+func ValidLanguage(language string) bool {
+	subtags := strings.Split(language, "-")
+	for k, v := range subtags {
+		if k == 0 && (len(v) < 2 || len(v) > 3) {
+			return false
+		}
+		if k > 0 && (len(v) < 2 || len(v) > 8) {
+			return false
+		}
+		for _, r := range v {
+			if (r < 'a' || r > 'z') && (k == 0 || r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type DbsContentAltNames interface {
+	AddAltName(reqUser *User, reqAltName *ContentAltName) error
+	DeleteAltNames(reqUser *User, reqAltNames []*ContentAltName) error
+}
+
+type PgDbsContentAltNames struct {
+	db          DbService
+	suggestions DbsSuggestions
+}
+
+var dbsContentAltNamesInstance *PgDbsContentAltNames
+
+func NewDbsContentAltNames(db DbService, suggestions DbsSuggestions) DbsContentAltNames {
+	if dbsContentAltNamesInstance != nil {
+		return dbsContentAltNamesInstance
+	}
+
+	newDbsContentAltNames := &PgDbsContentAltNames{
+		db:          db,
+		suggestions: suggestions,
+	}
+	dbsContentAltNamesInstance = newDbsContentAltNames
+	return dbsContentAltNamesInstance
+}
+
+func (d *PgDbsContentAltNames) AddAltName(reqUser *User, reqAltName *ContentAltName) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames AddAltName: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentAltNames AddAltName: Rollback: %v", err)
+		}
+	}()
+
+	before, err := SelectContentSnapshot(tx, reqAltName.ContentId)
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames AddAltName: SelectContentSnapshot: %v", err)
+		return err
+	}
+
+	if err := InsertAltNameWithNew(tx, reqAltName); err != nil {
+		log.Printf("error: Dbs: ContentAltNames AddAltName: InsertAltName: %v", err)
+		return err
+	}
+
+	if err := RecordContentRevision(tx, reqUser, REVISION_ALT_NAMES, reqAltName.ContentId, before); err != nil {
+		log.Printf("error: Dbs: ContentAltNames AddAltName: RecordContentRevision: %v", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: ContentAltNames AddAltName: Rollback: %v", err)
+		return err
+	}
+	d.suggestions.Refresh(reqAltName.ContentId)
+
+	return nil
+}
+
+func (d *PgDbsContentAltNames) DeleteAltNames(reqUser *User, reqAltNames []*ContentAltName) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames DeleteAltNames: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentAltNames DeleteAltNames: Rollback: %v", err)
+		}
+	}()
+
+	if len(reqAltNames) == 0 {
+		return sql.ErrNoRows
+	}
+	contentId := reqAltNames[0].ContentId
+
+	before, err := SelectContentSnapshot(tx, contentId)
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames DeleteAltNames: SelectContentSnapshot: %v", err)
+		return err
+	}
+
+	if err := DeleteAltNames(tx, reqAltNames); err != nil {
+		log.Printf("error: Dbs: ContentAltNames DeleteAltNames: DeleteAltNames: %v", err)
+		return err
+	}
+
+	if err := RecordContentRevision(tx, reqUser, REVISION_ALT_NAMES, contentId, before); err != nil {
+		log.Printf("error: Dbs: ContentAltNames DeleteAltNames: RecordContentRevision: %v", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: ContentAltNames DeleteAltNames: Rollback: %v", err)
+		return err
+	}
+	d.suggestions.Refresh(contentId)
+
+	return nil
+}
+
+func InsertAltName(tx *sql.Tx, params *ContentAltName) error {
+	query := `INSERT INTO rel_content_content_names (id, content_id, content_names_id)
+		VALUES ($1, $2, $3)`
+
+	queryResult, err := tx.Exec(
+		query,
+		uuid.New(),
+		params.ContentId,
+		params.ContentName.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames: InsertAltName: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames: InsertAltName: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: ContentAltNames: InsertAltName: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func InsertAltNameWithNew(tx *sql.Tx, params *ContentAltName) error {
+	query := `
+		WITH source_data(new_name_id, new_content_name, new_search_name, new_romaji, new_script) AS (
+			VALUES ($1::uuid, $2, $5, $6, $7)
+		), upsert AS (
+			MERGE INTO content_names
+			USING source_data
+				ON content_names.name = source_data.new_content_name
+			WHEN MATCHED THEN
+				UPDATE SET name = content_names.name
+			WHEN NOT MATCHED THEN
+				INSERT (id, name, search_name, romaji, script)
+				VALUES (source_data.new_name_id, source_data.new_content_name, source_data.new_search_name, source_data.new_romaji, source_data.new_script)
+			RETURNING id, created_at, updated_at, name
+		)
+		INSERT INTO rel_content_content_names (id, content_id, content_names_id, language)
+		SELECT $3, $4, upsert.id, $8
+		FROM upsert
+	`
+
+	searchName, romaji, script := nameForms(params.ContentName.Name)
+	queryResult, err := tx.Exec(
+		query,
+		uuid.New(),
+		params.ContentName.Name,
+		uuid.New(),
+		params.ContentId,
+		searchName,
+		romaji,
+		script,
+		params.ContentName.Language,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames: InsertAltNameWithNew: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames: InsertAltNameWithNew: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: ContentAltNames: InsertAltNameWithNew: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func DeleteAltNames(tx *sql.Tx, reqAltNames []*ContentAltName) error {
+	query := `
+	DELETE FROM rel_content_content_names alt_names
+	WHERE alt_names.content_id = $1
+	AND alt_names.content_names_id = ANY($2)
+	`
+
+	if len(reqAltNames) == 0 {
+		return sql.ErrNoRows
+	}
+
+	contentId := reqAltNames[0].ContentId
+	args := make([]uuid.UUID, 0)
+	for _, v := range reqAltNames {
+		args = append(args, v.ContentName.Id)
+	}
+
+	queryResult, err := tx.Exec(
+		query,
+		contentId,
+		args,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames: DeleteAltNames: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ContentAltNames: DeleteAltNames: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: ContentAltNames: DeleteAltNames: RowsAffected: %v", n)
+	}
+
+	return nil
+}
+
+func SelectAllContentAltNames(tx *sql.Tx) ([]*ContentAltName, error) {
+	result := make([]*ContentAltName, 0)
+
+	query := `SELECT
+	ran.id, ran.created_at, ran.updated_at, ran.content_id,
+	an.id, an.created_at, an.updated_at, an.name, an.script, ran.language
+	FROM rel_content_content_names ran
+	JOIN content_names an ON ran.content_names_id = an.id`
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		log.Printf("error: DbsRelContentContentNames SelectAllNamesContent: Query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error: DbsRelContentContentNames SelectAllNamesContent: Close rows: %v", err)
+		}
+	}()
+
+	for rows.Next() == true {
+		rel := &ContentAltName{
+			ContentName: ContentName{},
+		}
+		err := rows.Scan(
+			&rel.Id,
+			&rel.CreatedAt,
+			&rel.UpdatedAt,
+			&rel.ContentId,
+			&rel.ContentName.Id,
+			&rel.ContentName.CreatedAt,
+			&rel.ContentName.UpdatedAt,
+			&rel.ContentName.Name,
+		)
+		if err != nil {
+			log.Printf("error: DbsRelContentContentNames SelectAllNamesContent: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, rel)
+	}
+
+	return result, nil
+}
+
+func SelectContentAltNames(tx *sql.Tx, reqContent []*Content) ([]*ContentAltName, error) {
+	result := make([]*ContentAltName, 0)
+
+	args := make([]uuid.UUID, 0)
+	for _, v := range reqContent {
+		args = append(args, v.Id)
+	}
+
+	if len(args) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	query := `SELECT
+	ran.id, ran.created_at, ran.updated_at, ran.content_id,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM rel_content_content_names ran
+	JOIN content_names an ON ran.content_names_id = an.id
+	WHERE ran.content_id = ANY($1)`
+
+	queryRows, err := tx.Query(
+		query,
+		args,
+	)
+	if err != nil {
+		log.Printf("error: DbsRelContentContentNames SelectContentNames: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() == true {
+		rel := &ContentAltName{
+			ContentName: ContentName{},
+		}
+		err := queryRows.Scan(
+			&rel.Id,
+			&rel.CreatedAt,
+			&rel.UpdatedAt,
+			&rel.ContentId,
+			&rel.ContentName.Id,
+			&rel.ContentName.CreatedAt,
+			&rel.ContentName.UpdatedAt,
+			&rel.ContentName.Name,
+			&rel.ContentName.Script,
+			&rel.ContentName.Language,
+		)
+		if err != nil {
+			log.Printf("error: DbsRelContentContentNames SelectContentNames: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, rel)
+	}
+
+	return result, nil
+}
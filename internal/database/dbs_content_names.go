@@ -0,0 +1,140 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/algo"
+	"github.com/google/uuid"
+)
+
+type ContentName struct {
+	Id        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Script    *string   `json:"script,omitempty"`
+	Language  *string   `json:"language,omitempty"`
+}
+
+type DbsContentNames interface {
+}
+
+type PgDbsContentNames struct {
+	db DbService
+}
+
+var dbsContentNamesInstance *PgDbsContentNames
+
+func NewDbsContentNames(db DbService) DbsContentNames {
+	if dbsContentNamesInstance != nil {
+		return dbsContentNamesInstance
+	}
+
+	newDbsContentNames := &PgDbsContentNames{
+		db: db,
+	}
+	dbsContentNamesInstance = newDbsContentNames
+
+	return dbsContentNamesInstance
+}
+
+// nameForms returns what content_names keeps next to a name for search: the
+// folded name, its romaji and its script.
+func nameForms(name string) (string, string, string) {
+	folded := algo.Fold(name)
+	return folded, algo.Romanize(folded), algo.Script(name)
+}
+
+func InsertContentName(tx *sql.Tx, params *ContentName) (*ContentName, error) {
+	result := &ContentName{}
+
+	query := `INSERT INTO content_names (id, name, search_name, romaji, script)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at, name`
+
+	searchName, romaji, script := nameForms(params.Name)
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		params.Name,
+		searchName,
+		romaji,
+		script,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentNames: InsertContentName: Query: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func InsertContentNameIfNotExist(tx *sql.Tx, reqContentName *ContentName) (*ContentName, error) {
+	result := &ContentName{}
+
+	query := `
+	WITH source(id, name, search_name, romaji, script) AS (
+		VALUES($1::uuid, $2, $3, $4, $5)
+	), upsert AS (
+		MERGE INTO content_names AS an
+		USING source AS src
+			ON LOWER(an.name) = LOWER(src.name)
+		WHEN MATCHED THEN
+			UPDATE SET id = an.id
+		WHEN NOT MATCHED THEN
+			INSERT(id, name, search_name, romaji, script)
+			VALUES(src.id, src.name, src.search_name, src.romaji, src.script)
+		RETURNING an.id, an.created_at, an.updated_at, an.name
+	)
+	SELECT n.id, n.created_at, n.updated_at, n.name
+	FROM upsert n
+	JOIN rel_content_content_names alt ON n.id = alt.content_names_id
+	`
+
+	searchName, romaji, script := nameForms(reqContentName.Name)
+	if err := tx.QueryRow(
+		query,
+		uuid.New(),
+		reqContentName.Name,
+		searchName,
+		romaji,
+		script,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+	); err != nil {
+		log.Printf("error: Dbs: ContentNames: InsertContentNameIfNotExist: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectContentNameByName(tx *sql.Tx, params *ContentName) (*ContentName, error) {
+	result := &ContentName{}
+
+	query := `SELECT id, created_at, updated_at, name FROM content_names
+	WHERE LOWER(name) = LOWER($1)`
+
+	err := tx.QueryRow(query, params.Name).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentNames: SelectContentNameByName: Query: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
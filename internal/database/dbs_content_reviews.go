@@ -0,0 +1,453 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotPending         = errors.New("content is not pending review")
+	ErrInvalidMergeTarget = errors.New("merge target must be other approved content")
+	ErrMissingReason      = errors.New("reason is required")
+)
+
+// ContentSubmission is a pending entry in the moderation queue.
+type ContentSubmission struct {
+	Content  *Content   `json:"content"`
+	UserId   *uuid.UUID `json:"user_id"`
+	Username *string    `json:"username"`
+}
+
+type DbsContentReviews interface {
+	GetPending() ([]*ContentSubmission, error)
+	Approve(reqUser *User, reqContent *Content, reason *string) error
+	Reject(reqUser *User, reqContent *Content, reason string) error
+	Merge(reqUser *User, reqContent *Content, reqTarget *Content, reason string) error
+	MergeDuplicate(reqUser *User, reqContent *Content, reqTarget *Content) error
+}
+
+type PgDbsContentReviews struct {
+	db          DbService
+	suggestions DbsSuggestions
+}
+
+var dbsContentReviewsInstance *PgDbsContentReviews
+
+func NewDbsContentReviews(db DbService, suggestions DbsSuggestions) DbsContentReviews {
+	if dbsContentReviewsInstance != nil {
+		return dbsContentReviewsInstance
+	}
+
+	newDbsContentReviews := &PgDbsContentReviews{
+		db:          db,
+		suggestions: suggestions,
+	}
+	dbsContentReviewsInstance = newDbsContentReviews
+
+	return dbsContentReviewsInstance
+}
+
+func (d *PgDbsContentReviews) GetPending() ([]*ContentSubmission, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: GetPending: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentReviews: GetPending: Rollback: %v", err)
+		}
+	}()
+
+	dbSubmissions, err := SelectPendingContent(tx)
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: GetPending: SelectPendingContent: %v", err)
+		return nil, err
+	}
+
+	contentList := make([]*Content, 0)
+	for _, v := range dbSubmissions {
+		contentList = append(contentList, v.Content)
+	}
+
+	allNames := make([]*ContentAltName, 0)
+	if len(contentList) > 0 {
+		allNames, err = SelectContentAltNames(tx, contentList)
+		if err != nil {
+			log.Printf("error: Dbs: ContentReviews: GetPending: SelectContentAltNames: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: GetPending: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	for _, v := range contentList {
+		if names, ok := namesMap[v.Id]; ok {
+			v.AlternativeNames = names
+		}
+	}
+
+	return dbSubmissions, nil
+}
+
+func (d *PgDbsContentReviews) Approve(reqUser *User, reqContent *Content, reason *string) error {
+	return d.review(reqUser, reqContent, CONTENT_APPROVED, reason, nil)
+}
+
+func (d *PgDbsContentReviews) Reject(reqUser *User, reqContent *Content, reason string) error {
+	if reason == "" {
+		return ErrMissingReason
+	}
+	return d.review(reqUser, reqContent, CONTENT_REJECTED, &reason, nil)
+}
+
+// Merge folds a pending submission into existing approved content. Its names,
+// library entries, list items, genres and tags move to reqTarget, and the
+// submission is kept as merged so the submitter can see where it went.
+func (d *PgDbsContentReviews) Merge(reqUser *User, reqContent *Content, reqTarget *Content, reason string) error {
+	if reason == "" {
+		return ErrMissingReason
+	}
+	return d.review(reqUser, reqContent, CONTENT_MERGED, &reason, reqTarget)
+}
+
+// MergeDuplicate folds any content into approved reqTarget and deletes it.
+// Unlike Merge the source leaves no trace behind, its owner becomes the owner
+// of reqTarget if that has none, and submissions merged into it follow along.
+func (d *PgDbsContentReviews) MergeDuplicate(reqUser *User, reqContent *Content, reqTarget *Content) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeDuplicate: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentReviews: MergeDuplicate: Rollback: %v", err)
+		}
+	}()
+
+	if _, err := SelectContentStatusForUpdate(tx, reqContent); err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeDuplicate: SelectContentStatusForUpdate: %v", err)
+		return err
+	}
+
+	if err := MergeContent(tx, reqUser, reqContent, reqTarget); err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeDuplicate: MergeContent: %v", err)
+		return err
+	}
+
+	queries := []string{
+		`UPDATE rel_users_content
+		SET content_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE content_id = $1
+		AND NOT EXISTS (
+			SELECT 1 FROM rel_users_content WHERE content_id = $2
+		)`,
+		`UPDATE content
+		SET merged_into_id = $2
+		WHERE merged_into_id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, reqContent.Id, reqTarget.Id); err != nil {
+			log.Printf("error: Dbs: ContentReviews: MergeDuplicate: Exec: %v", err)
+			return err
+		}
+	}
+
+	if err := DeleteContent(tx, reqUser, reqContent); err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeDuplicate: DeleteContent: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeDuplicate: Commit: %v", err)
+		return err
+	}
+	d.suggestions.Refresh(reqContent.Id, reqTarget.Id)
+
+	return nil
+}
+
+func (d *PgDbsContentReviews) review(reqUser *User, reqContent *Content, status string, reason *string, reqTarget *Content) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: review: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentReviews: review: Rollback: %v", err)
+		}
+	}()
+
+	currentStatus, err := SelectContentStatusForUpdate(tx, reqContent)
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: review: SelectContentStatusForUpdate: %v", err)
+		return err
+	}
+	if currentStatus != CONTENT_PENDING {
+		return ErrNotPending
+	}
+
+	var mergedIntoId *uuid.UUID
+	if reqTarget != nil {
+		if err := MergeContent(tx, reqUser, reqContent, reqTarget); err != nil {
+			log.Printf("error: Dbs: ContentReviews: review: MergeContent: %v", err)
+			return err
+		}
+		mergedIntoId = &reqTarget.Id
+	}
+
+	// a rejected submission lets go of its names, so a corrected one can use them
+	if status == CONTENT_REJECTED {
+		if _, err := tx.Exec(`DELETE FROM rel_content_content_names WHERE content_id = $1`, reqContent.Id); err != nil {
+			log.Printf("error: Dbs: ContentReviews: review: release names: %v", err)
+			return err
+		}
+	}
+
+	if err := UpdateContentReview(tx, reqUser, reqContent, status, reason, mergedIntoId); err != nil {
+		log.Printf("error: Dbs: ContentReviews: review: UpdateContentReview: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: review: Commit: %v", err)
+		return err
+	}
+	if reqTarget != nil {
+		d.suggestions.Refresh(reqContent.Id, reqTarget.Id)
+	} else {
+		d.suggestions.Refresh(reqContent.Id)
+	}
+
+	return nil
+}
+
+func SelectPendingContent(tx *sql.Tx) ([]*ContentSubmission, error) {
+	result := make([]*ContentSubmission, 0)
+
+	query := `
+	SELECT a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes, a.status,
+	an.id, an.created_at, an.updated_at, an.name,
+	u.id, u.username
+	FROM content a
+	JOIN content_names an ON a.content_names_id = an.id
+	LEFT JOIN rel_users_content owner ON owner.content_id = a.id
+	LEFT JOIN users u ON owner.user_id = u.id
+	WHERE a.status = 'pending'
+	ORDER BY a.created_at ASC
+	`
+
+	queryRows, err := tx.Query(query)
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: SelectPendingContent: Query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := queryRows.Close(); err != nil {
+			log.Printf("error: Dbs: ContentReviews: SelectPendingContent: Close rows: %v", err)
+		}
+	}()
+
+	for queryRows.Next() {
+		temp := &ContentSubmission{
+			Content: &Content{
+				AlternativeNames: make([]*ContentName, 0),
+			},
+		}
+		if err := queryRows.Scan(
+			&temp.Content.Id,
+			&temp.Content.CreatedAt,
+			&temp.Content.UpdatedAt,
+			&temp.Content.Kind,
+			&temp.Content.Unit,
+			&temp.Content.Total,
+			&temp.Content.Seasons,
+			&temp.Content.Description,
+			&temp.Content.ImageUrl,
+			&temp.Content.UnitMinutes,
+			&temp.Content.Status,
+			&temp.Content.ContentName.Id,
+			&temp.Content.ContentName.CreatedAt,
+			&temp.Content.ContentName.UpdatedAt,
+			&temp.Content.ContentName.Name,
+			&temp.UserId,
+			&temp.Username,
+		); err != nil {
+			log.Printf("error: Dbs: ContentReviews: SelectPendingContent: Scan: %v", err)
+			return nil, err
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectContentStatusForUpdate(tx *sql.Tx, reqContent *Content) (string, error) {
+	var result string
+
+	query := `
+	SELECT status
+	FROM content
+	WHERE id = $1
+	FOR UPDATE
+	`
+
+	if err := tx.QueryRow(query, reqContent.Id).Scan(&result); err != nil {
+		log.Printf("error: Dbs: ContentReviews: SelectContentStatusForUpdate: Scan: %v", err)
+		return "", err
+	}
+
+	return result, nil
+}
+
+func UpdateContentReview(tx *sql.Tx, reqUser *User, reqContent *Content, status string, reason *string, mergedIntoId *uuid.UUID) error {
+	query := `
+	UPDATE content
+	SET
+		updated_at = $2,
+		status = $3,
+		review_reason = $4,
+		reviewed_at = $2,
+		reviewed_by = $5,
+		merged_into_id = $6
+	WHERE id = $1
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqContent.Id,
+		time.Now(),
+		status,
+		reason,
+		reqUser.Id,
+		mergedIntoId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: UpdateContentReview: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: UpdateContentReview: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ContentReviews: UpdateContentReview: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// MergeContent moves everything that hangs off source to target. When a user
+// has both in their library the entry with the furthest progress is kept, a
+// completed entry counting as furthest. Other rows that target already has are
+// dropped with source's copy. Source itself is left in place for the caller to
+// mark or delete, ownership is not moved.
+func MergeContent(tx *sql.Tx, reqUser *User, source *Content, target *Content) error {
+	if source.Id == target.Id {
+		return ErrInvalidMergeTarget
+	}
+
+	var targetStatus string
+	err := tx.QueryRow(`SELECT status FROM content WHERE id = $1`, target.Id).Scan(&targetStatus)
+	if err == sql.ErrNoRows || (err == nil && targetStatus != CONTENT_APPROVED) {
+		return ErrInvalidMergeTarget
+	} else if err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeContent: target status: %v", err)
+		return err
+	}
+
+	before, err := SelectContentSnapshot(tx, target.Id)
+	if err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeContent: SelectContentSnapshot: %v", err)
+		return err
+	}
+
+	queries := []string{
+		`DELETE FROM progress_content t
+		USING progress_content s
+		WHERE t.content_id = $2
+		AND s.content_id = $1
+		AND s.user_library_id = t.user_library_id
+		AND (
+			(s.status = 'completed') IS TRUE AND (t.status = 'completed') IS NOT TRUE
+			OR ((s.status = 'completed') IS TRUE) = ((t.status = 'completed') IS TRUE) AND (COALESCE(s.season, 1), s.value) > (COALESCE(t.season, 1), t.value)
+		)`,
+		`UPDATE rel_content_content_names
+		SET content_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE content_id = $1
+		AND content_names_id NOT IN (
+			SELECT content_names_id FROM rel_content_content_names WHERE content_id = $2
+		)`,
+		`UPDATE progress_content
+		SET content_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE content_id = $1
+		AND user_library_id NOT IN (
+			SELECT user_library_id FROM progress_content WHERE content_id = $2
+		)`,
+		`UPDATE progress_events
+		SET content_id = $2
+		WHERE content_id = $1`,
+		`UPDATE list_items
+		SET content_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE content_id = $1
+		AND list_id NOT IN (
+			SELECT list_id FROM list_items WHERE content_id = $2
+		)`,
+		`UPDATE rel_content_genres
+		SET content_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE content_id = $1
+		AND genres_id NOT IN (
+			SELECT genres_id FROM rel_content_genres WHERE content_id = $2
+		)`,
+		`UPDATE rel_content_tags
+		SET content_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE content_id = $1
+		AND tags_id NOT IN (
+			SELECT tags_id FROM rel_content_tags WHERE content_id = $2
+		)`,
+		`DELETE FROM progress_content WHERE content_id = $1`,
+		`DELETE FROM list_items WHERE content_id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, source.Id, target.Id); err != nil {
+			log.Printf("error: Dbs: ContentReviews: MergeContent: Exec: %v", err)
+			return err
+		}
+	}
+
+	if err := RecordContentRevision(tx, reqUser, REVISION_MERGED, target.Id, before); err != nil {
+		log.Printf("error: Dbs: ContentReviews: MergeContent: RecordContentRevision: %v", err)
+		return err
+	}
+
+	return nil
+}
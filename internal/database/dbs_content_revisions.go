@@ -0,0 +1,556 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	REVISION_CREATED   = "created"
+	REVISION_UPDATED   = "updated"
+	REVISION_ALT_NAMES = "alt_names"
+	REVISION_REVERTED  = "reverted"
+	REVISION_MERGED    = "merged"
+	REVISION_DELETED   = "deleted"
+)
+
+var ErrNothingToRevert = errors.New("revision has no earlier state")
+
+// ContentRevision is an append-only record of a change to a catalog entry.
+// Before and After hold the entry as returned by SelectContentSnapshot, Before
+// is null for the revision that created it and After for the one that deleted
+// it. Revisions outlive the entry they belong to.
+type ContentRevision struct {
+	Id                uuid.UUID                  `json:"id"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	Action            string                     `json:"action"`
+	Before            json.RawMessage            `json:"before"`
+	After             json.RawMessage            `json:"after"`
+	Diff              map[string]*RevisionChange `json:"diff"`
+	RevertsRevisionId *uuid.UUID                 `json:"reverts_revision_id"`
+	ContentId         uuid.UUID                  `json:"content_id"`
+	UserId            *uuid.UUID                 `json:"user_id"`
+	Username          *string                    `json:"username"`
+}
+
+type RevisionChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+type DbsContentRevisions interface {
+	GetRevisions(reqUser *User, reqContent *Content) ([]*ContentRevision, error)
+	Revert(reqUser *User, reqRevision *ContentRevision) (*ContentRevision, error)
+}
+
+type PgDbsContentRevisions struct {
+	db          DbService
+	suggestions DbsSuggestions
+}
+
+var dbsContentRevisionsInstance *PgDbsContentRevisions
+
+func NewDbsContentRevisions(db DbService, suggestions DbsSuggestions) DbsContentRevisions {
+	if dbsContentRevisionsInstance != nil {
+		return dbsContentRevisionsInstance
+	}
+
+	newDbsContentRevisions := &PgDbsContentRevisions{
+		db:          db,
+		suggestions: suggestions,
+	}
+	dbsContentRevisionsInstance = newDbsContentRevisions
+
+	return dbsContentRevisionsInstance
+}
+
+// GetRevisions follows the visibility of GetContentById: the history of content
+// under review is only shown to its submitter and to reviewers, that of a
+// deleted entry only to reviewers.
+func (d *PgDbsContentRevisions) GetRevisions(reqUser *User, reqContent *Content) ([]*ContentRevision, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: GetRevisions: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentRevisions: GetRevisions: Rollback: %v", err)
+		}
+	}()
+
+	reviewer := auth.Can(reqUser.Role, auth.PermContentReview)
+
+	dbContent, err := SelectContentJoinName(tx, reqContent)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("error: Dbs: ContentRevisions: GetRevisions: SelectContentJoinName: %v", err)
+		return nil, err
+	}
+	deleted := err != nil
+	if deleted && !reviewer {
+		return nil, sql.ErrNoRows
+	}
+
+	if !deleted && dbContent.Status != CONTENT_APPROVED && !reviewer {
+		dbOwner, err := SelectContentOwner(tx, dbContent)
+		if err != nil {
+			log.Printf("error: Dbs: ContentRevisions: GetRevisions: SelectContentOwner: %v", err)
+			return nil, err
+		}
+		if dbOwner.UserId != reqUser.Id {
+			return nil, sql.ErrNoRows
+		}
+	}
+
+	dbRevisions, err := SelectContentRevisions(tx, reqContent)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: GetRevisions: SelectContentRevisions: %v", err)
+		return nil, err
+	}
+	if deleted && len(dbRevisions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: GetRevisions: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbRevisions, nil
+}
+
+// Revert puts the entry back into the state it had before reqRevision. Later
+// revisions are kept, the revert is recorded on top of them and can be
+// reverted in turn. A deleted entry cannot be reverted, it has no row to
+// restore into.
+func (d *PgDbsContentRevisions) Revert(reqUser *User, reqRevision *ContentRevision) (*ContentRevision, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: ContentRevisions: Revert: Rollback: %v", err)
+		}
+	}()
+
+	dbRevision, err := SelectContentRevision(tx, reqRevision)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: SelectContentRevision: %v", err)
+		return nil, err
+	}
+	if dbRevision.Before == nil {
+		return nil, ErrNothingToRevert
+	}
+
+	before, err := SelectContentSnapshot(tx, dbRevision.ContentId)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: SelectContentSnapshot before: %v", err)
+		return nil, err
+	}
+
+	if err := RestoreContentSnapshot(tx, dbRevision.ContentId, dbRevision.Before); err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: RestoreContentSnapshot: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectContentSnapshot(tx, dbRevision.ContentId)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: SelectContentSnapshot after: %v", err)
+		return nil, err
+	}
+
+	result, err := InsertContentRevision(tx, reqUser, REVISION_REVERTED, dbRevision.ContentId, before, after, &dbRevision.Id)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: InsertContentRevision: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: Revert: Commit: %v", err)
+		return nil, err
+	}
+	d.suggestions.Refresh(dbRevision.ContentId)
+
+	return result, nil
+}
+
+// SelectContentSnapshot returns the editable state of a catalog entry: its
+// fields, primary name and alternative names. It locks the content row, so
+// callers see no concurrent edit between the snapshot and their change.
+func SelectContentSnapshot(tx *sql.Tx, contentId uuid.UUID) ([]byte, error) {
+	var result []byte
+
+	query := `
+	SELECT jsonb_build_object(
+		'kind', c.kind,
+		'unit', c.unit,
+		'total', c.total,
+		'seasons', c.seasons,
+		'description', c.description,
+		'image_url', c.image_url,
+		'unit_minutes', c.unit_minutes,
+		'name', jsonb_build_object('id', n.id, 'name', n.name),
+		'alt_names', COALESCE((
+			SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object('id', an.id, 'name', an.name, 'language', alt.language)) ORDER BY an.name)
+			FROM rel_content_content_names alt
+			JOIN content_names an ON alt.content_names_id = an.id
+			WHERE alt.content_id = c.id
+		), '[]'::jsonb)
+	)
+	FROM content c
+	JOIN content_names n ON c.content_names_id = n.id
+	WHERE c.id = $1
+	FOR UPDATE OF c
+	`
+
+	err := tx.QueryRow(
+		query,
+		contentId,
+	).Scan(&result)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: SelectContentSnapshot: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RestoreContentSnapshot writes the fields, primary name and alternative
+// names recorded in data back to the entry. Kind is not editable and is left
+// as is. Names are looked up by their recorded id, then by name, and inserted
+// again when neither is found.
+func RestoreContentSnapshot(tx *sql.Tx, contentId uuid.UUID, data []byte) error {
+	type snapshotName struct {
+		Id       uuid.UUID `json:"id"`
+		Name     string    `json:"name"`
+		Language *string   `json:"language"`
+	}
+	var snapshot struct {
+		Name     snapshotName    `json:"name"`
+		AltNames []*snapshotName `json:"alt_names"`
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: Unmarshal: %v", err)
+		return err
+	}
+
+	nameId, err := RestoreContentName(tx, snapshot.Name.Id, snapshot.Name.Name)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: RestoreContentName: %v", err)
+		return err
+	}
+
+	queryUpdate := `
+	UPDATE content c
+	SET
+		updated_at = $3,
+		unit = s.unit,
+		total = s.total,
+		seasons = s.seasons,
+		description = s.description,
+		image_url = s.image_url,
+		unit_minutes = s.unit_minutes,
+		content_names_id = $4
+	FROM jsonb_to_record($2::jsonb) AS s(unit TEXT, total INT, seasons INT, description TEXT, image_url TEXT, unit_minutes INT)
+	WHERE c.id = $1
+	`
+
+	queryResult, err := tx.Exec(queryUpdate, contentId, data, time.Now(), nameId)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: Update: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	queryDelete := `
+	DELETE FROM rel_content_content_names
+	WHERE content_id = $1
+	`
+
+	if _, err := tx.Exec(queryDelete, contentId); err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: Delete: %v", err)
+		return err
+	}
+
+	queryInsert := `
+	INSERT INTO rel_content_content_names (id, content_id, content_names_id, language)
+	VALUES ($1, $2, $3, $4)
+	`
+
+	seen := make(map[uuid.UUID]bool)
+	for _, v := range snapshot.AltNames {
+		altNameId, err := RestoreContentName(tx, v.Id, v.Name)
+		if err != nil {
+			log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: RestoreContentName: %v", err)
+			return err
+		}
+		if seen[altNameId] {
+			continue
+		}
+		seen[altNameId] = true
+
+		if _, err := tx.Exec(queryInsert, uuid.New(), contentId, altNameId, v.Language); err != nil {
+			log.Printf("error: Dbs: ContentRevisions: RestoreContentSnapshot: Insert: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestoreContentName returns the id of the content_names row recorded in a
+// snapshot. A row that is gone is matched by name instead, or inserted again.
+func RestoreContentName(tx *sql.Tx, nameId uuid.UUID, name string) (uuid.UUID, error) {
+	var result uuid.UUID
+
+	query := `
+	SELECT an.id
+	FROM content_names an
+	WHERE an.id = $1
+	OR LOWER(an.name) = LOWER($2)
+	ORDER BY an.id = $1 DESC
+	LIMIT 1
+	`
+
+	err := tx.QueryRow(
+		query,
+		nameId,
+		name,
+	).Scan(&result)
+	if err == nil {
+		return result, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentName: Scan: %v", err)
+		return result, err
+	}
+
+	dbContentName, err := InsertContentName(tx, &ContentName{
+		Name: name,
+	})
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RestoreContentName: InsertContentName: %v", err)
+		return result, err
+	}
+
+	return dbContentName.Id, nil
+}
+
+// InsertContentRevision records action against the entry. before is nil when
+// the entry was just created.
+func InsertContentRevision(tx *sql.Tx, reqUser *User, action string, contentId uuid.UUID, before, after []byte, revertsRevisionId *uuid.UUID) (*ContentRevision, error) {
+	result := &ContentRevision{}
+
+	query := `
+	INSERT INTO content_revisions (id, action, before, after, reverts_revision_id, content_id, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at, action, before, after, reverts_revision_id, content_id, user_id
+	`
+
+	var resultBefore, resultAfter []byte
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		action,
+		before,
+		after,
+		revertsRevisionId,
+		contentId,
+		reqUser.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.Action,
+		&resultBefore,
+		&resultAfter,
+		&result.RevertsRevisionId,
+		&result.ContentId,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: InsertContentRevision: Scan: %v", err)
+		return nil, err
+	}
+	result.Before = resultBefore
+	result.After = resultAfter
+	result.Diff = revisionDiff(resultBefore, resultAfter)
+
+	return result, nil
+}
+
+func SelectContentRevisions(tx *sql.Tx, reqContent *Content) ([]*ContentRevision, error) {
+	result := make([]*ContentRevision, 0)
+
+	query := `
+	SELECT r.id, r.created_at, r.action, r.before, r.after, r.reverts_revision_id, r.content_id, r.user_id, u.username
+	FROM content_revisions r
+	LEFT JOIN users u ON r.user_id = u.id
+	WHERE r.content_id = $1
+	ORDER BY r.created_at DESC
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		reqContent.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: SelectContentRevisions: Query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := queryRows.Close(); err != nil {
+			log.Printf("error: Dbs: ContentRevisions: SelectContentRevisions: Close rows: %v", err)
+		}
+	}()
+
+	for queryRows.Next() {
+		temp := &ContentRevision{}
+		var before, after []byte
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.Action,
+			&before,
+			&after,
+			&temp.RevertsRevisionId,
+			&temp.ContentId,
+			&temp.UserId,
+			&temp.Username,
+		); err != nil {
+			log.Printf("error: Dbs: ContentRevisions: SelectContentRevisions: Scan: %v", err)
+			return nil, err
+		}
+		temp.Before = before
+		temp.After = after
+		temp.Diff = revisionDiff(before, after)
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectContentRevision(tx *sql.Tx, reqRevision *ContentRevision) (*ContentRevision, error) {
+	result := &ContentRevision{}
+
+	query := `
+	SELECT r.id, r.created_at, r.action, r.before, r.after, r.reverts_revision_id, r.content_id, r.user_id
+	FROM content_revisions r
+	WHERE r.id = $1
+	AND r.content_id = $2
+	`
+
+	var before, after []byte
+	err := tx.QueryRow(
+		query,
+		reqRevision.Id,
+		reqRevision.ContentId,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.Action,
+		&before,
+		&after,
+		&result.RevertsRevisionId,
+		&result.ContentId,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: SelectContentRevision: Scan: %v", err)
+		return nil, err
+	}
+	result.Before = before
+	result.After = after
+
+	return result, nil
+}
+
+// revisionDiff lists the top level snapshot fields that differ between before
+// and after. Postgres writes jsonb in a canonical form, so equal values are
+// equal bytes.
+func revisionDiff(before, after []byte) map[string]*RevisionChange {
+	result := make(map[string]*RevisionChange)
+
+	beforeFields := make(map[string]json.RawMessage)
+	afterFields := make(map[string]json.RawMessage)
+	if before != nil {
+		if err := json.Unmarshal(before, &beforeFields); err != nil {
+			log.Printf("error: Dbs: ContentRevisions: revisionDiff: Unmarshal before: %v", err)
+			return result
+		}
+	}
+	if after != nil {
+		if err := json.Unmarshal(after, &afterFields); err != nil {
+			log.Printf("error: Dbs: ContentRevisions: revisionDiff: Unmarshal after: %v", err)
+			return result
+		}
+	}
+
+	for k, v := range afterFields {
+		if old, ok := beforeFields[k]; ok && bytes.Equal(old, v) {
+			continue
+		}
+		result[k] = &RevisionChange{
+			Before: beforeFields[k],
+			After:  v,
+		}
+	}
+	for k, v := range beforeFields {
+		if _, ok := afterFields[k]; !ok {
+			result[k] = &RevisionChange{
+				Before: v,
+			}
+		}
+	}
+
+	return result
+}
+
+// RecordContentRevision snapshots the entry after a change and records it
+// against before. Changes that left the entry as it was are not recorded.
+func RecordContentRevision(tx *sql.Tx, reqUser *User, action string, contentId uuid.UUID, before []byte) error {
+	after, err := SelectContentSnapshot(tx, contentId)
+	if err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RecordContentRevision: SelectContentSnapshot: %v", err)
+		return err
+	}
+
+	if before != nil && bytes.Equal(before, after) {
+		return nil
+	}
+
+	if _, err := InsertContentRevision(tx, reqUser, action, contentId, before, after, nil); err != nil {
+		log.Printf("error: Dbs: ContentRevisions: RecordContentRevision: InsertContentRevision: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,456 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/tokens"
+	"github.com/google/uuid"
+)
+
+const (
+	DEVICE_CODE_STATUS_PENDING  = "pending"
+	DEVICE_CODE_STATUS_APPROVED = "approved"
+	DEVICE_CODE_STATUS_DENIED   = "denied"
+
+	DEVICE_CODE_TTL           = time.Minute * 10
+	DEVICE_CODE_POLL_INTERVAL = 5
+	// DEVICE_CODE_SLOW_DOWN is added to the interval of a client polling too
+	// fast, as RFC 8628 asks.
+	DEVICE_CODE_SLOW_DOWN = 5
+)
+
+var (
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrDeviceCodeExpired    = errors.New("device code expired")
+)
+
+// DeviceCode is a pending device authorization. DeviceCode is the secret the
+// device polls with, UserCode is what the user types to approve it.
+type DeviceCode struct {
+	Id           uuid.UUID  `json:"-"`
+	CreatedAt    time.Time  `json:"-"`
+	UpdatedAt    time.Time  `json:"-"`
+	DeviceCode   string     `json:"device_code"`
+	UserCode     string     `json:"user_code"`
+	ClientName   *string    `json:"client_name"`
+	Status       string     `json:"-"`
+	PollInterval int        `json:"interval"`
+	LastPolledAt *time.Time `json:"-"`
+	ExpiresAt    time.Time  `json:"-"`
+	UserId       *uuid.UUID `json:"-"`
+}
+
+type DbsDeviceCodes interface {
+	CreateCode(reqCode *DeviceCode) (*DeviceCode, error)
+	ApproveCode(reqUser *User, reqCode *DeviceCode, approve bool) error
+	ExchangeCode(reqCode *DeviceCode) (*User, *DeviceCode, error)
+	DeleteExpired() error
+}
+
+type PgDbsDeviceCodes struct {
+	db DbService
+}
+
+var dbsDeviceCodesInstance *PgDbsDeviceCodes
+
+func NewDbsDeviceCodes(db DbService) DbsDeviceCodes {
+	if dbsDeviceCodesInstance != nil {
+		return dbsDeviceCodesInstance
+	}
+
+	newDbsDeviceCodes := &PgDbsDeviceCodes{
+		db: db,
+	}
+	dbsDeviceCodesInstance = newDbsDeviceCodes
+
+	return dbsDeviceCodesInstance
+}
+
+func (d *PgDbsDeviceCodes) CreateCode(reqCode *DeviceCode) (*DeviceCode, error) {
+	deviceToken, err := tokens.GenerateToken(DEVICE_CODE_TTL)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: CreateCode: GenerateToken: %v", err)
+		return nil, err
+	}
+
+	userCode, err := tokens.GenerateUserCode()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: CreateCode: GenerateUserCode: %v", err)
+		return nil, err
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: CreateCode: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: DeviceCodes: CreateCode: Rollback: %v", err)
+		}
+	}()
+
+	newCode := &DeviceCode{
+		UserCode:     userCode,
+		ClientName:   reqCode.ClientName,
+		PollInterval: DEVICE_CODE_POLL_INTERVAL,
+		ExpiresAt:    deviceToken.Expiry,
+	}
+	dbCode, err := InsertDeviceCode(tx, newCode, deviceToken.Hash)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: CreateCode: InsertDeviceCode: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: CreateCode: Commit: %v", err)
+		return nil, err
+	}
+
+	dbCode.DeviceCode = deviceToken.PlainText
+	dbCode.UserCode = tokens.FormatUserCode(dbCode.UserCode)
+
+	return dbCode, nil
+}
+
+// ApproveCode settles a pending code typed in by the signed in user, a
+// denied code is rejected the next time the device polls.
+func (d *PgDbsDeviceCodes) ApproveCode(reqUser *User, reqCode *DeviceCode, approve bool) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ApproveCode: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: DeviceCodes: ApproveCode: Rollback: %v", err)
+		}
+	}()
+
+	status := DEVICE_CODE_STATUS_DENIED
+	if approve {
+		status = DEVICE_CODE_STATUS_APPROVED
+	}
+
+	reqCode.UserCode = tokens.NormalizeUserCode(reqCode.UserCode)
+	err = UpdateDeviceCodeStatus(tx, reqUser, reqCode, status)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ApproveCode: UpdateDeviceCodeStatus: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ApproveCode: Commit: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// ExchangeCode is a device polling with its device code. Only an approved
+// code yields the user, and only once, the code is gone afterwards.
+func (d *PgDbsDeviceCodes) ExchangeCode(reqCode *DeviceCode) (*User, *DeviceCode, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ExchangeCode: Conn: %v", err)
+		return nil, nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: DeviceCodes: ExchangeCode: Rollback: %v", err)
+		}
+	}()
+
+	dbCode, err := SelectDeviceCodeForUpdate(tx, reqCode)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ExchangeCode: SelectDeviceCodeForUpdate: %v", err)
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	// every outcome but a plain error is final for this poll, so the
+	// bookkeeping below is committed before the result is returned
+	commitWith := func(resultErr error) (*User, *DeviceCode, error) {
+		if err := tx.Commit(); err != nil {
+			log.Printf("error: Dbs: DeviceCodes: ExchangeCode: Commit: %v", err)
+			return nil, nil, err
+		}
+		return nil, nil, resultErr
+	}
+
+	if now.After(dbCode.ExpiresAt) {
+		if err := DeleteDeviceCode(tx, dbCode); err != nil {
+			log.Printf("error: Dbs: DeviceCodes: ExchangeCode: DeleteDeviceCode: %v", err)
+			return nil, nil, err
+		}
+		return commitWith(ErrDeviceCodeExpired)
+	}
+
+	if dbCode.LastPolledAt != nil && now.Sub(*dbCode.LastPolledAt) < time.Duration(dbCode.PollInterval)*time.Second {
+		dbCode.PollInterval += DEVICE_CODE_SLOW_DOWN
+		if err := UpdateDeviceCodePolled(tx, dbCode, now); err != nil {
+			log.Printf("error: Dbs: DeviceCodes: ExchangeCode: UpdateDeviceCodePolled: %v", err)
+			return nil, nil, err
+		}
+		return commitWith(ErrSlowDown)
+	}
+
+	switch dbCode.Status {
+	case DEVICE_CODE_STATUS_PENDING:
+		if err := UpdateDeviceCodePolled(tx, dbCode, now); err != nil {
+			log.Printf("error: Dbs: DeviceCodes: ExchangeCode: UpdateDeviceCodePolled: %v", err)
+			return nil, nil, err
+		}
+		return commitWith(ErrAuthorizationPending)
+	case DEVICE_CODE_STATUS_DENIED:
+		if err := DeleteDeviceCode(tx, dbCode); err != nil {
+			log.Printf("error: Dbs: DeviceCodes: ExchangeCode: DeleteDeviceCode: %v", err)
+			return nil, nil, err
+		}
+		return commitWith(ErrAccessDenied)
+	}
+
+	if dbCode.UserId == nil {
+		log.Printf("error: Dbs: DeviceCodes: ExchangeCode: approved without user")
+		return nil, nil, sql.ErrNoRows
+	}
+
+	role, err := SelectUserRole(tx, *dbCode.UserId)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ExchangeCode: SelectUserRole: %v", err)
+		return nil, nil, err
+	}
+
+	err = DeleteDeviceCode(tx, dbCode)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ExchangeCode: DeleteDeviceCode: %v", err)
+		return nil, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: ExchangeCode: Commit: %v", err)
+		return nil, nil, err
+	}
+
+	dbUser := &User{
+		Id:   *dbCode.UserId,
+		Role: role,
+	}
+
+	return dbUser, dbCode, nil
+}
+
+func (d *PgDbsDeviceCodes) DeleteExpired() error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: DeleteExpired: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: DeviceCodes: DeleteExpired: Rollback: %v", err)
+		}
+	}()
+
+	err = DeleteExpiredDeviceCodes(tx)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: DeleteExpired: DeleteExpiredDeviceCodes: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: DeleteExpired: Commit: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func InsertDeviceCode(tx *sql.Tx, reqCode *DeviceCode, hash []byte) (*DeviceCode, error) {
+	result := &DeviceCode{}
+
+	query := `
+	INSERT INTO device_codes (id, device_code, user_code, client_name, poll_interval, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at, updated_at, user_code, client_name, status, poll_interval, last_polled_at, expires_at, user_id
+	`
+
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		hash,
+		reqCode.UserCode,
+		reqCode.ClientName,
+		reqCode.PollInterval,
+		reqCode.ExpiresAt,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.UserCode,
+		&result.ClientName,
+		&result.Status,
+		&result.PollInterval,
+		&result.LastPolledAt,
+		&result.ExpiresAt,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: InsertDeviceCode: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectDeviceCodeForUpdate(tx *sql.Tx, reqCode *DeviceCode) (*DeviceCode, error) {
+	result := &DeviceCode{}
+
+	query := `
+	SELECT id, created_at, updated_at, user_code, client_name, status, poll_interval, last_polled_at, expires_at, user_id
+	FROM device_codes
+	WHERE device_code = $1
+	FOR UPDATE
+	`
+
+	hash := tokens.HashFromPlainText(reqCode.DeviceCode)
+
+	err := tx.QueryRow(
+		query,
+		hash[:],
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.UserCode,
+		&result.ClientName,
+		&result.Status,
+		&result.PollInterval,
+		&result.LastPolledAt,
+		&result.ExpiresAt,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: SelectDeviceCodeForUpdate: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func UpdateDeviceCodeStatus(tx *sql.Tx, reqUser *User, reqCode *DeviceCode, status string) error {
+	query := `
+	UPDATE device_codes
+	SET updated_at = $2, status = $3, user_id = $4
+	WHERE user_code = $1
+	AND status = 'pending'
+	AND expires_at > $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqCode.UserCode,
+		time.Now(),
+		status,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: UpdateDeviceCodeStatus: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: UpdateDeviceCodeStatus: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: DeviceCodes: UpdateDeviceCodeStatus: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func UpdateDeviceCodePolled(tx *sql.Tx, reqCode *DeviceCode, polledAt time.Time) error {
+	query := `
+	UPDATE device_codes
+	SET updated_at = $2, last_polled_at = $2, poll_interval = $3
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(
+		query,
+		reqCode.Id,
+		polledAt,
+		reqCode.PollInterval,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: UpdateDeviceCodePolled: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func DeleteDeviceCode(tx *sql.Tx, reqCode *DeviceCode) error {
+	query := `
+	DELETE FROM device_codes
+	WHERE id = $1
+	`
+
+	_, err := tx.Exec(
+		query,
+		reqCode.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: DeleteDeviceCode: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func DeleteExpiredDeviceCodes(tx *sql.Tx) error {
+	query := `
+	DELETE FROM device_codes
+	WHERE expires_at < $1
+	`
+
+	_, err := tx.Exec(
+		query,
+		time.Now(),
+	)
+	if err != nil {
+		log.Printf("error: Dbs: DeviceCodes: DeleteExpiredDeviceCodes: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Genre struct {
+	Id        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+type ContentGenre struct {
+	ContentId uuid.UUID
+	Genre     Genre
+}
+
+var ErrUnknownGenre = errors.New("unknown genre")
+
+type DbsGenres interface {
+	GetAllGenres() ([]*Genre, error)
+	SetContentGenres(reqContent *Content, names []string) ([]*Genre, error)
+}
+
+type PgDbsGenres struct {
+	db DbService
+}
+
+var dbsGenresInstance *PgDbsGenres
+
+func NewDbsGenres(db DbService) DbsGenres {
+	if dbsGenresInstance != nil {
+		return dbsGenresInstance
+	}
+
+	newDbsGenres := &PgDbsGenres{
+		db: db,
+	}
+	dbsGenresInstance = newDbsGenres
+
+	return dbsGenresInstance
+}
+
+func (d *PgDbsGenres) GetAllGenres() ([]*Genre, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Genres: GetAllGenres: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Genres: GetAllGenres: Rollback: %v", err)
+		}
+	}()
+
+	dbGenres, err := SelectAllGenres(tx)
+	if err != nil {
+		log.Printf("error: Dbs: Genres: GetAllGenres: SelectAllGenres: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Genres: GetAllGenres: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbGenres, nil
+}
+
+// SetContentGenres replaces the genres of reqContent. Genres are curated, so
+// every name must already exist.
+func (d *PgDbsGenres) SetContentGenres(reqContent *Content, names []string) ([]*Genre, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Genres: SetContentGenres: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Genres: SetContentGenres: Rollback: %v", err)
+		}
+	}()
+
+	allGenres, err := SelectAllGenres(tx)
+	if err != nil {
+		log.Printf("error: Dbs: Genres: SetContentGenres: SelectAllGenres: %v", err)
+		return nil, err
+	}
+
+	genresByName := make(map[string]*Genre)
+	for _, v := range allGenres {
+		genresByName[strings.ToLower(v.Name)] = v
+	}
+
+	result := make([]*Genre, 0)
+	seen := make(map[uuid.UUID]bool)
+	for _, v := range names {
+		genre, ok := genresByName[strings.ToLower(strings.TrimSpace(v))]
+		if !ok {
+			log.Printf("error: Dbs: Genres: SetContentGenres: %v: %v", ErrUnknownGenre, v)
+			return nil, ErrUnknownGenre
+		}
+		if seen[genre.Id] {
+			continue
+		}
+		seen[genre.Id] = true
+		result = append(result, genre)
+	}
+
+	if err := DeleteContentGenres(tx, reqContent); err != nil {
+		log.Printf("error: Dbs: Genres: SetContentGenres: DeleteContentGenres: %v", err)
+		return nil, err
+	}
+
+	for _, v := range result {
+		if err := InsertContentGenre(tx, reqContent, v); err != nil {
+			log.Printf("error: Dbs: Genres: SetContentGenres: InsertContentGenre: %v", err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Genres: SetContentGenres: Commit: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectAllGenres(tx *sql.Tx) ([]*Genre, error) {
+	result := make([]*Genre, 0)
+
+	query := `SELECT id, created_at, updated_at, name FROM genres ORDER BY name ASC`
+
+	queryRows, err := tx.Query(query)
+	if err != nil {
+		log.Printf("error: Dbs: Genres: SelectAllGenres: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &Genre{}
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Name,
+		); err != nil {
+			log.Printf("error: Dbs: Genres: SelectAllGenres: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectContentGenres(tx *sql.Tx, reqContent []*Content) ([]*ContentGenre, error) {
+	result := make([]*ContentGenre, 0)
+
+	args := make([]uuid.UUID, 0)
+	for _, v := range reqContent {
+		args = append(args, v.Id)
+	}
+
+	if len(args) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT
+	rcg.content_id,
+	g.id, g.created_at, g.updated_at, g.name
+	FROM rel_content_genres rcg
+	JOIN genres g ON rcg.genres_id = g.id
+	WHERE rcg.content_id = ANY($1)
+	ORDER BY g.name ASC`
+
+	queryRows, err := tx.Query(
+		query,
+		args,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Genres: SelectContentGenres: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ContentGenre{}
+		if err := queryRows.Scan(
+			&temp.ContentId,
+			&temp.Genre.Id,
+			&temp.Genre.CreatedAt,
+			&temp.Genre.UpdatedAt,
+			&temp.Genre.Name,
+		); err != nil {
+			log.Printf("error: Dbs: Genres: SelectContentGenres: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func InsertContentGenre(tx *sql.Tx, reqContent *Content, reqGenre *Genre) error {
+	query := `INSERT INTO rel_content_genres (id, content_id, genres_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (content_id, genres_id) DO NOTHING`
+
+	if _, err := tx.Exec(
+		query,
+		uuid.New(),
+		reqContent.Id,
+		reqGenre.Id,
+	); err != nil {
+		log.Printf("error: Dbs: Genres: InsertContentGenre: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func DeleteContentGenres(tx *sql.Tx, reqContent *Content) error {
+	query := `DELETE FROM rel_content_genres WHERE content_id = $1`
+
+	if _, err := tx.Exec(
+		query,
+		reqContent.Id,
+	); err != nil {
+		log.Printf("error: Dbs: Genres: DeleteContentGenres: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -4,46 +4,68 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/JustinLi007/whatdoing-server/internal/tokens"
 	"github.com/google/uuid"
 )
 
+var ErrRefreshReused = errors.New("refresh token reused")
+
+// SESSION_CACHE_TTL is how long a session found active is trusted before it
+// is looked up again. Sessions revoked through this server are dropped from
+// the cache at once, the ttl only bounds how long another instance's
+// revocation takes to be noticed.
+const SESSION_CACHE_TTL = time.Minute
+
 type DbsJwt interface {
-	Insert(userId uuid.UUID, ttl_token, ttl_refresh time.Duration, scope string) (*tokens.Jwt, error)
-	Get(reqUser *User) (*tokens.Jwt, error)
+	Insert(reqUser *User, reqSession *Session, ttl_token, ttl_refresh time.Duration, scope string) (*tokens.Jwt, error)
+	Refresh(reqJwt *tokens.Jwt, reqSession *Session, ttl_token, ttl_refresh time.Duration) (*tokens.Jwt, error)
 	Delete(reqUser *User, reqJwt *tokens.Jwt) error
+	GetSessions(reqUser *User) ([]*Session, error)
+	DeleteSession(reqUser *User, reqSession *Session) error
+	DeleteOtherSessions(reqUser *User, reqSession *Session) error
 	DeleteExpired() error
+	SessionActive(sessionId uuid.UUID) (bool, error)
+	ForgetSessions(sessionIds ...uuid.UUID)
 }
 
 type PgDbsJwt struct {
-	db DbService
+	db   DbService
+	keys *tokens.Keys
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]time.Time
 }
 
 var dbsJwtInstance *PgDbsJwt
 
-func NewDbsJwt(db DbService) DbsJwt {
+func NewDbsJwt(db DbService, keys *tokens.Keys) DbsJwt {
 	if dbsJwtInstance != nil {
 		return dbsJwtInstance
 	}
 
 	newDbsJwt := &PgDbsJwt{
-		db: db,
+		db:       db,
+		keys:     keys,
+		sessions: make(map[uuid.UUID]time.Time),
 	}
 	dbsJwtInstance = newDbsJwt
 
 	return dbsJwtInstance
 }
 
-func (d *PgDbsJwt) Insert(userId uuid.UUID, ttl_token, ttl_refresh time.Duration, scope string) (*tokens.Jwt, error) {
-	token, err := tokens.GenerateJwt(userId, ttl_token, ttl_refresh, scope)
+// Insert starts a new session, reqSession describes the device signing in.
+func (d *PgDbsJwt) Insert(reqUser *User, reqSession *Session, ttl_token, ttl_refresh time.Duration, scope string) (*tokens.Jwt, error) {
+	token, err := tokens.GenerateJwt(d.keys, reqUser.Id, uuid.New(), reqUser.Role, ttl_token, ttl_refresh, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	query := `INSERT INTO jwt (id, token, refresh_token, refresh_token_expiration, scope, user_id)
-	VALUES ($1, $2, $3, $4, $5, $6);`
+	now := time.Now()
+	reqSession.SignedInAt = now
+	reqSession.LastUsedAt = now
 
 	tx, err := d.db.Conn().Begin()
 	if err != nil {
@@ -59,26 +81,11 @@ func (d *PgDbsJwt) Insert(userId uuid.UUID, ttl_token, ttl_refresh time.Duration
 		}
 	}()
 
-	result, err := tx.Exec(
-		query,
-		uuid.New(),
-		token.Token.Hash,
-		token.RefreshToken.Hash,
-		token.RefreshToken.Expiry,
-		token.Scope,
-		token.UserId,
-	)
+	err = InsertJwt(tx, token, reqSession)
 	if err != nil {
 		return nil, err
 	}
 
-	n, err := result.RowsAffected()
-	if err == nil {
-		if n == 0 {
-			return nil, errors.New("error: dbs jwt CreateToken, failed to insert token.")
-		}
-	}
-
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -87,10 +94,14 @@ func (d *PgDbsJwt) Insert(userId uuid.UUID, ttl_token, ttl_refresh time.Duration
 	return token, nil
 }
 
-func (d *PgDbsJwt) Get(reqUser *User) (*tokens.Jwt, error) {
+// Refresh trades the refresh token in reqJwt for a new pair in the same
+// family. The old refresh token is kept as rotated, presenting it again means
+// it leaked, so the whole family is revoked and ErrRefreshReused returned.
+// The user agent and ip in reqSession replace the stored ones.
+func (d *PgDbsJwt) Refresh(reqJwt *tokens.Jwt, reqSession *Session, ttl_token, ttl_refresh time.Duration) (*tokens.Jwt, error) {
 	tx, err := d.db.Conn().Begin()
 	if err != nil {
-		log.Printf("error: Dbs: Jwt: Get: Conn: %v", err)
+		log.Printf("error: Dbs: Jwt: Refresh: Conn: %v", err)
 		return nil, err
 	}
 	defer func() {
@@ -99,23 +110,71 @@ func (d *PgDbsJwt) Get(reqUser *User) (*tokens.Jwt, error) {
 			if err.Error() == "sql: transaction has already been committed or rolled back" {
 				return
 			}
-			log.Printf("error: Dbs: Jwt: Get: Rollback: %v", err)
+			log.Printf("error: Dbs: Jwt: Refresh: Rollback: %v", err)
 		}
 	}()
 
-	dbJwt, err := SelectJwtByUserId(tx, reqUser)
+	dbJwt, dbSession, err := SelectJwtByRefreshToken(tx, reqJwt)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: Refresh: SelectJwtByRefreshToken: %v", err)
+		return nil, err
+	}
+
+	if dbJwt.RotatedAt != nil {
+		log.Printf("error: Dbs: Jwt: Refresh: %v: family %v", ErrRefreshReused, dbJwt.FamilyId)
+		err = DeleteJwtFamily(tx, dbJwt.FamilyId)
+		if err != nil {
+			log.Printf("error: Dbs: Jwt: Refresh: DeleteJwtFamily: %v", err)
+			return nil, err
+		}
+		err = tx.Commit()
+		if err != nil {
+			log.Printf("error: Dbs: Jwt: Refresh: Commit: %v", err)
+			return nil, err
+		}
+		d.ForgetSessions(dbJwt.FamilyId)
+		return nil, ErrRefreshReused
+	}
+
+	if time.Now().After(dbJwt.RefreshToken.Expiry) {
+		log.Printf("error: Dbs: Jwt: Refresh: %v", tokens.ErrExpiredToken)
+		return nil, tokens.ErrExpiredToken
+	}
+
+	role, err := SelectUserRole(tx, dbJwt.UserId)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: Refresh: SelectUserRole: %v", err)
+		return nil, err
+	}
+
+	err = UpdateJwtRotated(tx, dbJwt)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: Refresh: UpdateJwtRotated: %v", err)
+		return nil, err
+	}
+
+	newJwt, err := tokens.GenerateJwt(d.keys, dbJwt.UserId, dbJwt.FamilyId, role, ttl_token, ttl_refresh, dbJwt.Scope)
 	if err != nil {
-		log.Printf("error: Dbs: Jwt: Get: SelectJwt: %v", err)
+		log.Printf("error: Dbs: Jwt: Refresh: GenerateJwt: %v", err)
+		return nil, err
+	}
+
+	dbSession.UserAgent = reqSession.UserAgent
+	dbSession.IpAddress = reqSession.IpAddress
+	dbSession.LastUsedAt = time.Now()
+	err = InsertJwt(tx, newJwt, dbSession)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: Refresh: InsertJwt: %v", err)
 		return nil, err
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		log.Printf("error: Dbs: Jwt: Get: Commit: %v", err)
+		log.Printf("error: Dbs: Jwt: Refresh: Commit: %v", err)
 		return nil, err
 	}
 
-	return dbJwt, nil
+	return newJwt, nil
 }
 
 func (d *PgDbsJwt) Delete(reqUser *User, reqJwt *tokens.Jwt) error {
@@ -134,7 +193,7 @@ func (d *PgDbsJwt) Delete(reqUser *User, reqJwt *tokens.Jwt) error {
 		}
 	}()
 
-	err = DeleteJwt(tx, reqUser, reqJwt)
+	familyId, err := DeleteJwt(tx, reqUser, reqJwt)
 	if err != nil {
 		log.Printf("error: Dbs: Jwt: Delete: DeleteJwt: %v", err)
 		return err
@@ -145,6 +204,7 @@ func (d *PgDbsJwt) Delete(reqUser *User, reqJwt *tokens.Jwt) error {
 		log.Printf("error: Dbs: Jwt: Delete: Commit: %v", err)
 		return err
 	}
+	d.ForgetSessions(familyId)
 
 	return nil
 }
@@ -180,72 +240,273 @@ func (d *PgDbsJwt) DeleteExpired() error {
 	return nil
 }
 
-func SelectJwtByUserId(tx *sql.Tx, reqUser *User) (*tokens.Jwt, error) {
+// SessionActive reports whether the session an access token was issued in
+// still exists, so revoked sessions lose their access tokens right away
+// rather than when the tokens expire.
+func (d *PgDbsJwt) SessionActive(sessionId uuid.UUID) (bool, error) {
+	d.mu.Lock()
+	until, ok := d.sessions[sessionId]
+	d.mu.Unlock()
+	if ok && time.Now().Before(until) {
+		return true, nil
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: SessionActive: Conn: %v", err)
+		return false, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Jwt: SessionActive: Rollback: %v", err)
+		}
+	}()
+
+	active, err := SelectSessionActive(tx, sessionId)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: SessionActive: SelectSessionActive: %v", err)
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: SessionActive: Commit: %v", err)
+		return false, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if active {
+		d.sessions[sessionId] = time.Now().Add(SESSION_CACHE_TTL)
+	} else {
+		delete(d.sessions, sessionId)
+	}
+
+	return active, nil
+}
+
+// ForgetSessions drops revoked sessions from the cache. It runs after the
+// revocation committed, including revocations made outside of DbsJwt.
+func (d *PgDbsJwt) ForgetSessions(sessionIds ...uuid.UUID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range d.sessions {
+		if now.After(v) {
+			delete(d.sessions, k)
+		}
+	}
+	for _, v := range sessionIds {
+		delete(d.sessions, v)
+	}
+}
+
+func SelectSessionActive(tx *sql.Tx, sessionId uuid.UUID) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM jwt
+		WHERE family_id = $1
+		AND rotated_at IS NULL
+		AND refresh_token_expiration > $2
+	)
+	`
+
+	var active bool
+	err := tx.QueryRow(
+		query,
+		sessionId,
+		time.Now(),
+	).Scan(&active)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: SelectSessionActive: Scan: %v", err)
+		return false, err
+	}
+
+	return active, nil
+}
+
+func InsertJwt(tx *sql.Tx, reqJwt *tokens.Jwt, reqSession *Session) error {
+	query := `INSERT INTO jwt (id, jti, family_id, refresh_token, refresh_token_expiration, scope, user_id, device_name, user_agent, ip_address, signed_in_at, last_used_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);`
+
+	queryResult, err := tx.Exec(
+		query,
+		uuid.New(),
+		reqJwt.Jti,
+		reqJwt.FamilyId,
+		reqJwt.RefreshToken.Hash,
+		reqJwt.RefreshToken.Expiry,
+		reqJwt.Scope,
+		reqJwt.UserId,
+		reqSession.DeviceName,
+		reqSession.UserAgent,
+		reqSession.IpAddress,
+		reqSession.SignedInAt,
+		reqSession.LastUsedAt,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: InsertJwt: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: InsertJwt: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: Jwt: InsertJwt: RowsAffected: %v", n)
+		return errors.New("error: dbs jwt CreateToken, failed to insert token.")
+	}
+
+	return nil
+}
+
+func SelectJwtByRefreshToken(tx *sql.Tx, reqJwt *tokens.Jwt) (*tokens.Jwt, *Session, error) {
 	result := &tokens.Jwt{
-		Token:        &tokens.Token{},
 		RefreshToken: &tokens.Token{},
 	}
+	resultSession := &Session{}
 
-	query := `SELECT * FROM jwt
-	WHERE user_id = $1
-	ORDER BY created_at DESC
-	LIMIT 1`
+	query := `SELECT id, created_at, updated_at, jti, family_id, rotated_at, refresh_token, refresh_token_expiration, scope, user_id, device_name, user_agent, ip_address, signed_in_at, last_used_at FROM jwt
+	WHERE refresh_token = $1
+	FOR UPDATE`
+
+	refreshHash := tokens.HashFromPlainText(reqJwt.RefreshToken.PlainText)
 
 	err := tx.QueryRow(
 		query,
-		reqUser.Id,
+		refreshHash[:],
 	).Scan(
 		&result.Id,
 		&result.CreatedAt,
 		&result.UpdatedAt,
-		&result.Token.Hash,
+		&result.Jti,
+		&result.FamilyId,
+		&result.RotatedAt,
 		&result.RefreshToken.Hash,
 		&result.RefreshToken.Expiry,
 		&result.Scope,
 		&result.UserId,
+		&resultSession.DeviceName,
+		&resultSession.UserAgent,
+		&resultSession.IpAddress,
+		&resultSession.SignedInAt,
+		&resultSession.LastUsedAt,
 	)
 	if err != nil {
-		log.Printf("error: Dbs: Jwt: SelectJwt: Scan: %v", err)
-		return nil, err
+		log.Printf("error: Dbs: Jwt: SelectJwtByRefreshToken: Scan: %v", err)
+		return nil, nil, err
 	}
+	resultSession.Id = result.FamilyId
+	resultSession.ExpiresAt = result.RefreshToken.Expiry
 
-	return result, nil
+	return result, resultSession, nil
 }
 
-func DeleteJwt(tx *sql.Tx, reqUser *User, reqJwt *tokens.Jwt) error {
+func UpdateJwtRotated(tx *sql.Tx, reqJwt *tokens.Jwt) error {
 	query := `
-	DELETE FROM jwt
-	WHERE token = $1
-	AND user_id = $2
+	UPDATE jwt
+	SET updated_at = $2, rotated_at = $2
+	WHERE id = $1
+	AND rotated_at IS NULL
 	`
 
-	log.Printf(reqJwt.Token.PlainText)
-	reqJwtHash := tokens.HashFromPlainText(reqJwt.Token.PlainText)
-
 	queryResult, err := tx.Exec(
 		query,
-		reqJwtHash[:],
-		reqUser.Id,
+		reqJwt.Id,
+		time.Now(),
 	)
 	if err != nil {
-		log.Printf("error: Dbs: Jwt: DeleteJwt: Query: %v", err)
+		log.Printf("error: Dbs: Jwt: UpdateJwtRotated: Exec: %v", err)
 		return err
 	}
 
 	n, err := queryResult.RowsAffected()
 	if err != nil {
-		log.Printf("error: Dbs: Jwt: DeleteJwt: RowsAffected: %v", err)
+		log.Printf("error: Dbs: Jwt: UpdateJwtRotated: RowsAffected: %v", err)
 		return err
 	}
 
 	if n == 0 {
-		log.Printf("error: Dbs: Jwt: DeleteJwt: RowsAffected: %v", n)
+		log.Printf("error: Dbs: Jwt: UpdateJwtRotated: RowsAffected: %v", n)
 		return sql.ErrNoRows
 	}
 
 	return nil
 }
 
+func DeleteJwtFamily(tx *sql.Tx, familyId uuid.UUID) error {
+	query := `
+	DELETE FROM jwt
+	WHERE family_id = $1
+	`
+
+	_, err := tx.Exec(
+		query,
+		familyId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteJwtFamily: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteJwt revokes the family the access token was issued in, so the refresh
+// tokens rotated before it go too. It returns the family id.
+func DeleteJwt(tx *sql.Tx, reqUser *User, reqJwt *tokens.Jwt) (uuid.UUID, error) {
+	var familyId uuid.UUID
+
+	query := `
+	DELETE FROM jwt
+	WHERE family_id = (
+		SELECT family_id FROM jwt
+		WHERE jti = $1
+		AND user_id = $2
+	)
+	RETURNING family_id
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		reqJwt.Jti,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteJwt: Query: %v", err)
+		return familyId, err
+	}
+	defer queryRows.Close()
+
+	n := 0
+	for queryRows.Next() {
+		if err := queryRows.Scan(&familyId); err != nil {
+			log.Printf("error: Dbs: Jwt: DeleteJwt: Scan: %v", err)
+			return familyId, err
+		}
+		n++
+	}
+	if err := queryRows.Err(); err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteJwt: Rows: %v", err)
+		return familyId, err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: Jwt: DeleteJwt: RowsAffected: %v", n)
+		return familyId, sql.ErrNoRows
+	}
+
+	return familyId, nil
+}
+
 func DeleteExpired(tx *sql.Tx) error {
 	query := `
 	DELETE FROM jwt
@@ -0,0 +1,825 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/algo"
+	"github.com/google/uuid"
+)
+
+type List struct {
+	Id          uuid.UUID   `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Name        string      `json:"name"`
+	Description *string     `json:"description"`
+	Public      bool        `json:"public"`
+	UserId      uuid.UUID   `json:"-"`
+	Items       []*ListItem `json:"items,omitempty"`
+}
+
+// ListItem is a catalog entry on a list. Items are ordered by Rank, a key from
+// algo.RankBetween, so moving an item only rewrites that item.
+type ListItem struct {
+	Id        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Rank      string    `json:"rank"`
+	ListId    uuid.UUID `json:"list_id"`
+	Content   *Content  `json:"content"`
+}
+
+var ErrInvalidListName = errors.New("invalid list name")
+var ErrListItemExists = errors.New("content already on list")
+
+type DbsLists interface {
+	GetLists(reqUser *User) ([]*List, error)
+	GetList(reqUser *User, reqList *List) (*List, error)
+	CreateList(reqUser *User, reqList *List) (*List, error)
+	UpdateList(reqUser *User, reqList *List) (*List, error)
+	DeleteList(reqUser *User, reqList *List) error
+	AddItem(reqUser *User, reqList *List, reqContent *Content) (*ListItem, error)
+	MoveItem(reqUser *User, reqItem *ListItem, after *uuid.UUID) (*ListItem, error)
+	RemoveItem(reqUser *User, reqItem *ListItem) error
+}
+
+type PgDbsLists struct {
+	db DbService
+}
+
+var dbsListsInstance *PgDbsLists
+
+func NewDbsLists(db DbService) DbsLists {
+	if dbsListsInstance != nil {
+		return dbsListsInstance
+	}
+
+	newDbsLists := &PgDbsLists{
+		db: db,
+	}
+	dbsListsInstance = newDbsLists
+
+	return dbsListsInstance
+}
+
+func (d *PgDbsLists) GetLists(reqUser *User) ([]*List, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: GetLists: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: GetLists: Rollback: %v", err)
+		}
+	}()
+
+	dbLists, err := SelectLists(tx, reqUser)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: GetLists: SelectLists: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: GetLists: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbLists, nil
+}
+
+// GetList returns a list with its items. Private lists are only visible to
+// their owner, everyone else gets sql.ErrNoRows.
+func (d *PgDbsLists) GetList(reqUser *User, reqList *List) (*List, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: GetList: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: GetList: Rollback: %v", err)
+		}
+	}()
+
+	dbList, err := SelectList(tx, reqList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: GetList: SelectList: %v", err)
+		return nil, err
+	}
+	if !dbList.Public && (reqUser == nil || reqUser.Id != dbList.UserId) {
+		log.Printf("error: Dbs: Lists: GetList: private list")
+		return nil, sql.ErrNoRows
+	}
+
+	dbItems, err := SelectListItems(tx, reqUser, dbList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: GetList: SelectListItems: %v", err)
+		return nil, err
+	}
+
+	allNames := make([]*ContentAltName, 0)
+	if len(dbItems) > 0 {
+		tempContent := make([]*Content, 0)
+		for _, v := range dbItems {
+			tempContent = append(tempContent, v.Content)
+		}
+
+		allNames, err = SelectContentAltNames(tx, tempContent)
+		if err != nil {
+			log.Printf("error: Dbs: Lists: GetList: SelectContentAltNames: %v", err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: GetList: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	for _, v := range dbItems {
+		if altNames, ok := namesMap[v.Content.Id]; ok {
+			v.Content.AlternativeNames = altNames
+		}
+	}
+	dbList.Items = dbItems
+
+	return dbList, nil
+}
+
+func (d *PgDbsLists) CreateList(reqUser *User, reqList *List) (*List, error) {
+	reqList.Name = strings.TrimSpace(reqList.Name)
+	if reqList.Name == "" {
+		log.Printf("error: Dbs: Lists: CreateList: %v", ErrInvalidListName)
+		return nil, ErrInvalidListName
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: CreateList: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: CreateList: Rollback: %v", err)
+		}
+	}()
+
+	dbList, err := InsertList(tx, reqUser, reqList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: CreateList: InsertList: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: CreateList: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbList, nil
+}
+
+func (d *PgDbsLists) UpdateList(reqUser *User, reqList *List) (*List, error) {
+	reqList.Name = strings.TrimSpace(reqList.Name)
+	if reqList.Name == "" {
+		log.Printf("error: Dbs: Lists: UpdateList: %v", ErrInvalidListName)
+		return nil, ErrInvalidListName
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: UpdateList: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: UpdateList: Rollback: %v", err)
+		}
+	}()
+
+	dbList, err := UpdateList(tx, reqUser, reqList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: UpdateList: UpdateList: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: UpdateList: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbList, nil
+}
+
+func (d *PgDbsLists) DeleteList(reqUser *User, reqList *List) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: DeleteList: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: DeleteList: Rollback: %v", err)
+		}
+	}()
+
+	if err := DeleteList(tx, reqUser, reqList); err != nil {
+		log.Printf("error: Dbs: Lists: DeleteList: DeleteList: %v", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: DeleteList: Commit: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddItem appends reqContent to the end of the list.
+func (d *PgDbsLists) AddItem(reqUser *User, reqList *List, reqContent *Content) (*ListItem, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: AddItem: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: AddItem: Rollback: %v", err)
+		}
+	}()
+
+	dbList, err := SelectOwnedListForUpdate(tx, reqUser, reqList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: AddItem: SelectOwnedListForUpdate: %v", err)
+		return nil, err
+	}
+
+	lastRank, err := SelectListLastRank(tx, dbList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: AddItem: SelectListLastRank: %v", err)
+		return nil, err
+	}
+
+	reqItem := &ListItem{
+		Rank:   algo.RankBetween(lastRank, ""),
+		ListId: dbList.Id,
+		Content: &Content{
+			Id: reqContent.Id,
+		},
+	}
+	dbItem, err := InsertListItem(tx, reqUser, reqItem)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: AddItem: InsertListItem: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: AddItem: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbItem, nil
+}
+
+// MoveItem places reqItem directly after the item with id after, or first in
+// the list when after is nil. Only the moved item is rewritten.
+func (d *PgDbsLists) MoveItem(reqUser *User, reqItem *ListItem, after *uuid.UUID) (*ListItem, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: MoveItem: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: MoveItem: Rollback: %v", err)
+		}
+	}()
+
+	reqList := &List{
+		Id: reqItem.ListId,
+	}
+	dbList, err := SelectOwnedListForUpdate(tx, reqUser, reqList)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: MoveItem: SelectOwnedListForUpdate: %v", err)
+		return nil, err
+	}
+
+	lo := ""
+	if after != nil {
+		if *after == reqItem.Id {
+			log.Printf("error: Dbs: Lists: MoveItem: item placed after itself")
+			return nil, sql.ErrNoRows
+		}
+		afterItem := &ListItem{
+			Id:     *after,
+			ListId: dbList.Id,
+		}
+		lo, err = SelectListItemRank(tx, afterItem)
+		if err != nil {
+			log.Printf("error: Dbs: Lists: MoveItem: SelectListItemRank: %v", err)
+			return nil, err
+		}
+	}
+
+	hi, err := SelectListNextRank(tx, dbList, reqItem, lo)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: MoveItem: SelectListNextRank: %v", err)
+		return nil, err
+	}
+
+	reqItem.Rank = algo.RankBetween(lo, hi)
+	dbItem, err := UpdateListItemRank(tx, reqItem)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: MoveItem: UpdateListItemRank: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: MoveItem: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbItem, nil
+}
+
+func (d *PgDbsLists) RemoveItem(reqUser *User, reqItem *ListItem) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: RemoveItem: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Lists: RemoveItem: Rollback: %v", err)
+		}
+	}()
+
+	reqList := &List{
+		Id: reqItem.ListId,
+	}
+	if _, err := SelectOwnedListForUpdate(tx, reqUser, reqList); err != nil {
+		log.Printf("error: Dbs: Lists: RemoveItem: SelectOwnedListForUpdate: %v", err)
+		return err
+	}
+
+	if err := DeleteListItem(tx, reqItem); err != nil {
+		log.Printf("error: Dbs: Lists: RemoveItem: DeleteListItem: %v", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Lists: RemoveItem: Commit: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func SelectLists(tx *sql.Tx, reqUser *User) ([]*List, error) {
+	result := make([]*List, 0)
+
+	query := `SELECT id, created_at, updated_at, name, description, public, user_id
+	FROM lists
+	WHERE user_id = $1
+	ORDER BY name ASC`
+
+	queryRows, err := tx.Query(query, reqUser.Id)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: SelectLists: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &List{}
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Name,
+			&temp.Description,
+			&temp.Public,
+			&temp.UserId,
+		); err != nil {
+			log.Printf("error: Dbs: Lists: SelectLists: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectList(tx *sql.Tx, reqList *List) (*List, error) {
+	result := &List{}
+
+	query := `SELECT id, created_at, updated_at, name, description, public, user_id
+	FROM lists
+	WHERE id = $1`
+
+	err := tx.QueryRow(
+		query,
+		reqList.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+		&result.Description,
+		&result.Public,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: SelectList: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SelectOwnedListForUpdate locks the list so concurrent rank changes on it
+// are serialized.
+func SelectOwnedListForUpdate(tx *sql.Tx, reqUser *User, reqList *List) (*List, error) {
+	result := &List{}
+
+	query := `SELECT id, created_at, updated_at, name, description, public, user_id
+	FROM lists
+	WHERE id = $1
+	AND user_id = $2
+	FOR UPDATE`
+
+	err := tx.QueryRow(
+		query,
+		reqList.Id,
+		reqUser.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+		&result.Description,
+		&result.Public,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: SelectOwnedListForUpdate: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func InsertList(tx *sql.Tx, reqUser *User, reqList *List) (*List, error) {
+	result := &List{}
+
+	query := `INSERT INTO lists (id, name, description, public, user_id)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at, name, description, public, user_id`
+
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		reqList.Name,
+		reqList.Description,
+		reqList.Public,
+		reqUser.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+		&result.Description,
+		&result.Public,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: InsertList: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func UpdateList(tx *sql.Tx, reqUser *User, reqList *List) (*List, error) {
+	result := &List{}
+
+	query := `UPDATE lists
+	SET updated_at = $3, name = $4, description = $5, public = $6
+	WHERE id = $1
+	AND user_id = $2
+	RETURNING id, created_at, updated_at, name, description, public, user_id`
+
+	err := tx.QueryRow(
+		query,
+		reqList.Id,
+		reqUser.Id,
+		time.Now(),
+		reqList.Name,
+		reqList.Description,
+		reqList.Public,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+		&result.Description,
+		&result.Public,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: UpdateList: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func DeleteList(tx *sql.Tx, reqUser *User, reqList *List) error {
+	query := `DELETE FROM lists WHERE id = $1 AND user_id = $2`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqList.Id,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: DeleteList: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: DeleteList: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: Lists: DeleteList: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SelectListItems leaves out content under review unless reqUser, who may be
+// nil, submitted it.
+func SelectListItems(tx *sql.Tx, reqUser *User, reqList *List) ([]*ListItem, error) {
+	result := make([]*ListItem, 0)
+
+	viewerId := uuid.Nil
+	if reqUser != nil {
+		viewerId = reqUser.Id
+	}
+
+	query := `SELECT
+	i.id, i.created_at, i.updated_at, i.rank, i.list_id,
+	a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM list_items i
+	JOIN content a ON i.content_id = a.id
+	JOIN content_names an ON a.content_names_id = an.id
+	WHERE i.list_id = $1
+	AND (
+		a.status = 'approved'
+		OR EXISTS (
+			SELECT 1 FROM rel_users_content owner
+			WHERE owner.content_id = a.id
+			AND owner.user_id = $2
+		)
+	)
+	ORDER BY i.rank ASC`
+
+	queryRows, err := tx.Query(query, reqList.Id, viewerId)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: SelectListItems: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ListItem{
+			Content: &Content{
+				AlternativeNames: make([]*ContentName, 0),
+			},
+		}
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Rank,
+			&temp.ListId,
+			&temp.Content.Id,
+			&temp.Content.CreatedAt,
+			&temp.Content.UpdatedAt,
+			&temp.Content.Kind,
+			&temp.Content.Unit,
+			&temp.Content.Total,
+			&temp.Content.Seasons,
+			&temp.Content.Description,
+			&temp.Content.ImageUrl,
+			&temp.Content.UnitMinutes,
+			&temp.Content.ContentName.Id,
+			&temp.Content.ContentName.CreatedAt,
+			&temp.Content.ContentName.UpdatedAt,
+			&temp.Content.ContentName.Name,
+		); err != nil {
+			log.Printf("error: Dbs: Lists: SelectListItems: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+// SelectListLastRank returns the rank of the last item, or "" for an empty
+// list.
+func SelectListLastRank(tx *sql.Tx, reqList *List) (string, error) {
+	var rank string
+
+	query := `SELECT COALESCE(MAX(rank), '') FROM list_items WHERE list_id = $1`
+
+	if err := tx.QueryRow(query, reqList.Id).Scan(&rank); err != nil {
+		log.Printf("error: Dbs: Lists: SelectListLastRank: Scan: %v", err)
+		return "", err
+	}
+
+	return rank, nil
+}
+
+func SelectListItemRank(tx *sql.Tx, reqItem *ListItem) (string, error) {
+	var rank string
+
+	query := `SELECT rank FROM list_items WHERE id = $1 AND list_id = $2`
+
+	if err := tx.QueryRow(query, reqItem.Id, reqItem.ListId).Scan(&rank); err != nil {
+		log.Printf("error: Dbs: Lists: SelectListItemRank: Scan: %v", err)
+		return "", err
+	}
+
+	return rank, nil
+}
+
+// SelectListNextRank returns the first rank after rank, ignoring reqItem, or
+// "" when nothing follows.
+func SelectListNextRank(tx *sql.Tx, reqList *List, reqItem *ListItem, rank string) (string, error) {
+	var next string
+
+	query := `SELECT COALESCE(MIN(rank), '')
+	FROM list_items
+	WHERE list_id = $1
+	AND id <> $2
+	AND rank > $3`
+
+	if err := tx.QueryRow(query, reqList.Id, reqItem.Id, rank).Scan(&next); err != nil {
+		log.Printf("error: Dbs: Lists: SelectListNextRank: Scan: %v", err)
+		return "", err
+	}
+
+	return next, nil
+}
+
+// InsertListItem returns ErrListItemExists when the content is already on
+// the list, and sql.ErrNoRows when it does not exist or is under review and
+// reqUser did not submit it.
+func InsertListItem(tx *sql.Tx, reqUser *User, reqItem *ListItem) (*ListItem, error) {
+	result := &ListItem{
+		Content: &Content{},
+	}
+
+	query := `INSERT INTO list_items (id, rank, list_id, content_id)
+	SELECT $1, $2, $3, content.id
+	FROM content
+	WHERE content.id = $4
+	AND (
+		content.status = 'approved'
+		OR EXISTS (
+			SELECT 1 FROM rel_users_content owner
+			WHERE owner.content_id = content.id
+			AND owner.user_id = $5
+		)
+	)
+	ON CONFLICT (list_id, content_id) DO NOTHING
+	RETURNING id, created_at, updated_at, rank, list_id, content_id`
+
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		reqItem.Rank,
+		reqItem.ListId,
+		reqItem.Content.Id,
+		reqUser.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Rank,
+		&result.ListId,
+		&result.Content.Id,
+	)
+	if err == sql.ErrNoRows {
+		var exists bool
+		queryExists := `SELECT EXISTS (
+			SELECT 1 FROM list_items WHERE list_id = $1 AND content_id = $2
+		)`
+		if err := tx.QueryRow(queryExists, reqItem.ListId, reqItem.Content.Id).Scan(&exists); err != nil {
+			log.Printf("error: Dbs: Lists: InsertListItem: Exists: %v", err)
+			return nil, err
+		}
+		if exists {
+			return nil, ErrListItemExists
+		}
+		return nil, sql.ErrNoRows
+	} else if err != nil {
+		log.Printf("error: Dbs: Lists: InsertListItem: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func UpdateListItemRank(tx *sql.Tx, reqItem *ListItem) (*ListItem, error) {
+	result := &ListItem{
+		Content: &Content{},
+	}
+
+	query := `UPDATE list_items
+	SET updated_at = $3, rank = $4
+	WHERE id = $1
+	AND list_id = $2
+	RETURNING id, created_at, updated_at, rank, list_id, content_id`
+
+	err := tx.QueryRow(
+		query,
+		reqItem.Id,
+		reqItem.ListId,
+		time.Now(),
+		reqItem.Rank,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Rank,
+		&result.ListId,
+		&result.Content.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: UpdateListItemRank: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func DeleteListItem(tx *sql.Tx, reqItem *ListItem) error {
+	query := `DELETE FROM list_items WHERE id = $1 AND list_id = $2`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqItem.Id,
+		reqItem.ListId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Lists: DeleteListItem: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: Lists: DeleteListItem: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: Lists: DeleteListItem: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
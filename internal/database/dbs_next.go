@@ -0,0 +1,259 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NEXT_LIMIT_DEFAULT = 10
+
+	// NEXT_WEIGHT_* are the points an entry earns towards its place in GetNext.
+	NEXT_WEIGHT_PRIORITY = 10.0
+	NEXT_WEIGHT_RECENCY  = 20.0
+	NEXT_WEIGHT_FINISH   = 15.0
+	NEXT_WEIGHT_NEAR_END = 10.0
+	NEXT_PENALTY_TOO_BIG = 30.0
+
+	// NEXT_RECENCY_HALF_LIFE is how long it takes the recency bonus to halve.
+	NEXT_RECENCY_HALF_LIFE = 7 * 24 * time.Hour
+)
+
+// Recommendation is an entry suggested by GetNext, with the reasons behind
+// its score. Started entries always rank ahead of entries not started yet.
+type Recommendation struct {
+	Progress         *ProgressContent `json:"progress"`
+	Started          bool             `json:"started"`
+	Score            float64          `json:"score"`
+	LastProgressAt   *time.Time       `json:"last_progress_at"`
+	RemainingUnits   *int             `json:"remaining_units"`
+	RemainingMinutes *int             `json:"remaining_minutes"`
+	SessionUnits     *int             `json:"session_units"`
+	Reasons          []string         `json:"reasons"`
+}
+
+func (d *PgDbsProgressContent) GetNext(reqUser *User, budgetMinutes *int, limit int) ([]*Recommendation, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetNext: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent GetNext: Rollback: %v", err)
+		}
+	}()
+
+	started, err := SelectProgressStarted(tx, reqUser, nil, SORT_ASC)
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetNext: SelectProgressStarted: %v", err)
+		return nil, err
+	}
+
+	notStarted, err := SelectProgressNotStarted(tx, reqUser, nil, SORT_ASC)
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetNext: SelectProgressNotStarted: %v", err)
+		return nil, err
+	}
+
+	all := append(started, notStarted...)
+	if len(all) == 0 {
+		if err := tx.Commit(); err != nil {
+			log.Printf("error: DbsProgressContent GetNext: Commit: %v", err)
+			return nil, err
+		}
+		return make([]*Recommendation, 0), nil
+	}
+
+	lastProgress, err := SelectLastProgressTimes(tx, reqUser, all)
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetNext: SelectLastProgressTimes: %v", err)
+		return nil, err
+	}
+
+	tempContent := make([]*Content, 0)
+	for _, v := range all {
+		tempContent = append(tempContent, v.Content)
+	}
+	allNames, err := SelectContentAltNames(tx, tempContent)
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetNext: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetNext: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent GetNext: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	now := time.Now()
+	result := make([]*Recommendation, 0, len(all))
+	for k, v := range all {
+		if altNames, ok := namesMap[v.Content.Id]; ok {
+			v.Content.AlternativeNames = altNames
+		}
+		v.resolveStatus()
+		v.resolveScore(scale)
+
+		recommendation := &Recommendation{
+			Progress: v,
+			Started:  k < len(started),
+			Reasons:  make([]string, 0),
+		}
+		if at, ok := lastProgress[v.Id]; ok {
+			recommendation.LastProgressAt = &at
+		}
+		recommendation.rank(budgetMinutes, now)
+		result = append(result, recommendation)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Started != result[j].Started {
+			return result[i].Started
+		}
+		return result[i].Score > result[j].Score
+	})
+
+	if limit <= 0 {
+		limit = NEXT_LIMIT_DEFAULT
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// rank scores the entry on its priority, how recently it was progressed, how
+// much of it is left and, given a budget, how well it fits in the time.
+func (r *Recommendation) rank(budgetMinutes *int, now time.Time) {
+	p := r.Progress
+	unit := p.Content.Unit
+	unitSingular := strings.TrimSuffix(unit, "s")
+
+	if r.Started {
+		r.Reasons = append(r.Reasons, "already in progress")
+	} else {
+		r.Reasons = append(r.Reasons, "not started yet")
+	}
+
+	if p.Priority > PRIORITY_NONE {
+		r.Score += NEXT_WEIGHT_PRIORITY * float64(p.Priority)
+		r.Reasons = append(r.Reasons, fmt.Sprintf("priority %d", p.Priority))
+	}
+
+	if r.Started {
+		last := p.UpdatedAt
+		if r.LastProgressAt != nil {
+			last = *r.LastProgressAt
+		}
+		elapsed := now.Sub(last)
+		r.Score += NEXT_WEIGHT_RECENCY * math.Pow(0.5, float64(elapsed)/float64(NEXT_RECENCY_HALF_LIFE))
+		days := int(elapsed.Hours() / 24)
+		switch days {
+		case 0:
+			r.Reasons = append(r.Reasons, "progressed today")
+		case 1:
+			r.Reasons = append(r.Reasons, "progressed yesterday")
+		default:
+			r.Reasons = append(r.Reasons, fmt.Sprintf("last progressed %d days ago", days))
+		}
+	}
+
+	if p.Content.Total != nil {
+		remaining := max(*p.Content.Total-p.Value, 0)
+		r.RemainingUnits = &remaining
+		if p.Content.UnitMinutes != nil {
+			remainingMinutes := remaining * *p.Content.UnitMinutes
+			r.RemainingMinutes = &remainingMinutes
+		}
+		if r.Started && remaining > 0 && remaining*10 <= *p.Content.Total {
+			r.Score += NEXT_WEIGHT_NEAR_END
+			r.Reasons = append(r.Reasons, fmt.Sprintf("only %d %s left", remaining, unit))
+		}
+	}
+
+	if budgetMinutes == nil {
+		return
+	}
+	if p.Content.UnitMinutes == nil {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%s length unknown, not weighed against your %d min", unitSingular, *budgetMinutes))
+		return
+	}
+
+	unitMinutes := *p.Content.UnitMinutes
+	if unitMinutes > *budgetMinutes {
+		r.Score -= NEXT_PENALTY_TOO_BIG
+		r.Reasons = append(r.Reasons, fmt.Sprintf("one %s takes %d min, more than your %d min", unitSingular, unitMinutes, *budgetMinutes))
+		return
+	}
+
+	session := *budgetMinutes / unitMinutes
+	if r.RemainingUnits != nil {
+		session = min(session, *r.RemainingUnits)
+	}
+	r.SessionUnits = &session
+
+	if r.RemainingMinutes != nil && *r.RemainingMinutes <= *budgetMinutes {
+		r.Score += NEXT_WEIGHT_FINISH
+		r.Reasons = append(r.Reasons, fmt.Sprintf("can be finished in %d min", *r.RemainingMinutes))
+		return
+	}
+	r.Reasons = append(r.Reasons, fmt.Sprintf("%d %s fit in %d min", session, unit, *budgetMinutes))
+}
+
+// SelectLastProgressTimes returns when each entry last had its progress
+// changed, from the progress history. Entries without history are left out.
+func SelectLastProgressTimes(tx *sql.Tx, reqUser *User, reqProgress []*ProgressContent) (map[uuid.UUID]time.Time, error) {
+	result := make(map[uuid.UUID]time.Time)
+
+	args := make([]uuid.UUID, 0)
+	for _, v := range reqProgress {
+		args = append(args, v.Id)
+	}
+
+	query := `
+	SELECT progress_id, MAX(created_at)
+	FROM progress_events
+	WHERE user_id = $1
+	AND progress_id = ANY($2)
+	AND action = 'progress'
+	GROUP BY progress_id
+	`
+
+	queryRows, err := tx.Query(query, reqUser.Id, args)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: SelectLastProgressTimes: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		var id uuid.UUID
+		var at time.Time
+		if err := queryRows.Scan(&id, &at); err != nil {
+			log.Printf("error: Dbs: ProgressContent: SelectLastProgressTimes: Scan: %v", err)
+			return nil, err
+		}
+		result[id] = at
+	}
+
+	return result, nil
+}
@@ -0,0 +1,413 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JustinLi007/whatdoing-server/internal/tokens"
+	"github.com/google/uuid"
+)
+
+const PERSONAL_TOKEN_NAME_MAX_LEN = 64
+
+var (
+	ErrInvalidTokenName = errors.New("invalid token name")
+	ErrInvalidScope     = errors.New("invalid scope")
+	ErrTokenNameExists  = errors.New("token name already in use")
+	ErrTokenExpired     = errors.New("token expired")
+)
+
+// PersonalToken is a long lived, named token for scripts. The plain text is
+// only ever returned by CreateToken.
+type PersonalToken struct {
+	Id         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+	UserId     uuid.UUID  `json:"-"`
+}
+
+type DbsPersonalTokens interface {
+	GetTokens(reqUser *User) ([]*PersonalToken, error)
+	CreateToken(reqUser *User, reqToken *PersonalToken, ttl *time.Duration) (*PersonalToken, error)
+	DeleteToken(reqUser *User, reqToken *PersonalToken) error
+	Authenticate(plainText string) (*User, *PersonalToken, error)
+}
+
+type PgDbsPersonalTokens struct {
+	db DbService
+}
+
+var dbsPersonalTokensInstance *PgDbsPersonalTokens
+
+func NewDbsPersonalTokens(db DbService) DbsPersonalTokens {
+	if dbsPersonalTokensInstance != nil {
+		return dbsPersonalTokensInstance
+	}
+
+	newDbsPersonalTokens := &PgDbsPersonalTokens{
+		db: db,
+	}
+	dbsPersonalTokensInstance = newDbsPersonalTokens
+
+	return dbsPersonalTokensInstance
+}
+
+func (d *PgDbsPersonalTokens) GetTokens(reqUser *User) ([]*PersonalToken, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: GetTokens: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: PersonalTokens: GetTokens: Rollback: %v", err)
+		}
+	}()
+
+	dbTokens, err := SelectPersonalTokens(tx, reqUser)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: GetTokens: SelectPersonalTokens: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: GetTokens: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbTokens, nil
+}
+
+// CreateToken stores a new token for the user, a nil ttl never expires.
+func (d *PgDbsPersonalTokens) CreateToken(reqUser *User, reqToken *PersonalToken, ttl *time.Duration) (*PersonalToken, error) {
+	name := strings.TrimSpace(reqToken.Name)
+	if name == "" || len(name) > PERSONAL_TOKEN_NAME_MAX_LEN {
+		log.Printf("error: Dbs: PersonalTokens: CreateToken: %v", ErrInvalidTokenName)
+		return nil, ErrInvalidTokenName
+	}
+	if len(reqToken.Scopes) == 0 {
+		log.Printf("error: Dbs: PersonalTokens: CreateToken: %v", ErrInvalidScope)
+		return nil, ErrInvalidScope
+	}
+	scopes := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, v := range reqToken.Scopes {
+		if !tokens.ValidScope(v) {
+			log.Printf("error: Dbs: PersonalTokens: CreateToken: %v: %v", ErrInvalidScope, v)
+			return nil, ErrInvalidScope
+		}
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		scopes = append(scopes, v)
+	}
+
+	token, err := tokens.GeneratePersonalToken(ttl)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: CreateToken: GeneratePersonalToken: %v", err)
+		return nil, err
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: CreateToken: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: PersonalTokens: CreateToken: Rollback: %v", err)
+		}
+	}()
+
+	newToken := &PersonalToken{
+		Name:   name,
+		Scopes: scopes,
+		UserId: reqUser.Id,
+	}
+	if ttl != nil {
+		newToken.ExpiresAt = &token.Expiry
+	}
+	dbToken, err := InsertPersonalToken(tx, newToken, token.Hash)
+	if err == sql.ErrNoRows {
+		return nil, ErrTokenNameExists
+	} else if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: CreateToken: InsertPersonalToken: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: CreateToken: Commit: %v", err)
+		return nil, err
+	}
+
+	dbToken.Token = token.PlainText
+
+	return dbToken, nil
+}
+
+func (d *PgDbsPersonalTokens) DeleteToken(reqUser *User, reqToken *PersonalToken) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: DeleteToken: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: PersonalTokens: DeleteToken: Rollback: %v", err)
+		}
+	}()
+
+	err = DeletePersonalToken(tx, reqUser, reqToken)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: DeleteToken: DeletePersonalToken: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: DeleteToken: Commit: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate looks up the token and its owner, unlike jwts personal tokens
+// are checked against the db on every request so revoking them is immediate.
+func (d *PgDbsPersonalTokens) Authenticate(plainText string) (*User, *PersonalToken, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: Authenticate: Conn: %v", err)
+		return nil, nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: PersonalTokens: Authenticate: Rollback: %v", err)
+		}
+	}()
+
+	dbUser, dbToken, err := SelectPersonalTokenByHash(tx, plainText)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: Authenticate: SelectPersonalTokenByHash: %v", err)
+		return nil, nil, err
+	}
+
+	if dbToken.ExpiresAt != nil && time.Now().After(*dbToken.ExpiresAt) {
+		log.Printf("error: Dbs: PersonalTokens: Authenticate: %v", ErrTokenExpired)
+		return nil, nil, ErrTokenExpired
+	}
+
+	err = UpdatePersonalTokenLastUsed(tx, dbToken)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: Authenticate: UpdatePersonalTokenLastUsed: %v", err)
+		return nil, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: Authenticate: Commit: %v", err)
+		return nil, nil, err
+	}
+
+	return dbUser, dbToken, nil
+}
+
+func SelectPersonalTokens(tx *sql.Tx, reqUser *User) ([]*PersonalToken, error) {
+	result := make([]*PersonalToken, 0)
+
+	query := `
+	SELECT id, created_at, updated_at, name, scopes, expires_at, last_used_at, user_id
+	FROM personal_tokens
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: SelectPersonalTokens: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		token := &PersonalToken{}
+		var scopes string
+		err := queryRows.Scan(
+			&token.Id,
+			&token.CreatedAt,
+			&token.UpdatedAt,
+			&token.Name,
+			&scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.UserId,
+		)
+		if err != nil {
+			log.Printf("error: Dbs: PersonalTokens: SelectPersonalTokens: Scan: %v", err)
+			return nil, err
+		}
+		token.Scopes = strings.Fields(scopes)
+		result = append(result, token)
+	}
+
+	return result, nil
+}
+
+func SelectPersonalTokenByHash(tx *sql.Tx, plainText string) (*User, *PersonalToken, error) {
+	resultUser := &User{}
+	resultToken := &PersonalToken{}
+
+	query := `
+	SELECT t.id, t.created_at, t.updated_at, t.name, t.scopes, t.expires_at, t.last_used_at, t.user_id, u.role
+	FROM personal_tokens t
+	INNER JOIN users u ON u.id = t.user_id
+	WHERE t.token_hash = $1
+	`
+
+	hash := tokens.HashFromPlainText(plainText)
+
+	var scopes string
+	err := tx.QueryRow(
+		query,
+		hash[:],
+	).Scan(
+		&resultToken.Id,
+		&resultToken.CreatedAt,
+		&resultToken.UpdatedAt,
+		&resultToken.Name,
+		&scopes,
+		&resultToken.ExpiresAt,
+		&resultToken.LastUsedAt,
+		&resultToken.UserId,
+		&resultUser.Role,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: SelectPersonalTokenByHash: Scan: %v", err)
+		return nil, nil, err
+	}
+	resultToken.Scopes = strings.Fields(scopes)
+	resultUser.Id = resultToken.UserId
+
+	return resultUser, resultToken, nil
+}
+
+func InsertPersonalToken(tx *sql.Tx, reqToken *PersonalToken, hash []byte) (*PersonalToken, error) {
+	result := &PersonalToken{}
+
+	query := `
+	INSERT INTO personal_tokens (id, name, token_hash, scopes, expires_at, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (user_id, name) DO NOTHING
+	RETURNING id, created_at, updated_at, name, scopes, expires_at, last_used_at, user_id
+	`
+
+	var scopes string
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		reqToken.Name,
+		hash,
+		strings.Join(reqToken.Scopes, " "),
+		reqToken.ExpiresAt,
+		reqToken.UserId,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+		&scopes,
+		&result.ExpiresAt,
+		&result.LastUsedAt,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: InsertPersonalToken: Scan: %v", err)
+		return nil, err
+	}
+	result.Scopes = strings.Fields(scopes)
+
+	return result, nil
+}
+
+// UpdatePersonalTokenLastUsed only writes once a minute so busy scripts do
+// not turn every request into an update.
+func UpdatePersonalTokenLastUsed(tx *sql.Tx, reqToken *PersonalToken) error {
+	query := `
+	UPDATE personal_tokens
+	SET last_used_at = $2
+	WHERE id = $1
+	AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')
+	`
+
+	_, err := tx.Exec(
+		query,
+		reqToken.Id,
+		time.Now(),
+	)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: UpdatePersonalTokenLastUsed: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func DeletePersonalToken(tx *sql.Tx, reqUser *User, reqToken *PersonalToken) error {
+	query := `
+	DELETE FROM personal_tokens
+	WHERE id = $1
+	AND user_id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqToken.Id,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: DeletePersonalToken: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: PersonalTokens: DeletePersonalToken: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: PersonalTokens: DeletePersonalToken: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,1857 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ProgressContent struct {
+	Id            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Value         int        `json:"value"`
+	Season        *int       `json:"season"`
+	Status        string     `json:"status"`
+	StatusSet     bool       `json:"status_set"`
+	StoredStatus  *string    `json:"-"`
+	Score         *int       `json:"score"`
+	ScoreScale    int        `json:"score_scale"`
+	StoredScore   *int       `json:"-"`
+	Review        *string    `json:"review"`
+	ReviewPublic  bool       `json:"review_public"`
+	Notes         *string    `json:"notes"`
+	StartedAt     *time.Time `json:"started_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	RepeatCount   int        `json:"repeat_count"`
+	Priority      int        `json:"priority"`
+	Content       *Content   `json:"content"`
+	UserLibraryId uuid.UUID  `json:"-"`
+}
+
+// statusTransitions lists the statuses a user may move an entry to from its
+// current status. Entries without a stored status transition from the status
+// derived from their progress, see fromDerivedStatus.
+var statusTransitions = map[string][]string{
+	STATUS_PLANNED:    {STATUS_WATCHING, STATUS_ON_HOLD, STATUS_DROPPED, STATUS_COMPLETED},
+	STATUS_WATCHING:   {STATUS_PLANNED, STATUS_ON_HOLD, STATUS_DROPPED, STATUS_COMPLETED},
+	STATUS_ON_HOLD:    {STATUS_PLANNED, STATUS_WATCHING, STATUS_DROPPED, STATUS_COMPLETED},
+	STATUS_DROPPED:    {STATUS_PLANNED, STATUS_WATCHING, STATUS_ON_HOLD},
+	STATUS_COMPLETED:  {STATUS_REWATCHING},
+	STATUS_REWATCHING: {STATUS_ON_HOLD, STATUS_DROPPED, STATUS_COMPLETED},
+}
+
+var fromDerivedStatus = map[string]string{
+	STATUS_NOT_STARTED: STATUS_PLANNED,
+	STATUS_STARTED:     STATUS_WATCHING,
+	STATUS_COMPLETED:   STATUS_COMPLETED,
+}
+
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+func ValidStoredStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+func ValidStatusTransition(from, to string) bool {
+	if mapped, ok := fromDerivedStatus[from]; ok {
+		from = mapped
+	}
+	if from == to {
+		return true
+	}
+	for _, v := range statusTransitions[from] {
+		if v == to {
+			return true
+		}
+	}
+	return false
+}
+
+// DerivedStatus is the status of an entry the user never set one for. For
+// shows total counts the episodes of a season, so a show is only completed on
+// the last of its seasons, and started once past the first episode of the
+// first one. A missing season counts as the first.
+func DerivedStatus(value int, season *int, total *int, seasons *int) string {
+	currentSeason := 1
+	if season != nil {
+		currentSeason = *season
+	}
+	if value == 0 && currentSeason <= 1 {
+		return STATUS_NOT_STARTED
+	}
+	if total != nil && value >= *total && (seasons == nil || currentSeason >= *seasons) {
+		return STATUS_COMPLETED
+	}
+	return STATUS_STARTED
+}
+
+// resolveStatus sets Status to the status stored by the user, falling back to
+// the status derived from the progress value when the user never set one.
+func (p *ProgressContent) resolveStatus() {
+	if p.StoredStatus != nil {
+		p.Status = *p.StoredStatus
+		p.StatusSet = true
+		return
+	}
+	p.Status = DerivedStatus(p.Value, p.Season, p.Content.Total, p.Content.Seasons)
+	p.StatusSet = false
+}
+
+const (
+	SCORE_SCALE_10  = 10
+	SCORE_SCALE_100 = 100
+
+	// SCORE_MAX is the scale scores are stored in, whatever scale the user
+	// rates in.
+	SCORE_MAX = SCORE_SCALE_100
+)
+
+var ErrInvalidScore = errors.New("invalid score")
+var ErrInvalidDates = errors.New("invalid dates")
+var ErrInvalidPriority = errors.New("invalid priority")
+
+const (
+	PRIORITY_NONE = 0
+	PRIORITY_HIGH = 3
+)
+
+var ErrInvalidScoreScale = errors.New("invalid score scale")
+
+func ValidScoreScale(scale int) bool {
+	return scale == SCORE_SCALE_10 || scale == SCORE_SCALE_100
+}
+
+// ScoreToStored converts a score given in scale to the stored 100 point scale.
+func ScoreToStored(score, scale int) (int, error) {
+	if !ValidScoreScale(scale) {
+		return 0, ErrInvalidScoreScale
+	}
+	if score < 1 || score > scale {
+		return 0, ErrInvalidScore
+	}
+	return score * (SCORE_MAX / scale), nil
+}
+
+// ScoreFromStored converts a stored score to scale, rounding to the nearest
+// point but never below 1.
+func ScoreFromStored(stored, scale int) int {
+	if !ValidScoreScale(scale) {
+		scale = SCORE_SCALE_10
+	}
+	score := (stored*scale + SCORE_MAX/2) / SCORE_MAX
+	return max(score, 1)
+}
+
+func (p *ProgressContent) resolveScore(scale int) {
+	if !ValidScoreScale(scale) {
+		scale = SCORE_SCALE_10
+	}
+	p.ScoreScale = scale
+	p.Score = nil
+	if p.StoredScore != nil {
+		score := ScoreFromStored(*p.StoredScore, scale)
+		p.Score = &score
+	}
+}
+
+type DbsProgressContent interface {
+	AddToLibrary(reqUser *User, reqContent *Content) (*ProgressContent, error)
+	UpdateProgress(reqUser *User, reqRelContentUserLibrary *ProgressContent) (*ProgressContent, error)
+	UpdateStatus(reqUser *User, reqProgress *ProgressContent) (*ProgressContent, error)
+	UpdateReview(reqUser *User, reqProgress *ProgressContent) (*ProgressContent, error)
+	UpdateDates(reqUser *User, reqProgress *ProgressContent, repeatCount *int) (*ProgressContent, error)
+	UpdatePriority(reqUser *User, reqProgress *ProgressContent) (*ProgressContent, error)
+	GetProgress(reqUser *User, opts ...OptionsFunc) ([]*ProgressContent, error)
+	RemoveProgress(reqUser *User, reqRelContentUserLibrary *ProgressContent) error
+	GetHistory(reqUser *User, reqProgress *ProgressContent) ([]*ProgressEvent, error)
+	GetNext(reqUser *User, budgetMinutes *int, limit int) ([]*Recommendation, error)
+	Undo(reqUser *User) (*ProgressEvent, error)
+}
+
+type PgDbsProgressContent struct {
+	db DbService
+}
+
+var dbsProgressContentInstance *PgDbsProgressContent
+
+func NewDbsProgressContent(db DbService) DbsProgressContent {
+	if dbsProgressContentInstance != nil {
+		return dbsProgressContentInstance
+	}
+	newDbsProgressContent := &PgDbsProgressContent{
+		db: db,
+	}
+	dbsProgressContentInstance = newDbsProgressContent
+
+	return dbsProgressContentInstance
+}
+
+func (d *PgDbsProgressContent) AddToLibrary(reqUser *User, reqContent *Content) (*ProgressContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsRelContentUserLibrary AddToLibrary: Rollback: %v", err)
+		}
+	}()
+
+	dbRelContentUserLibrary, err := InsertProgress(tx, reqUser, reqContent)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: InsertProgress: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, dbRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+	if err := InsertProgressEvent(tx, reqUser, EVENT_ADDED, nil, after); err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: InsertProgressEvent: %v", err)
+		return nil, err
+	}
+
+	temp := []*Content{dbRelContentUserLibrary.Content}
+	allNames, err := SelectContentAltNames(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary AddToLibrary: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	if altNames, ok := namesMap[dbRelContentUserLibrary.Content.Id]; ok {
+		dbRelContentUserLibrary.Content.AlternativeNames = altNames
+	} else {
+		dbRelContentUserLibrary.Content.AlternativeNames = make([]*ContentName, 0)
+	}
+	dbRelContentUserLibrary.resolveStatus()
+	dbRelContentUserLibrary.resolveScore(scale)
+
+	return dbRelContentUserLibrary, nil
+}
+
+func (d *PgDbsProgressContent) UpdateProgress(reqUser *User, reqRelContentUserLibrary *ProgressContent) (*ProgressContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsRelContentUserLibrary UpdateProgress: Rollback: %v", err)
+		}
+	}()
+
+	before, err := SelectProgressSnapshot(tx, reqUser, reqRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	dbRelContentUserLibrary, err := UpdateProgress(tx, reqUser, reqRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: UpdateProgress: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, dbRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+	if err := InsertProgressEvent(tx, reqUser, EVENT_PROGRESS, before, after); err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: InsertProgressEvent: %v", err)
+		return nil, err
+	}
+
+	temp := []*Content{dbRelContentUserLibrary.Content}
+	allNames, err := SelectContentAltNames(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateProgress: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	if altNames, ok := namesMap[dbRelContentUserLibrary.Content.Id]; ok {
+		dbRelContentUserLibrary.Content.AlternativeNames = altNames
+	}
+	dbRelContentUserLibrary.resolveStatus()
+	dbRelContentUserLibrary.resolveScore(scale)
+
+	return dbRelContentUserLibrary, nil
+}
+
+func (d *PgDbsProgressContent) UpdateStatus(reqUser *User, reqProgress *ProgressContent) (*ProgressContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent UpdateStatus: Rollback: %v", err)
+		}
+	}()
+
+	if reqProgress.StoredStatus == nil || !ValidStoredStatus(*reqProgress.StoredStatus) {
+		log.Printf("error: DbsProgressContent UpdateStatus: invalid status")
+		return nil, ErrInvalidStatusTransition
+	}
+
+	dbProgress, err := SelectProgressById(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: SelectProgressById: %v", err)
+		return nil, err
+	}
+	dbProgress.resolveStatus()
+
+	if !ValidStatusTransition(dbProgress.Status, *reqProgress.StoredStatus) {
+		log.Printf("error: DbsProgressContent UpdateStatus: %v -> %v: %v", dbProgress.Status, *reqProgress.StoredStatus, ErrInvalidStatusTransition)
+		return nil, ErrInvalidStatusTransition
+	}
+
+	before, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	if err := UpdateProgressStatus(tx, reqUser, reqProgress); err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: UpdateProgressStatus: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+	if err := InsertProgressEvent(tx, reqUser, EVENT_STATUS, before, after); err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: InsertProgressEvent: %v", err)
+		return nil, err
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	dbProgress, err = SelectProgressById(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: SelectProgressById: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent UpdateStatus: Commit: %v", err)
+		return nil, err
+	}
+
+	dbProgress.resolveStatus()
+	dbProgress.resolveScore(scale)
+
+	return dbProgress, nil
+}
+
+func (d *PgDbsProgressContent) UpdateReview(reqUser *User, reqProgress *ProgressContent) (*ProgressContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent UpdateReview: Rollback: %v", err)
+		}
+	}()
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	reqProgress.StoredScore = nil
+	if reqProgress.Score != nil {
+		stored, err := ScoreToStored(*reqProgress.Score, scale)
+		if err != nil {
+			log.Printf("error: DbsProgressContent UpdateReview: ScoreToStored: %v", err)
+			return nil, err
+		}
+		reqProgress.StoredScore = &stored
+	}
+
+	before, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	if err := UpdateProgressReview(tx, reqUser, reqProgress); err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: UpdateProgressReview: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+	if err := InsertProgressEvent(tx, reqUser, EVENT_REVIEW, before, after); err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: InsertProgressEvent: %v", err)
+		return nil, err
+	}
+
+	dbProgress, err := SelectProgressById(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: SelectProgressById: %v", err)
+		return nil, err
+	}
+
+	temp := []*Content{dbProgress.Content}
+	allNames, err := SelectContentAltNames(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent UpdateReview: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	if altNames, ok := namesMap[dbProgress.Content.Id]; ok {
+		dbProgress.Content.AlternativeNames = altNames
+	}
+	dbProgress.resolveStatus()
+	dbProgress.resolveScore(scale)
+
+	return dbProgress, nil
+}
+
+// UpdateDates overrides the dates and repeat count filled in by progress and
+// status updates. A nil repeatCount keeps the stored count.
+func (d *PgDbsProgressContent) UpdateDates(reqUser *User, reqProgress *ProgressContent, repeatCount *int) (*ProgressContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent UpdateDates: Rollback: %v", err)
+		}
+	}()
+
+	if reqProgress.StartedAt != nil && reqProgress.CompletedAt != nil && reqProgress.CompletedAt.Before(*reqProgress.StartedAt) {
+		log.Printf("error: DbsProgressContent UpdateDates: completed before started: %v", ErrInvalidDates)
+		return nil, ErrInvalidDates
+	}
+	if repeatCount != nil && *repeatCount < 0 {
+		log.Printf("error: DbsProgressContent UpdateDates: negative repeat count: %v", ErrInvalidDates)
+		return nil, ErrInvalidDates
+	}
+
+	before, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	if err := UpdateProgressDates(tx, reqUser, reqProgress, repeatCount); err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: UpdateProgressDates: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+	if err := InsertProgressEvent(tx, reqUser, EVENT_DATES, before, after); err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: InsertProgressEvent: %v", err)
+		return nil, err
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	dbProgress, err := SelectProgressById(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: SelectProgressById: %v", err)
+		return nil, err
+	}
+
+	temp := []*Content{dbProgress.Content}
+	allNames, err := SelectContentAltNames(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent UpdateDates: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	if altNames, ok := namesMap[dbProgress.Content.Id]; ok {
+		dbProgress.Content.AlternativeNames = altNames
+	}
+	dbProgress.resolveStatus()
+	dbProgress.resolveScore(scale)
+
+	return dbProgress, nil
+}
+
+func (d *PgDbsProgressContent) UpdatePriority(reqUser *User, reqProgress *ProgressContent) (*ProgressContent, error) {
+	if reqProgress.Priority < PRIORITY_NONE || reqProgress.Priority > PRIORITY_HIGH {
+		log.Printf("error: DbsProgressContent UpdatePriority: %v", ErrInvalidPriority)
+		return nil, ErrInvalidPriority
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent UpdatePriority: Rollback: %v", err)
+		}
+	}()
+
+	before, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	if err := UpdateProgressPriority(tx, reqUser, reqProgress); err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: UpdateProgressPriority: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+	if err := InsertProgressEvent(tx, reqUser, EVENT_PRIORITY, before, after); err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: InsertProgressEvent: %v", err)
+		return nil, err
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	dbProgress, err := SelectProgressById(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: SelectProgressById: %v", err)
+		return nil, err
+	}
+
+	temp := []*Content{dbProgress.Content}
+	allNames, err := SelectContentAltNames(tx, temp)
+	if err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: SelectContentNames: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent UpdatePriority: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	if altNames, ok := namesMap[dbProgress.Content.Id]; ok {
+		dbProgress.Content.AlternativeNames = altNames
+	}
+	dbProgress.resolveStatus()
+	dbProgress.resolveScore(scale)
+
+	return dbProgress, nil
+}
+
+func (d *PgDbsProgressContent) GetProgress(reqUser *User, opts ...OptionsFunc) ([]*ProgressContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary GetProgress: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsRelContentUserLibrary GetProgress: Rollback: %v", err)
+		}
+	}()
+
+	options := NewOptions()
+	for _, v := range opts {
+		v(options)
+	}
+
+	orderBy := SORT_ASC
+	if options.Sort != nil {
+		orderBy = options.Sort.SortValue
+	}
+
+	var kind *string
+	if options.Kind != nil {
+		kind = &options.Kind.KindValue
+	}
+
+	result := make([]*ProgressContent, 0)
+
+	if options.ProgressId != nil || options.ContentId != nil {
+		var msg string
+		var err error
+		var dbProgress *ProgressContent
+		if options.ProgressId != nil {
+			reqRelContentUserLibrary := &ProgressContent{
+				Id: options.ProgressId.Id,
+			}
+			dbProgress, err = SelectProgressById(tx, reqUser, reqRelContentUserLibrary)
+			msg = fmt.Sprintf("error: DbsRelContentUserLibrary GetProgress: SelectRelContentUserLibraryById: %v", err)
+		}
+		if dbProgress == nil && options.ContentId != nil {
+			reqContent := &Content{
+				Id: options.ContentId.Id,
+			}
+			dbProgress, err = SelectProgressByContentId(tx, reqUser, reqContent)
+			msg = fmt.Sprintf("error: DbsRelContentUserLibrary GetProgress: SelectRelContentUserLibraryByContentId: %v", err)
+		}
+		if err != nil {
+			log.Println(msg)
+			return nil, err
+		}
+		result = append(result, dbProgress)
+	} else if options.Status != nil {
+		var msg string
+		var err error
+		var dbProgress []*ProgressContent
+		switch options.Status.StatusValue {
+		case STATUS_NOT_STARTED:
+			dbProgress, err = SelectProgressNotStarted(tx, reqUser, kind, orderBy)
+			msg = fmt.Sprintf("error: DbsRelContentUserLibrary GetProgress: SelectProgressNotStarted: %v", err)
+		case STATUS_STARTED:
+			dbProgress, err = SelectProgressStarted(tx, reqUser, kind, orderBy)
+			msg = fmt.Sprintf("error: DbsRelContentUserLibrary GetProgress: SelectProgressStarted: %v", err)
+		case STATUS_COMPLETED:
+			dbProgress, err = SelectProgressCompleted(tx, reqUser, kind, orderBy)
+			msg = fmt.Sprintf("error: DbsRelContentUserLibrary GetProgress: SelectProgressCompleted: %v", err)
+		default:
+			dbProgress, err = SelectProgressByStatus(tx, reqUser, options.Status.StatusValue, kind, orderBy)
+			msg = fmt.Sprintf("error: DbsRelContentUserLibrary GetProgress: SelectProgressByStatus: %v", err)
+		}
+		if err != nil {
+			log.Println(msg)
+			return nil, err
+		}
+		result = dbProgress
+	} else {
+		msg := "error: DbsRelContentUserLibrary GetProgress: invalid options"
+		log.Printf(msg)
+		return nil, errors.New(msg)
+	}
+
+	allNames := make([]*ContentAltName, 0)
+	allGenres := make([]*ContentGenre, 0)
+	allTags := make([]*ContentTag, 0)
+	if len(result) > 0 {
+		tempContent := make([]*Content, 0)
+		for _, v := range result {
+			tempContent = append(tempContent, v.Content)
+		}
+
+		allNames, err = SelectContentAltNames(tx, tempContent)
+		if err != nil {
+			log.Printf("error: DbsRelContentUserLibrary GetProgress: SelectContentNames: %v", err)
+			return nil, err
+		}
+
+		allGenres, err = SelectContentGenres(tx, tempContent)
+		if err != nil {
+			log.Printf("error: DbsRelContentUserLibrary GetProgress: SelectContentGenres: %v", err)
+			return nil, err
+		}
+
+		allTags, err = SelectContentTags(tx, reqUser, tempContent)
+		if err != nil {
+			log.Printf("error: DbsRelContentUserLibrary GetProgress: SelectContentTags: %v", err)
+			return nil, err
+		}
+	}
+
+	scale, err := SelectScoreScale(tx, reqUser)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary GetProgress: SelectScoreScale: %v", err)
+		return nil, err
+	}
+
+	var scores map[uuid.UUID]float64
+	if options.Search != nil {
+		scores, err = SelectLibrarySearchScores(tx, reqUser, options.Search)
+		if err != nil {
+			log.Printf("error: DbsRelContentUserLibrary GetProgress: SelectLibrarySearchScores: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary GetProgress: Commit: %v", err)
+		return nil, err
+	}
+
+	namesMap := buildNamesMap(allNames)
+	genresMap := buildGenresMap(allGenres)
+	tagsMap := buildTagsMap(allTags)
+	for k, v := range result {
+		curId := v.Content.Id
+		if altNames, ok := namesMap[curId]; ok {
+			result[k].Content.AlternativeNames = altNames
+		}
+		result[k].Content.Genres = genresMap[curId]
+		result[k].Content.Tags = tagsMap[curId]
+		result[k].resolveStatus()
+		result[k].resolveScore(scale)
+	}
+
+	if options.Search != nil {
+		filteredProgressList := make([]*ProgressContent, 0)
+		for _, v := range result {
+			if score, ok := scores[v.Content.Id]; ok {
+				v.Content.Score = &score
+				filteredProgressList = append(filteredProgressList, v)
+			}
+		}
+		result = filteredProgressList
+
+		contentList := make([]*Content, len(result))
+		for k, v := range result {
+			contentList[k] = v.Content
+		}
+		ranks := rankContent(contentList, options.Search.SearchValue)
+		sort.SliceStable(result, func(i, j int) bool {
+			return ranks[result[i].Content.Id] < ranks[result[j].Content.Id]
+		})
+	}
+
+	if options.Genres != nil || options.Tags != nil {
+		filteredProgressList := make([]*ProgressContent, 0)
+		for _, v := range result {
+			if filterContentByFacets(v.Content, options) {
+				filteredProgressList = append(filteredProgressList, v)
+			}
+		}
+		result = filteredProgressList
+	}
+
+	if options.MinScore != nil || options.MaxScore != nil {
+		filteredProgressList := make([]*ProgressContent, 0)
+		for _, v := range result {
+			if v.Score == nil {
+				continue
+			}
+			if options.MinScore != nil && *v.Score < options.MinScore.ScoreValue {
+				continue
+			}
+			if options.MaxScore != nil && *v.Score > options.MaxScore.ScoreValue {
+				continue
+			}
+			filteredProgressList = append(filteredProgressList, v)
+		}
+		result = filteredProgressList
+	}
+
+	if options.SortBy != nil && options.SortBy.SortByValue == SORT_BY_SCORE {
+		// unscored entries stay last in either direction
+		sort.SliceStable(result, func(i, j int) bool {
+			a, b := result[i].StoredScore, result[j].StoredScore
+			if a == nil || b == nil {
+				return a != nil && b == nil
+			}
+			if orderBy == SORT_DESC {
+				return *a > *b
+			}
+			return *a < *b
+		})
+	}
+
+	return result, nil
+}
+
+func (d *PgDbsProgressContent) RemoveProgress(reqUser *User, reqRelContentUserLibrary *ProgressContent) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary RemoveProgress: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsRelContentUserLibrary RemoveProgress: Rollback: %v", err)
+		}
+	}()
+
+	before, err := SelectProgressSnapshot(tx, reqUser, reqRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary RemoveProgress: SelectProgressSnapshot: %v", err)
+		return err
+	}
+
+	err = DeleteProgress(tx, reqUser, reqRelContentUserLibrary)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary RemoveProgress: DeleteRelContentUserLibrary: %v", err)
+		return err
+	}
+
+	if err := InsertProgressEvent(tx, reqUser, EVENT_REMOVED, before, nil); err != nil {
+		log.Printf("error: DbsRelContentUserLibrary RemoveProgress: InsertProgressEvent: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary RemoveProgress: Commit: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *PgDbsProgressContent) GetHistory(reqUser *User, reqProgress *ProgressContent) ([]*ProgressEvent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetHistory: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent GetHistory: Rollback: %v", err)
+		}
+	}()
+
+	dbEvents, err := SelectProgressEvents(tx, reqUser, reqProgress)
+	if err != nil {
+		log.Printf("error: DbsProgressContent GetHistory: SelectProgressEvents: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent GetHistory: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbEvents, nil
+}
+
+func (d *PgDbsProgressContent) Undo(reqUser *User) (*ProgressEvent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsProgressContent Undo: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsProgressContent Undo: Rollback: %v", err)
+		}
+	}()
+
+	target, err := SelectUndoableEvent(tx, reqUser)
+	if err == sql.ErrNoRows {
+		return nil, ErrNothingToUndo
+	} else if err != nil {
+		log.Printf("error: DbsProgressContent Undo: SelectUndoableEvent: %v", err)
+		return nil, err
+	}
+
+	match, err := ProgressMatchesSnapshot(tx, reqUser, target.ProgressId, target.Before, target.After)
+	if err != nil {
+		log.Printf("error: DbsProgressContent Undo: ProgressMatchesSnapshot: %v", err)
+		return nil, err
+	}
+	if !match {
+		log.Printf("error: DbsProgressContent Undo: %v", ErrUndoConflict)
+		return nil, ErrUndoConflict
+	}
+
+	// the content may have been added back as a new entry since it was removed
+	if target.Before != nil {
+		exists, err := ProgressContentInLibrary(tx, reqUser, target.ContentId, target.ProgressId)
+		if err != nil {
+			log.Printf("error: DbsProgressContent Undo: ProgressContentInLibrary: %v", err)
+			return nil, err
+		}
+		if exists {
+			log.Printf("error: DbsProgressContent Undo: %v", ErrUndoConflict)
+			return nil, ErrUndoConflict
+		}
+	}
+
+	reqProgress := &ProgressContent{
+		Id: target.ProgressId,
+	}
+	before, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("error: DbsProgressContent Undo: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	if err := RestoreProgressSnapshot(tx, reqUser, target.ProgressId, target.Before); err != nil {
+		log.Printf("error: DbsProgressContent Undo: RestoreProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	after, err := SelectProgressSnapshot(tx, reqUser, reqProgress)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("error: DbsProgressContent Undo: SelectProgressSnapshot: %v", err)
+		return nil, err
+	}
+
+	dbEvent, err := InsertProgressUndoEvent(tx, reqUser, target, before, after)
+	if err != nil {
+		log.Printf("error: DbsProgressContent Undo: InsertProgressUndoEvent: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: DbsProgressContent Undo: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbEvent, nil
+}
+
+func InsertProgress(tx *sql.Tx, reqUser *User, reqContent *Content) (*ProgressContent, error) {
+	result := &ProgressContent{
+		Content: &Content{},
+	}
+
+	query := `WITH user_lib AS (
+		SELECT * FROM user_library WHERE user_id = $1
+	), insert_progress AS (
+		INSERT INTO progress_content (id, content_id, user_library_id)
+		SELECT $2, content.id, user_lib.id
+		FROM user_lib
+		JOIN content ON content.id = $3
+		WHERE content.status = 'approved'
+		OR EXISTS (
+			SELECT 1 FROM rel_users_content owner
+			WHERE owner.content_id = content.id
+			AND owner.user_id = $1
+		)
+		RETURNING id, created_at, updated_at, value, season, status, score, review, review_public, notes, started_at, completed_at, repeat_count, priority, content_id
+	)
+	SELECT insert_progress.id, insert_progress.created_at, insert_progress.updated_at, insert_progress.value, insert_progress.season, insert_progress.status, insert_progress.score, insert_progress.review, insert_progress.review_public, insert_progress.notes, insert_progress.started_at, insert_progress.completed_at, insert_progress.repeat_count, insert_progress.priority,
+	content.id, content.created_at, content.updated_at, content.kind, content.unit, content.total, content.seasons, content.description, content.image_url, content.unit_minutes,
+	content_names.id, content_names.created_at, content_names.updated_at, content_names.name
+	FROM insert_progress
+	JOIN content ON content.id = insert_progress.content_id
+	JOIN content_names ON content.content_names_id = content_names.id`
+
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		uuid.New(),
+		reqContent.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Value,
+		&result.Season,
+		&result.StoredStatus,
+		&result.StoredScore,
+		&result.Review,
+		&result.ReviewPublic,
+		&result.Notes,
+		&result.StartedAt,
+		&result.CompletedAt,
+		&result.RepeatCount,
+		&result.Priority,
+		&result.Content.Id,
+		&result.Content.CreatedAt,
+		&result.Content.UpdatedAt,
+		&result.Content.Kind,
+		&result.Content.Unit,
+		&result.Content.Total,
+		&result.Content.Seasons,
+		&result.Content.Description,
+		&result.Content.ImageUrl,
+		&result.Content.UnitMinutes,
+		&result.Content.ContentName.Id,
+		&result.Content.ContentName.CreatedAt,
+		&result.Content.ContentName.UpdatedAt,
+		&result.Content.ContentName.Name,
+	)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary InsertRelContentUserLibrary: Query: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func UpdateProgress(tx *sql.Tx, reqUser *User, reqRelContentUserLibrary *ProgressContent) (*ProgressContent, error) {
+	result := &ProgressContent{
+		Content: &Content{
+			AlternativeNames: make([]*ContentName, 0),
+		},
+	}
+
+	query := `
+	WITH user_lib AS (
+		SELECT user_library.id FROM user_library WHERE user_id = $1
+	), select_content AS (
+		SELECT content.* FROM content
+		JOIN progress_content ON content.id = progress_content.content_id
+		WHERE progress_content.id = $2
+	), update_progress AS (
+		UPDATE progress_content progress
+		SET
+			updated_at = $3,
+			value = $4,
+			season = CASE WHEN select_content.kind = 'show' THEN $5::int ELSE NULL END,
+			started_at = CASE WHEN $4 > 0 THEN COALESCE(progress.started_at, $3) ELSE progress.started_at END,
+			completed_at = CASE
+				WHEN select_content.total IS NOT NULL
+				AND $4 >= select_content.total
+				AND (select_content.seasons IS NULL OR COALESCE($5::int, 1) >= select_content.seasons)
+				AND NOT (
+					progress.value >= select_content.total
+					AND (select_content.seasons IS NULL OR COALESCE(progress.season, 1) >= select_content.seasons)
+				) THEN $3
+				ELSE progress.completed_at
+			END,
+			status = CASE
+				WHEN progress.status = 'rewatching'
+				AND select_content.total IS NOT NULL
+				AND $4 >= select_content.total
+				AND (select_content.seasons IS NULL OR COALESCE($5::int, 1) >= select_content.seasons) THEN 'completed'
+				ELSE progress.status
+			END
+		FROM user_lib, select_content
+		WHERE progress.id = $2
+		AND progress.user_library_id = user_lib.id
+		AND (select_content.total IS NULL OR $4 <= select_content.total)
+		AND ($5::int IS NULL OR select_content.seasons IS NULL OR $5 <= select_content.seasons)
+		RETURNING progress.id, progress.created_at, progress.updated_at, progress.value, progress.season, progress.status, progress.score, progress.review, progress.review_public, progress.notes, progress.started_at, progress.completed_at, progress.repeat_count, progress.priority, progress.content_id
+	)
+	SELECT
+	update_progress.id, update_progress.created_at, update_progress.updated_at, update_progress.value, update_progress.season, update_progress.status, update_progress.score, update_progress.review, update_progress.review_public, update_progress.notes, update_progress.started_at, update_progress.completed_at, update_progress.repeat_count, update_progress.priority,
+	content.id, content.created_at, content.updated_at, content.kind, content.unit, content.total, content.seasons, content.description, content.image_url, content.unit_minutes,
+	content_names.id, content_names.created_at, content_names.updated_at, content_names.name
+	FROM update_progress
+	JOIN select_content content ON update_progress.content_id = content.id
+	JOIN content_names ON content.content_names_id = content_names.id
+	`
+
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		reqRelContentUserLibrary.Id,
+		time.Now(),
+		reqRelContentUserLibrary.Value,
+		reqRelContentUserLibrary.Season,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Value,
+		&result.Season,
+		&result.StoredStatus,
+		&result.StoredScore,
+		&result.Review,
+		&result.ReviewPublic,
+		&result.Notes,
+		&result.StartedAt,
+		&result.CompletedAt,
+		&result.RepeatCount,
+		&result.Priority,
+		&result.Content.Id,
+		&result.Content.CreatedAt,
+		&result.Content.UpdatedAt,
+		&result.Content.Kind,
+		&result.Content.Unit,
+		&result.Content.Total,
+		&result.Content.Seasons,
+		&result.Content.Description,
+		&result.Content.ImageUrl,
+		&result.Content.UnitMinutes,
+		&result.Content.ContentName.Id,
+		&result.Content.ContentName.CreatedAt,
+		&result.Content.ContentName.UpdatedAt,
+		&result.Content.ContentName.Name,
+	)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary UpdateRelContentUserLibraryProgress: Query: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectProgressByContentId(tx *sql.Tx, reqUser *User, reqContent *Content) (*ProgressContent, error) {
+	result := &ProgressContent{
+		Content: &Content{
+			AlternativeNames: make([]*ContentName, 0),
+		},
+	}
+
+	query := `
+	WITH user_lib AS (
+		SELECT * FROM user_library WHERE user_id = $1
+	)
+	SELECT
+	ul.id, ul.created_at, ul.updated_at, ul.value, ul.season, ul.status, ul.score, ul.review, ul.review_public, ul.notes, ul.started_at, ul.completed_at, ul.repeat_count, ul.priority,
+	a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM progress_content ul
+	JOIN content a ON ul.content_id = a.id
+	JOIN content_names an ON a.content_names_id = an.id
+	JOIN user_lib ON ul.user_library_id = user_lib.id
+	WHERE a.id = $2
+	`
+
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		reqContent.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Value,
+		&result.Season,
+		&result.StoredStatus,
+		&result.StoredScore,
+		&result.Review,
+		&result.ReviewPublic,
+		&result.Notes,
+		&result.StartedAt,
+		&result.CompletedAt,
+		&result.RepeatCount,
+		&result.Priority,
+		&result.Content.Id,
+		&result.Content.CreatedAt,
+		&result.Content.UpdatedAt,
+		&result.Content.Kind,
+		&result.Content.Unit,
+		&result.Content.Total,
+		&result.Content.Seasons,
+		&result.Content.Description,
+		&result.Content.ImageUrl,
+		&result.Content.UnitMinutes,
+		&result.Content.ContentName.Id,
+		&result.Content.ContentName.CreatedAt,
+		&result.Content.ContentName.UpdatedAt,
+		&result.Content.ContentName.Name,
+	)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary SelectRelContentUserLibraryByContentId: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectProgressById(tx *sql.Tx, reqUser *User, reqRelContentUserLibrary *ProgressContent) (*ProgressContent, error) {
+	result := &ProgressContent{
+		Content: &Content{
+			AlternativeNames: make([]*ContentName, 0),
+		},
+	}
+
+	query := `
+	SELECT
+	p.id, p.created_at, p.updated_at, p.value, p.season, p.status, p.score, p.review, p.review_public, p.notes, p.started_at, p.completed_at, p.repeat_count, p.priority,
+	a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+	an.id, an.created_at, an.updated_at, an.name
+	FROM progress_content p
+	JOIN content a ON p.content_id = a.id
+	JOIN content_names an ON a.content_names_id = an.id
+	JOIN user_library ON p.user_library_id = user_library.id
+	WHERE user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		reqRelContentUserLibrary.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Value,
+		&result.Season,
+		&result.StoredStatus,
+		&result.StoredScore,
+		&result.Review,
+		&result.ReviewPublic,
+		&result.Notes,
+		&result.StartedAt,
+		&result.CompletedAt,
+		&result.RepeatCount,
+		&result.Priority,
+		&result.Content.Id,
+		&result.Content.CreatedAt,
+		&result.Content.UpdatedAt,
+		&result.Content.Kind,
+		&result.Content.Unit,
+		&result.Content.Total,
+		&result.Content.Seasons,
+		&result.Content.Description,
+		&result.Content.ImageUrl,
+		&result.Content.UnitMinutes,
+		&result.Content.ContentName.Id,
+		&result.Content.ContentName.CreatedAt,
+		&result.Content.ContentName.UpdatedAt,
+		&result.Content.ContentName.Name,
+	)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary SelectRelContentUserLibraryById: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectProgressStarted(tx *sql.Tx, reqUser *User, kind *string, orderBy string) ([]*ProgressContent, error) {
+	result := make([]*ProgressContent, 0)
+
+	query := fmt.Sprintf(`
+		SELECT
+		p.id, p.created_at, p.updated_at, p.value, p.season, p.status, p.score, p.review, p.review_public, p.notes, p.started_at, p.completed_at, p.repeat_count, p.priority,
+		a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+		an.id, an.created_at, an.updated_at, an.name
+		FROM progress_content p
+		JOIN content a ON p.content_id = a.id
+		JOIN content_names an ON a.content_names_id = an.id
+		JOIN user_library ON p.user_library_id = user_library.id
+		WHERE user_library.user_id = $1
+		AND ($2::text IS NULL OR a.kind = $2)
+		AND (
+			(
+				p.status IS NULL
+				AND (p.value > 0 OR COALESCE(p.season, 1) > 1)
+				AND NOT (
+					a.total IS NOT NULL
+					AND p.value >= a.total
+					AND (a.seasons IS NULL OR COALESCE(p.season, 1) >= a.seasons)
+				)
+			)
+			OR p.status IN ('watching', 'rewatching')
+		)
+		ORDER BY an.name %s
+	`,
+		orderBy,
+	)
+
+	queryRows, err := tx.Query(query, reqUser.Id, kind)
+	if err != nil {
+		log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressStarted: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ProgressContent{
+			Content: &Content{
+				ContentName:      ContentName{},
+				AlternativeNames: make([]*ContentName, 0),
+			},
+		}
+
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Value,
+			&temp.Season,
+			&temp.StoredStatus,
+			&temp.StoredScore,
+			&temp.Review,
+			&temp.ReviewPublic,
+			&temp.Notes,
+			&temp.StartedAt,
+			&temp.CompletedAt,
+			&temp.RepeatCount,
+			&temp.Priority,
+			&temp.Content.Id,
+			&temp.Content.CreatedAt,
+			&temp.Content.UpdatedAt,
+			&temp.Content.Kind,
+			&temp.Content.Unit,
+			&temp.Content.Total,
+			&temp.Content.Seasons,
+			&temp.Content.Description,
+			&temp.Content.ImageUrl,
+			&temp.Content.UnitMinutes,
+			&temp.Content.ContentName.Id,
+			&temp.Content.ContentName.CreatedAt,
+			&temp.Content.ContentName.UpdatedAt,
+			&temp.Content.ContentName.Name,
+		); err != nil {
+			log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressStarted: Scan: %v", err)
+			return nil, err
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectProgressNotStarted(tx *sql.Tx, reqUser *User, kind *string, orderBy string) ([]*ProgressContent, error) {
+	result := make([]*ProgressContent, 0)
+
+	query := fmt.Sprintf(`
+		SELECT
+		p.id, p.created_at, p.updated_at, p.value, p.season, p.status, p.score, p.review, p.review_public, p.notes, p.started_at, p.completed_at, p.repeat_count, p.priority,
+		a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+		an.id, an.created_at, an.updated_at, an.name
+		FROM progress_content p
+		JOIN content a ON p.content_id = a.id
+		JOIN content_names an ON a.content_names_id = an.id
+		JOIN user_library ON p.user_library_id = user_library.id
+		WHERE user_library.user_id = $1
+		AND ($2::text IS NULL OR a.kind = $2)
+		AND (
+			(p.status IS NULL AND p.value = 0 AND COALESCE(p.season, 1) <= 1)
+			OR p.status = 'planned'
+		)
+		ORDER BY an.name %s
+	`,
+		orderBy,
+	)
+
+	queryRows, err := tx.Query(query, reqUser.Id, kind)
+	if err != nil {
+		log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressStarted: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ProgressContent{
+			Content: &Content{
+				ContentName:      ContentName{},
+				AlternativeNames: make([]*ContentName, 0),
+			},
+		}
+
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Value,
+			&temp.Season,
+			&temp.StoredStatus,
+			&temp.StoredScore,
+			&temp.Review,
+			&temp.ReviewPublic,
+			&temp.Notes,
+			&temp.StartedAt,
+			&temp.CompletedAt,
+			&temp.RepeatCount,
+			&temp.Priority,
+			&temp.Content.Id,
+			&temp.Content.CreatedAt,
+			&temp.Content.UpdatedAt,
+			&temp.Content.Kind,
+			&temp.Content.Unit,
+			&temp.Content.Total,
+			&temp.Content.Seasons,
+			&temp.Content.Description,
+			&temp.Content.ImageUrl,
+			&temp.Content.UnitMinutes,
+			&temp.Content.ContentName.Id,
+			&temp.Content.ContentName.CreatedAt,
+			&temp.Content.ContentName.UpdatedAt,
+			&temp.Content.ContentName.Name,
+		); err != nil {
+			log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressStarted: Scan: %v", err)
+			return nil, err
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectProgressCompleted(tx *sql.Tx, reqUser *User, kind *string, orderBy string) ([]*ProgressContent, error) {
+	result := make([]*ProgressContent, 0)
+
+	query := fmt.Sprintf(`
+		SELECT
+		p.id, p.created_at, p.updated_at, p.value, p.season, p.status, p.score, p.review, p.review_public, p.notes, p.started_at, p.completed_at, p.repeat_count, p.priority,
+		a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+		an.id, an.created_at, an.updated_at, an.name
+		FROM progress_content p
+		JOIN content a ON p.content_id = a.id
+		JOIN content_names an ON a.content_names_id = an.id
+		JOIN user_library ON p.user_library_id = user_library.id
+		WHERE user_library.user_id = $1
+		AND ($2::text IS NULL OR a.kind = $2)
+		AND (
+			(
+				p.status IS NULL
+				AND a.total IS NOT NULL
+				AND p.value >= a.total
+				AND (a.seasons IS NULL OR COALESCE(p.season, 1) >= a.seasons)
+			)
+			OR p.status = 'completed'
+		)
+		ORDER BY an.name %s
+	`,
+		orderBy,
+	)
+
+	queryRows, err := tx.Query(query, reqUser.Id, kind)
+	if err != nil {
+		log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressStarted: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ProgressContent{
+			Content: &Content{
+				ContentName:      ContentName{},
+				AlternativeNames: make([]*ContentName, 0),
+			},
+		}
+
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Value,
+			&temp.Season,
+			&temp.StoredStatus,
+			&temp.StoredScore,
+			&temp.Review,
+			&temp.ReviewPublic,
+			&temp.Notes,
+			&temp.StartedAt,
+			&temp.CompletedAt,
+			&temp.RepeatCount,
+			&temp.Priority,
+			&temp.Content.Id,
+			&temp.Content.CreatedAt,
+			&temp.Content.UpdatedAt,
+			&temp.Content.Kind,
+			&temp.Content.Unit,
+			&temp.Content.Total,
+			&temp.Content.Seasons,
+			&temp.Content.Description,
+			&temp.Content.ImageUrl,
+			&temp.Content.UnitMinutes,
+			&temp.Content.ContentName.Id,
+			&temp.Content.ContentName.CreatedAt,
+			&temp.Content.ContentName.UpdatedAt,
+			&temp.Content.ContentName.Name,
+		); err != nil {
+			log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressStarted: Scan: %v", err)
+			return nil, err
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectProgressByStatus(tx *sql.Tx, reqUser *User, status string, kind *string, orderBy string) ([]*ProgressContent, error) {
+	result := make([]*ProgressContent, 0)
+
+	query := fmt.Sprintf(`
+		SELECT
+		p.id, p.created_at, p.updated_at, p.value, p.season, p.status, p.score, p.review, p.review_public, p.notes, p.started_at, p.completed_at, p.repeat_count, p.priority,
+		a.id, a.created_at, a.updated_at, a.kind, a.unit, a.total, a.seasons, a.description, a.image_url, a.unit_minutes,
+		an.id, an.created_at, an.updated_at, an.name
+		FROM progress_content p
+		JOIN content a ON p.content_id = a.id
+		JOIN content_names an ON a.content_names_id = an.id
+		JOIN user_library ON p.user_library_id = user_library.id
+		WHERE user_library.user_id = $1
+		AND ($2::text IS NULL OR a.kind = $2)
+		AND p.status = $3
+		ORDER BY an.name %s
+	`,
+		orderBy,
+	)
+
+	queryRows, err := tx.Query(query, reqUser.Id, kind, status)
+	if err != nil {
+		log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressByStatus: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ProgressContent{
+			Content: &Content{
+				ContentName:      ContentName{},
+				AlternativeNames: make([]*ContentName, 0),
+			},
+		}
+
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Value,
+			&temp.Season,
+			&temp.StoredStatus,
+			&temp.StoredScore,
+			&temp.Review,
+			&temp.ReviewPublic,
+			&temp.Notes,
+			&temp.StartedAt,
+			&temp.CompletedAt,
+			&temp.RepeatCount,
+			&temp.Priority,
+			&temp.Content.Id,
+			&temp.Content.CreatedAt,
+			&temp.Content.UpdatedAt,
+			&temp.Content.Kind,
+			&temp.Content.Unit,
+			&temp.Content.Total,
+			&temp.Content.Seasons,
+			&temp.Content.Description,
+			&temp.Content.ImageUrl,
+			&temp.Content.UnitMinutes,
+			&temp.Content.ContentName.Id,
+			&temp.Content.ContentName.CreatedAt,
+			&temp.Content.ContentName.UpdatedAt,
+			&temp.Content.ContentName.Name,
+		); err != nil {
+			log.Printf("error: Dbs: RelContentUserLibrary: SelectProgressByStatus: Scan: %v", err)
+			return nil, err
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func DeleteProgress(tx *sql.Tx, reqUser *User, reqRelContentUserLibrary *ProgressContent) error {
+	query := `
+	WITH user_lib AS (
+		SELECT * FROM user_library WHERE user_id = $1
+	)
+	DELETE FROM progress_content ul
+	USING user_lib
+	WHERE ul.user_library_id = user_lib.id
+	AND ul.id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqUser.Id,
+		reqRelContentUserLibrary.Id,
+	)
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary DeleteRelContentUserLibrary: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: DbsRelContentUserLibrary DeleteRelContentUserLibrary: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: DbsRelContentUserLibrary DeleteRelContentUserLibrary: RowsAffected: %v", sql.ErrNoRows)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateProgressStatus stores the status of an entry and keeps its dates in
+// step. Starting a rewatch counts a repeat and restarts progress from zero
+// while the earlier completion is kept.
+func UpdateProgressStatus(tx *sql.Tx, reqUser *User, reqProgress *ProgressContent) error {
+	query := `
+	UPDATE progress_content p
+	SET
+		updated_at = $3,
+		status = $4,
+		started_at = CASE WHEN $4 IN ('watching', 'rewatching', 'completed') THEN COALESCE(p.started_at, $3) ELSE p.started_at END,
+		completed_at = CASE
+			WHEN $4 = 'completed' AND (p.completed_at IS NULL OR p.status = 'rewatching') THEN $3
+			ELSE p.completed_at
+		END,
+		repeat_count = CASE WHEN $4 = 'rewatching' AND p.status IS DISTINCT FROM 'rewatching' THEN p.repeat_count + 1 ELSE p.repeat_count END,
+		value = CASE WHEN $4 = 'rewatching' AND p.status IS DISTINCT FROM 'rewatching' THEN 0 ELSE p.value END,
+		season = CASE WHEN $4 = 'rewatching' AND p.status IS DISTINCT FROM 'rewatching' AND p.season IS NOT NULL THEN 1 ELSE p.season END
+	FROM user_library
+	WHERE p.user_library_id = user_library.id
+	AND user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqUser.Id,
+		reqProgress.Id,
+		time.Now(),
+		reqProgress.StoredStatus,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressStatus: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressStatus: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressStatus: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateProgressReview replaces the score, review and notes of an entry. The
+// score is expected on the stored 100 point scale.
+func UpdateProgressReview(tx *sql.Tx, reqUser *User, reqProgress *ProgressContent) error {
+	query := `
+	UPDATE progress_content p
+	SET
+		updated_at = $3,
+		score = $4,
+		review = $5,
+		review_public = $6,
+		notes = $7
+	FROM user_library
+	WHERE p.user_library_id = user_library.id
+	AND user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqUser.Id,
+		reqProgress.Id,
+		time.Now(),
+		reqProgress.StoredScore,
+		reqProgress.Review,
+		reqProgress.ReviewPublic,
+		reqProgress.Notes,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressReview: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressReview: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressReview: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func UpdateProgressDates(tx *sql.Tx, reqUser *User, reqProgress *ProgressContent, repeatCount *int) error {
+	query := `
+	UPDATE progress_content p
+	SET
+		updated_at = $3,
+		started_at = $4,
+		completed_at = $5,
+		repeat_count = COALESCE($6, p.repeat_count)
+	FROM user_library
+	WHERE p.user_library_id = user_library.id
+	AND user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqUser.Id,
+		reqProgress.Id,
+		time.Now(),
+		reqProgress.StartedAt,
+		reqProgress.CompletedAt,
+		repeatCount,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressDates: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressDates: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressDates: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func UpdateProgressPriority(tx *sql.Tx, reqUser *User, reqProgress *ProgressContent) error {
+	query := `
+	UPDATE progress_content p
+	SET
+		updated_at = $3,
+		priority = $4
+	FROM user_library
+	WHERE p.user_library_id = user_library.id
+	AND user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqUser.Id,
+		reqProgress.Id,
+		time.Now(),
+		reqProgress.Priority,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressPriority: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressPriority: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ProgressContent: UpdateProgressPriority: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SelectLibrarySearchScores returns the search_content score of every
+// content in the library of reqUser matching search.
+func SelectLibrarySearchScores(tx *sql.Tx, reqUser *User, search *Search) (map[uuid.UUID]float64, error) {
+	result := make(map[uuid.UUID]float64)
+
+	query := `
+	SELECT sm.content_id, sm.score
+	FROM search_content($2, $3) sm
+	JOIN progress_content progress ON progress.content_id = sm.content_id
+	JOIN user_library ul ON ul.id = progress.user_library_id
+	WHERE ul.user_id = $1
+	`
+
+	rows, err := tx.Query(query, reqUser.Id, search.SearchValue, search.RomajiValue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contentId uuid.UUID
+		var score float64
+		if err := rows.Scan(&contentId, &score); err != nil {
+			return nil, err
+		}
+		result[contentId] = score
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
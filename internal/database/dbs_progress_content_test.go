@@ -0,0 +1,36 @@
+package database
+
+import "testing"
+
+func TestDerivedStatus(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	tests := []struct {
+		name    string
+		value   int
+		season  *int
+		total   *int
+		seasons *int
+		result  string
+	}{
+		{"nothing watched", 0, nil, intPtr(12), nil, STATUS_NOT_STARTED},
+		{"first season not started", 0, intPtr(1), intPtr(12), intPtr(3), STATUS_NOT_STARTED},
+		{"partway", 5, nil, intPtr(12), nil, STATUS_STARTED},
+		{"open ended", 40, nil, nil, nil, STATUS_STARTED},
+		{"finished", 12, nil, intPtr(12), nil, STATUS_COMPLETED},
+		{"finished first of three seasons", 12, intPtr(1), intPtr(12), intPtr(3), STATUS_STARTED},
+		{"finished without season of three", 12, nil, intPtr(12), intPtr(3), STATUS_STARTED},
+		{"start of second season", 0, intPtr(2), intPtr(12), intPtr(3), STATUS_STARTED},
+		{"partway last season", 6, intPtr(3), intPtr(12), intPtr(3), STATUS_STARTED},
+		{"finished last season", 12, intPtr(3), intPtr(12), intPtr(3), STATUS_COMPLETED},
+		{"finished single season", 12, nil, intPtr(12), intPtr(1), STATUS_COMPLETED},
+	}
+
+	for _, v := range tests {
+		if result := DerivedStatus(v.value, v.season, v.total, v.seasons); result != v.result {
+			t.Errorf("%s: DerivedStatus = %q, want %q", v.name, result, v.result)
+		}
+	}
+}
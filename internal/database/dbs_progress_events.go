@@ -0,0 +1,397 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EVENT_ADDED    = "added"
+	EVENT_PROGRESS = "progress"
+	EVENT_STATUS   = "status"
+	EVENT_REVIEW   = "review"
+	EVENT_DATES    = "dates"
+	EVENT_PRIORITY = "priority"
+	EVENT_REMOVED  = "removed"
+	EVENT_UNDO     = "undo"
+
+	// UNDO_STACK_SIZE is how many of a user's most recent mutations can be
+	// reverted with Undo.
+	UNDO_STACK_SIZE = 20
+)
+
+var ErrNothingToUndo = errors.New("nothing to undo")
+var ErrUndoConflict = errors.New("entry changed since the event being undone")
+
+// ProgressEvent is an append-only record of a change to a library entry.
+// Before and After hold the progress_content row as it was on either side of
+// the change and are null when the entry did not exist.
+type ProgressEvent struct {
+	Id             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Action         string          `json:"action"`
+	Before         json.RawMessage `json:"before"`
+	After          json.RawMessage `json:"after"`
+	ProgressId     uuid.UUID       `json:"progress_id"`
+	ContentId      uuid.UUID       `json:"content_id"`
+	RevertsEventId *uuid.UUID      `json:"reverts_event_id"`
+	UserId         uuid.UUID       `json:"-"`
+}
+
+type ProgressSnapshot struct {
+	ProgressId uuid.UUID
+	ContentId  uuid.UUID
+	Data       []byte
+}
+
+func SelectProgressSnapshot(tx *sql.Tx, reqUser *User, reqProgress *ProgressContent) (*ProgressSnapshot, error) {
+	result := &ProgressSnapshot{}
+
+	query := `
+	SELECT p.id, p.content_id, to_jsonb(p)
+	FROM progress_content p
+	JOIN user_library ON p.user_library_id = user_library.id
+	WHERE user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		reqProgress.Id,
+	).Scan(
+		&result.ProgressId,
+		&result.ContentId,
+		&result.Data,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: SelectProgressSnapshot: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// InsertProgressEvent records action against the entry described by the
+// snapshots. Either snapshot may be nil, but not both.
+func InsertProgressEvent(tx *sql.Tx, reqUser *User, action string, before, after *ProgressSnapshot) error {
+	query := `
+	INSERT INTO progress_events (id, action, before, after, progress_id, content_id, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	var beforeData, afterData []byte
+	ref := after
+	if before != nil {
+		beforeData = before.Data
+		ref = before
+	}
+	if after != nil {
+		afterData = after.Data
+	}
+
+	queryResult, err := tx.Exec(
+		query,
+		uuid.New(),
+		action,
+		beforeData,
+		afterData,
+		ref.ProgressId,
+		ref.ContentId,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: InsertProgressEvent: Query: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: InsertProgressEvent: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ProgressEvents: InsertProgressEvent: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func SelectProgressEvents(tx *sql.Tx, reqUser *User, reqProgress *ProgressContent) ([]*ProgressEvent, error) {
+	result := make([]*ProgressEvent, 0)
+
+	query := `
+	SELECT e.id, e.created_at, e.action, e.before, e.after, e.progress_id, e.content_id, e.reverts_event_id, e.user_id
+	FROM progress_events e
+	WHERE e.user_id = $1
+	AND e.progress_id = $2
+	ORDER BY e.created_at DESC
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		reqUser.Id,
+		reqProgress.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: SelectProgressEvents: Query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := queryRows.Close(); err != nil {
+			log.Printf("error: Dbs: ProgressEvents: SelectProgressEvents: Close rows: %v", err)
+		}
+	}()
+
+	for queryRows.Next() {
+		temp := &ProgressEvent{}
+		var before, after []byte
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.Action,
+			&before,
+			&after,
+			&temp.ProgressId,
+			&temp.ContentId,
+			&temp.RevertsEventId,
+			&temp.UserId,
+		); err != nil {
+			log.Printf("error: Dbs: ProgressEvents: SelectProgressEvents: Scan: %v", err)
+			return nil, err
+		}
+		temp.Before = before
+		temp.After = after
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+// SelectUndoableEvent returns the most recent of the user's last
+// UNDO_STACK_SIZE mutations that has not been undone yet.
+func SelectUndoableEvent(tx *sql.Tx, reqUser *User) (*ProgressEvent, error) {
+	result := &ProgressEvent{}
+
+	query := `
+	WITH recent AS (
+		SELECT e.id
+		FROM progress_events e
+		WHERE e.user_id = $1
+		AND e.action <> 'undo'
+		ORDER BY e.created_at DESC
+		LIMIT $2
+	)
+	SELECT e.id, e.created_at, e.action, e.before, e.after, e.progress_id, e.content_id, e.reverts_event_id, e.user_id
+	FROM progress_events e
+	JOIN recent ON e.id = recent.id
+	WHERE NOT EXISTS (
+		SELECT 1 FROM progress_events u
+		WHERE u.reverts_event_id = e.id
+	)
+	ORDER BY e.created_at DESC
+	LIMIT 1
+	`
+
+	var before, after []byte
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		UNDO_STACK_SIZE,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.Action,
+		&before,
+		&after,
+		&result.ProgressId,
+		&result.ContentId,
+		&result.RevertsEventId,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: SelectUndoableEvent: Scan: %v", err)
+		return nil, err
+	}
+	result.Before = before
+	result.After = after
+
+	return result, nil
+}
+
+// ProgressMatchesSnapshot reports whether the entry still holds the values
+// an event set: the columns of after that differ from before, all of them
+// when before is nil. Columns added to the table since the event are not
+// compared. A nil after matches an entry that does not exist.
+func ProgressMatchesSnapshot(tx *sql.Tx, reqUser *User, progressId uuid.UUID, before, after []byte) (bool, error) {
+	query := `
+	WITH current_progress AS (
+		SELECT to_jsonb(p) AS data
+		FROM progress_content p
+		JOIN user_library ON p.user_library_id = user_library.id
+		WHERE user_library.user_id = $1
+		AND p.id = $2
+	)
+	SELECT CASE
+		WHEN $4::jsonb IS NULL THEN NOT EXISTS (SELECT 1 FROM current_progress)
+		ELSE EXISTS (SELECT 1 FROM current_progress) AND NOT EXISTS (
+			SELECT 1
+			FROM current_progress, jsonb_each($4::jsonb) touched
+			WHERE ($3::jsonb IS NULL OR $3::jsonb -> touched.key IS DISTINCT FROM touched.value)
+			AND current_progress.data -> touched.key IS DISTINCT FROM touched.value
+		)
+	END
+	`
+
+	var match bool
+	if err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		progressId,
+		before,
+		after,
+	).Scan(&match); err != nil {
+		log.Printf("error: Dbs: ProgressEvents: ProgressMatchesSnapshot: Scan: %v", err)
+		return false, err
+	}
+
+	return match, nil
+}
+
+// ProgressContentInLibrary reports whether the library of reqUser has an
+// entry for contentId other than progressId.
+func ProgressContentInLibrary(tx *sql.Tx, reqUser *User, contentId uuid.UUID, progressId uuid.UUID) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM progress_content p
+		JOIN user_library ON p.user_library_id = user_library.id
+		WHERE user_library.user_id = $1
+		AND p.content_id = $2
+		AND p.id <> $3
+	)
+	`
+
+	var exists bool
+	if err := tx.QueryRow(
+		query,
+		reqUser.Id,
+		contentId,
+		progressId,
+	).Scan(&exists); err != nil {
+		log.Printf("error: Dbs: ProgressEvents: ProgressContentInLibrary: Scan: %v", err)
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// RestoreProgressSnapshot replaces the entry with the row recorded in data,
+// including its original id and created_at. Columns added to the table after
+// data was recorded take their defaults. A nil data removes the entry.
+func RestoreProgressSnapshot(tx *sql.Tx, reqUser *User, progressId uuid.UUID, data []byte) error {
+	queryDelete := `
+	DELETE FROM progress_content p
+	USING user_library
+	WHERE p.user_library_id = user_library.id
+	AND user_library.user_id = $1
+	AND p.id = $2
+	`
+
+	if _, err := tx.Exec(queryDelete, reqUser.Id, progressId); err != nil {
+		log.Printf("error: Dbs: ProgressEvents: RestoreProgressSnapshot: Delete: %v", err)
+		return err
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	queryInsert := `
+	INSERT INTO progress_content (
+		id, created_at, updated_at, value, season, status, score, review, review_public, notes,
+		started_at, completed_at, repeat_count, priority, content_id, user_library_id
+	)
+	SELECT snapshot.id, snapshot.created_at, snapshot.updated_at, COALESCE(snapshot.value, 0), snapshot.season, snapshot.status,
+	snapshot.score, snapshot.review, COALESCE(snapshot.review_public, FALSE), snapshot.notes,
+	snapshot.started_at, snapshot.completed_at, COALESCE(snapshot.repeat_count, 0), COALESCE(snapshot.priority, 0),
+	snapshot.content_id, snapshot.user_library_id
+	FROM jsonb_populate_record(NULL::progress_content, $2::jsonb) snapshot
+	JOIN user_library ON snapshot.user_library_id = user_library.id
+	WHERE user_library.user_id = $1
+	`
+
+	queryResult, err := tx.Exec(queryInsert, reqUser.Id, data)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: RestoreProgressSnapshot: Insert: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: RestoreProgressSnapshot: RowsAffected: %v", err)
+		return err
+	}
+	if n == 0 {
+		log.Printf("error: Dbs: ProgressEvents: RestoreProgressSnapshot: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func InsertProgressUndoEvent(tx *sql.Tx, reqUser *User, target *ProgressEvent, before, after *ProgressSnapshot) (*ProgressEvent, error) {
+	result := &ProgressEvent{}
+
+	query := `
+	INSERT INTO progress_events (id, action, before, after, progress_id, content_id, reverts_event_id, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, action, before, after, progress_id, content_id, reverts_event_id, user_id
+	`
+
+	var beforeData, afterData []byte
+	if before != nil {
+		beforeData = before.Data
+	}
+	if after != nil {
+		afterData = after.Data
+	}
+
+	var resultBefore, resultAfter []byte
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		EVENT_UNDO,
+		beforeData,
+		afterData,
+		target.ProgressId,
+		target.ContentId,
+		target.Id,
+		reqUser.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.Action,
+		&resultBefore,
+		&resultAfter,
+		&result.ProgressId,
+		&result.ContentId,
+		&result.RevertsEventId,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: ProgressEvents: InsertProgressUndoEvent: Scan: %v", err)
+		return nil, err
+	}
+	result.Before = resultBefore
+	result.After = resultAfter
+
+	return result, nil
+}
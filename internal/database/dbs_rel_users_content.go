@@ -0,0 +1,233 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrContentOwned = errors.New("content already has an owner")
+)
+
+// RelUsersContent records who created a content row. Each content has at
+// most one owner, rows created before ownership was tracked have none.
+type RelUsersContent struct {
+	Id        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserId    uuid.UUID `json:"user_id"`
+	ContentId uuid.UUID `json:"content_id"`
+}
+
+type DbsRelUsersContent interface {
+	InsertRel(rel *RelUsersContent) error
+	GetRel(rel *RelUsersContent) (*RelUsersContent, error)
+	GetOwner(reqContent *Content) (*RelUsersContent, error)
+	ClaimContent(reqUser *User, reqContent *Content) (*RelUsersContent, error)
+}
+
+type PgDbsUsersContent struct {
+	db DbService
+}
+
+var dbsUsersContentInstance *PgDbsUsersContent
+
+func NewDbsUsersContent(db DbService) DbsRelUsersContent {
+	if dbsUsersContentInstance != nil {
+		return dbsUsersContentInstance
+	}
+
+	newDbsUsersContent := PgDbsUsersContent{
+		db: db,
+	}
+	dbsUsersContentInstance = &newDbsUsersContent
+
+	return dbsUsersContentInstance
+}
+
+func (d *PgDbsUsersContent) InsertRel(rel *RelUsersContent) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: dbs rel_users_content InsertRel: Rollback: %v", err)
+		}
+	}()
+
+	err = InsertRelUsersContent(tx, rel)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *PgDbsUsersContent) GetRel(rel *RelUsersContent) (*RelUsersContent, error) {
+	existingRel := &RelUsersContent{}
+
+	query := `SELECT id, created_at, updated_at, user_id, content_id
+	FROM rel_users_content
+	WHERE user_id = $1
+	AND content_id = $2`
+
+	err := d.db.Conn().QueryRow(
+		query,
+		rel.UserId,
+		rel.ContentId,
+	).Scan(
+		&existingRel.Id,
+		&existingRel.CreatedAt,
+		&existingRel.UpdatedAt,
+		&existingRel.UserId,
+		&existingRel.ContentId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return existingRel, nil
+}
+
+// GetOwner returns sql.ErrNoRows when the content has no recorded owner.
+func (d *PgDbsUsersContent) GetOwner(reqContent *Content) (*RelUsersContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: RelUsersContent: GetOwner: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: RelUsersContent: GetOwner: Rollback: %v", err)
+		}
+	}()
+
+	dbRel, err := SelectContentOwner(tx, reqContent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: RelUsersContent: GetOwner: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbRel, nil
+}
+
+// ClaimContent makes reqUser the owner of content that has none. It returns
+// sql.ErrNoRows for unknown content and ErrContentOwned when an owner exists.
+func (d *PgDbsUsersContent) ClaimContent(reqUser *User, reqContent *Content) (*RelUsersContent, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: RelUsersContent: ClaimContent: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: RelUsersContent: ClaimContent: Rollback: %v", err)
+		}
+	}()
+
+	var contentId uuid.UUID
+	err = tx.QueryRow(`SELECT id FROM content WHERE id = $1`, reqContent.Id).Scan(&contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &RelUsersContent{
+		UserId:    reqUser.Id,
+		ContentId: contentId,
+	}
+	err = InsertRelUsersContent(tx, rel)
+	if err == sql.ErrNoRows {
+		return nil, ErrContentOwned
+	} else if err != nil {
+		log.Printf("error: Dbs: RelUsersContent: ClaimContent: InsertRelUsersContent: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: RelUsersContent: ClaimContent: Commit: %v", err)
+		return nil, err
+	}
+
+	return rel, nil
+}
+
+// InsertRelUsersContent fills in rel and returns sql.ErrNoRows when the
+// content already has an owner.
+func InsertRelUsersContent(tx *sql.Tx, rel *RelUsersContent) error {
+	query := `
+	INSERT INTO rel_users_content (id, user_id, content_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (content_id) DO NOTHING
+	RETURNING id, created_at, updated_at
+	`
+
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		rel.UserId,
+		rel.ContentId,
+	).Scan(
+		&rel.Id,
+		&rel.CreatedAt,
+		&rel.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func SelectContentOwner(tx *sql.Tx, reqContent *Content) (*RelUsersContent, error) {
+	result := &RelUsersContent{}
+
+	query := `
+	SELECT id, created_at, updated_at, user_id, content_id
+	FROM rel_users_content
+	WHERE content_id = $1
+	`
+
+	err := tx.QueryRow(
+		query,
+		reqContent.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.UserId,
+		&result.ContentId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
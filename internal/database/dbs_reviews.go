@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Review is a library entry review its author chose to make public. The score
+// is given in the author's own score scale.
+type Review struct {
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Username   *string   `json:"username"`
+	Score      *int      `json:"score"`
+	ScoreScale int       `json:"score_scale"`
+	Review     *string   `json:"review"`
+}
+
+func SelectPublicReviews(tx *sql.Tx, reqContent *Content) ([]*Review, error) {
+	result := make([]*Review, 0)
+
+	query := `
+	SELECT p.created_at, p.updated_at, u.username, p.score, u.score_scale, p.review
+	FROM progress_content p
+	JOIN user_library ON p.user_library_id = user_library.id
+	JOIN users u ON user_library.user_id = u.id
+	WHERE p.content_id = $1
+	AND p.review_public
+	AND (p.review IS NOT NULL OR p.score IS NOT NULL)
+	ORDER BY p.updated_at DESC
+	`
+
+	queryRows, err := tx.Query(query, reqContent.Id)
+	if err != nil {
+		log.Printf("error: Dbs: Reviews: SelectPublicReviews: Query: %v", err)
+		return nil, err
+	}
+	defer queryRows.Close()
+
+	for queryRows.Next() {
+		temp := &Review{}
+
+		if err := queryRows.Scan(
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Username,
+			&temp.Score,
+			&temp.ScoreScale,
+			&temp.Review,
+		); err != nil {
+			log.Printf("error: Dbs: Reviews: SelectPublicReviews: Scan: %v", err)
+			return nil, err
+		}
+
+		if temp.Score != nil {
+			score := ScoreFromStored(*temp.Score, temp.ScoreScale)
+			temp.Score = &score
+		}
+
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a signed in device. It is the live row of a refresh token family,
+// so its id stays the same across refreshes. LastUsedAt moves on refresh only.
+// Access tokens carry the session id and stop working as soon as the session
+// is deleted.
+type Session struct {
+	Id         uuid.UUID `json:"id"`
+	DeviceName *string   `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IpAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func (d *PgDbsJwt) GetSessions(reqUser *User) ([]*Session, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: GetSessions: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Jwt: GetSessions: Rollback: %v", err)
+		}
+	}()
+
+	dbSessions, err := SelectSessions(tx, reqUser)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: GetSessions: SelectSessions: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: GetSessions: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbSessions, nil
+}
+
+func (d *PgDbsJwt) DeleteSession(reqUser *User, reqSession *Session) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteSession: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Jwt: DeleteSession: Rollback: %v", err)
+		}
+	}()
+
+	err = DeleteSession(tx, reqUser, reqSession)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteSession: DeleteSession: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteSession: Commit: %v", err)
+		return err
+	}
+	d.ForgetSessions(reqSession.Id)
+
+	return nil
+}
+
+// DeleteOtherSessions revokes every session of the user except reqSession.
+func (d *PgDbsJwt) DeleteOtherSessions(reqUser *User, reqSession *Session) error {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteOtherSessions: Conn: %v", err)
+		return err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Jwt: DeleteOtherSessions: Rollback: %v", err)
+		}
+	}()
+
+	sessionIds, err := DeleteOtherSessions(tx, reqUser, reqSession)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteOtherSessions: DeleteOtherSessions: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteOtherSessions: Commit: %v", err)
+		return err
+	}
+	d.ForgetSessions(sessionIds...)
+
+	return nil
+}
+
+func SelectSessions(tx *sql.Tx, reqUser *User) ([]*Session, error) {
+	result := make([]*Session, 0)
+
+	query := `
+	SELECT family_id, device_name, user_agent, ip_address, signed_in_at, last_used_at, refresh_token_expiration
+	FROM jwt
+	WHERE user_id = $1
+	AND rotated_at IS NULL
+	AND refresh_token_expiration > $2
+	ORDER BY last_used_at DESC
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		reqUser.Id,
+		time.Now(),
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: SelectSessions: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		session := &Session{}
+		err := queryRows.Scan(
+			&session.Id,
+			&session.DeviceName,
+			&session.UserAgent,
+			&session.IpAddress,
+			&session.SignedInAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			log.Printf("error: Dbs: Jwt: SelectSessions: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, session)
+	}
+
+	return result, nil
+}
+
+func DeleteSession(tx *sql.Tx, reqUser *User, reqSession *Session) error {
+	query := `
+	DELETE FROM jwt
+	WHERE family_id = $1
+	AND user_id = $2
+	`
+
+	queryResult, err := tx.Exec(
+		query,
+		reqSession.Id,
+		reqUser.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteSession: Exec: %v", err)
+		return err
+	}
+
+	n, err := queryResult.RowsAffected()
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteSession: RowsAffected: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		log.Printf("error: Dbs: Jwt: DeleteSession: RowsAffected: %v", n)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteOtherSessions returns the ids of the sessions it revoked.
+func DeleteOtherSessions(tx *sql.Tx, reqUser *User, reqSession *Session) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0)
+
+	query := `
+	DELETE FROM jwt
+	WHERE user_id = $1
+	AND family_id <> $2
+	RETURNING family_id
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		reqUser.Id,
+		reqSession.Id,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteOtherSessions: Query: %v", err)
+		return nil, err
+	}
+	defer queryRows.Close()
+
+	seen := make(map[uuid.UUID]bool)
+	for queryRows.Next() {
+		var familyId uuid.UUID
+		if err := queryRows.Scan(&familyId); err != nil {
+			log.Printf("error: Dbs: Jwt: DeleteOtherSessions: Scan: %v", err)
+			return nil, err
+		}
+		if !seen[familyId] {
+			seen[familyId] = true
+			result = append(result, familyId)
+		}
+	}
+	if err := queryRows.Err(); err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteOtherSessions: Rows: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteUserSessions revokes every session of userId and returns their ids.
+func DeleteUserSessions(tx *sql.Tx, userId uuid.UUID) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0)
+
+	query := `
+	DELETE FROM jwt
+	WHERE user_id = $1
+	RETURNING family_id
+	`
+
+	queryRows, err := tx.Query(
+		query,
+		userId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteUserSessions: Query: %v", err)
+		return nil, err
+	}
+	defer queryRows.Close()
+
+	seen := make(map[uuid.UUID]bool)
+	for queryRows.Next() {
+		var familyId uuid.UUID
+		if err := queryRows.Scan(&familyId); err != nil {
+			log.Printf("error: Dbs: Jwt: DeleteUserSessions: Scan: %v", err)
+			return nil, err
+		}
+		if !seen[familyId] {
+			seen[familyId] = true
+			result = append(result, familyId)
+		}
+	}
+	if err := queryRows.Err(); err != nil {
+		log.Printf("error: Dbs: Jwt: DeleteUserSessions: Rows: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
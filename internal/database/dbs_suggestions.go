@@ -0,0 +1,274 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/JustinLi007/whatdoing-server/internal/algo"
+	"github.com/google/uuid"
+)
+
+const (
+	SUGGEST_LIMIT_DEFAULT = 10
+	SUGGEST_LIMIT_MAX     = 50
+)
+
+// Suggestion is a title completion. Name is the primary or alternative name
+// that matched.
+type Suggestion struct {
+	ContentId uuid.UUID `json:"content_id"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Primary   bool      `json:"primary"`
+}
+
+// DbsSuggestions keeps the names of approved content in memory for
+// typeahead. Writes to the catalog call Refresh once committed, so Suggest
+// never touches the database. Kanji are not read, a kanji title completes from
+// romaji only through a kana alternative name that reads it.
+type DbsSuggestions interface {
+	Load() error
+	Refresh(contentIds ...uuid.UUID)
+	Suggest(query string, limit int) []*Suggestion
+}
+
+type PgDbsSuggestions struct {
+	db DbService
+
+	// writeMu serializes Load and Refresh from the read through the apply, so
+	// an older read never overwrites a newer one. mu guards the index itself
+	// and is only held while applying, so Suggest does not wait on the db.
+	writeMu sync.Mutex
+	mu      sync.RWMutex
+	trie    *algo.Trie[*Suggestion]
+	keys    map[uuid.UUID][]string
+	ready   bool
+}
+
+var dbsSuggestionsInstance *PgDbsSuggestions
+
+func NewDbsSuggestions(db DbService) DbsSuggestions {
+	if dbsSuggestionsInstance != nil {
+		return dbsSuggestionsInstance
+	}
+
+	newDbsSuggestions := &PgDbsSuggestions{
+		db:   db,
+		trie: algo.NewTrie[*Suggestion](),
+		keys: make(map[uuid.UUID][]string),
+	}
+	dbsSuggestionsInstance = newDbsSuggestions
+
+	return dbsSuggestionsInstance
+}
+
+// Load indexes the whole catalog.
+func (d *PgDbsSuggestions) Load() error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	suggestions, err := d.selectSuggestions(nil)
+	if err != nil {
+		log.Printf("error: Dbs: Suggestions: Load: selectSuggestions: %v", err)
+		return err
+	}
+
+	trie := algo.NewTrie[*Suggestion]()
+	keys := make(map[uuid.UUID][]string)
+	insertSuggestions(trie, keys, suggestions)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.trie = trie
+	d.keys = keys
+	d.ready = true
+
+	return nil
+}
+
+// Refresh re-reads the names of contentIds, dropping content that was
+// deleted or is no longer approved. It runs after the write committed, so a
+// failure is only logged and leaves those entries stale until the next one.
+func (d *PgDbsSuggestions) Refresh(contentIds ...uuid.UUID) {
+	if len(contentIds) == 0 {
+		return
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	suggestions, err := d.selectSuggestions(contentIds)
+	if err != nil {
+		log.Printf("error: Dbs: Suggestions: Refresh: selectSuggestions: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.ready {
+		return
+	}
+	for _, id := range contentIds {
+		for _, key := range d.keys[id] {
+			d.trie.Remove(key, func(s *Suggestion) bool {
+				return s.ContentId == id
+			})
+		}
+		delete(d.keys, id)
+	}
+	insertSuggestions(d.trie, d.keys, suggestions)
+}
+
+func (d *PgDbsSuggestions) selectSuggestions(contentIds []uuid.UUID) ([]*Suggestion, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Suggestions: selectSuggestions: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Suggestions: selectSuggestions: Rollback: %v", err)
+		}
+	}()
+
+	suggestions, err := SelectSuggestions(tx, contentIds)
+	if err != nil {
+		log.Printf("error: Dbs: Suggestions: selectSuggestions: SelectSuggestions: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: Dbs: Suggestions: selectSuggestions: Commit: %v", err)
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// Suggest returns up to limit content, capped at SUGGEST_LIMIT_MAX, whose
+// name or its romaji starts with query. Shorter completions come first and
+// each content appears once.
+func (d *PgDbsSuggestions) Suggest(query string, limit int) []*Suggestion {
+	result := make([]*Suggestion, 0)
+
+	if limit <= 0 {
+		limit = SUGGEST_LIMIT_DEFAULT
+	}
+	limit = min(limit, SUGGEST_LIMIT_MAX)
+
+	query = algo.Fold(strings.TrimSpace(query))
+	if query == "" {
+		return result
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	seen := make(map[uuid.UUID]bool)
+	walk := func(s *Suggestion) bool {
+		if !seen[s.ContentId] {
+			seen[s.ContentId] = true
+			result = append(result, s)
+		}
+		return len(result) < limit
+	}
+
+	d.trie.WalkPrefix(query, walk)
+	if romaji := algo.Romanize(query); len(result) < limit && romaji != "" && romaji != query {
+		d.trie.WalkPrefix(romaji, walk)
+	}
+
+	return result
+}
+
+// insertSuggestions indexes each name under its folded form and, when it
+// differs, its romaji. The romaji of a kana name that reads a kanji title
+// completes to the title. The caller holds the lock guarding trie and keys.
+func insertSuggestions(trie *algo.Trie[*Suggestion], keys map[uuid.UUID][]string, suggestions []*Suggestion) {
+	titles := make(map[uuid.UUID]*Suggestion)
+	for _, v := range suggestions {
+		if v.Primary {
+			titles[v.ContentId] = v
+		}
+	}
+
+	for _, v := range suggestions {
+		searchName, romaji, _ := nameForms(v.Name)
+		trie.Insert(searchName, v)
+		keys[v.ContentId] = append(keys[v.ContentId], searchName)
+
+		if romaji == "" || romaji == searchName {
+			continue
+		}
+		target := v
+		if title, ok := titles[v.ContentId]; ok && !v.Primary && algo.IsReading(v.Name, title.Name) {
+			target = title
+		}
+		trie.Insert(romaji, target)
+		keys[v.ContentId] = append(keys[v.ContentId], romaji)
+	}
+}
+
+// SelectSuggestions lists the primary and alternative names of approved
+// content, all of it when contentIds is nil.
+func SelectSuggestions(tx *sql.Tx, contentIds []uuid.UUID) ([]*Suggestion, error) {
+	result := make([]*Suggestion, 0)
+
+	query := `
+	SELECT c.id, c.kind, n.name, n.id = c.content_names_id
+	FROM content c
+	JOIN content_names n ON n.id = c.content_names_id
+	OR n.id IN (
+		SELECT alt.content_names_id
+		FROM rel_content_content_names alt
+		WHERE alt.content_id = c.id
+	)
+	WHERE c.status = 'approved'
+	AND ($1::uuid[] IS NULL OR c.id = ANY($1))
+	`
+
+	var args any
+	if contentIds != nil {
+		args = contentIds
+	}
+
+	rows, err := tx.Query(query, args)
+	if err != nil {
+		log.Printf("error: Dbs: Suggestions: SelectSuggestions: Query: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("error: Dbs: Suggestions: SelectSuggestions: Close rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		suggestion := &Suggestion{}
+		if err := rows.Scan(
+			&suggestion.ContentId,
+			&suggestion.Kind,
+			&suggestion.Name,
+			&suggestion.Primary,
+		); err != nil {
+			log.Printf("error: Dbs: Suggestions: SelectSuggestions: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("error: Dbs: Suggestions: SelectSuggestions: Rows: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
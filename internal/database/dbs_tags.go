@@ -0,0 +1,309 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Tag struct {
+	Id        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	UserId    uuid.UUID `json:"-"`
+}
+
+type ContentTag struct {
+	ContentId uuid.UUID
+	Tag       Tag
+}
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+type DbsTags interface {
+	GetAllTags(reqUser *User) ([]*Tag, error)
+	SetContentTags(reqUser *User, reqContent *Content, names []string) ([]*Tag, error)
+}
+
+type PgDbsTags struct {
+	db DbService
+}
+
+var dbsTagsInstance *PgDbsTags
+
+func NewDbsTags(db DbService) DbsTags {
+	if dbsTagsInstance != nil {
+		return dbsTagsInstance
+	}
+
+	newDbsTags := &PgDbsTags{
+		db: db,
+	}
+	dbsTagsInstance = newDbsTags
+
+	return dbsTagsInstance
+}
+
+func (d *PgDbsTags) GetAllTags(reqUser *User) ([]*Tag, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Tags: GetAllTags: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Tags: GetAllTags: Rollback: %v", err)
+		}
+	}()
+
+	dbTags, err := SelectAllTags(tx, reqUser)
+	if err != nil {
+		log.Printf("error: Dbs: Tags: GetAllTags: SelectAllTags: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Tags: GetAllTags: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbTags, nil
+}
+
+// SetContentTags replaces the tags reqUser put on reqContent, creating tags on
+// first use and dropping tags the user no longer uses anywhere.
+func (d *PgDbsTags) SetContentTags(reqUser *User, reqContent *Content, names []string) ([]*Tag, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: Dbs: Tags: SetContentTags: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: Dbs: Tags: SetContentTags: Rollback: %v", err)
+		}
+	}()
+
+	if err := DeleteContentTags(tx, reqUser, reqContent); err != nil {
+		log.Printf("error: Dbs: Tags: SetContentTags: DeleteContentTags: %v", err)
+		return nil, err
+	}
+
+	result := make([]*Tag, 0)
+	seen := make(map[string]bool)
+	for _, v := range names {
+		name := strings.ToLower(strings.TrimSpace(v))
+		if name == "" {
+			log.Printf("error: Dbs: Tags: SetContentTags: %v", ErrInvalidTag)
+			return nil, ErrInvalidTag
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		dbTag, err := InsertTagIfNotExist(tx, reqUser, name)
+		if err != nil {
+			log.Printf("error: Dbs: Tags: SetContentTags: InsertTagIfNotExist: %v", err)
+			return nil, err
+		}
+
+		if err := InsertContentTag(tx, reqContent, dbTag); err != nil {
+			log.Printf("error: Dbs: Tags: SetContentTags: InsertContentTag: %v", err)
+			return nil, err
+		}
+		result = append(result, dbTag)
+	}
+
+	if err := DeleteUnusedTags(tx, reqUser); err != nil {
+		log.Printf("error: Dbs: Tags: SetContentTags: DeleteUnusedTags: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error: Dbs: Tags: SetContentTags: Commit: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func SelectAllTags(tx *sql.Tx, reqUser *User) ([]*Tag, error) {
+	result := make([]*Tag, 0)
+
+	query := `SELECT id, created_at, updated_at, name, user_id
+	FROM tags
+	WHERE user_id = $1
+	ORDER BY name ASC`
+
+	queryRows, err := tx.Query(query, reqUser.Id)
+	if err != nil {
+		log.Printf("error: Dbs: Tags: SelectAllTags: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &Tag{}
+		if err := queryRows.Scan(
+			&temp.Id,
+			&temp.CreatedAt,
+			&temp.UpdatedAt,
+			&temp.Name,
+			&temp.UserId,
+		); err != nil {
+			log.Printf("error: Dbs: Tags: SelectAllTags: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func SelectContentTags(tx *sql.Tx, reqUser *User, reqContent []*Content) ([]*ContentTag, error) {
+	result := make([]*ContentTag, 0)
+
+	args := make([]uuid.UUID, 0)
+	for _, v := range reqContent {
+		args = append(args, v.Id)
+	}
+
+	if len(args) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT
+	rct.content_id,
+	t.id, t.created_at, t.updated_at, t.name, t.user_id
+	FROM rel_content_tags rct
+	JOIN tags t ON rct.tags_id = t.id
+	WHERE t.user_id = $1
+	AND rct.content_id = ANY($2)
+	ORDER BY t.name ASC`
+
+	queryRows, err := tx.Query(
+		query,
+		reqUser.Id,
+		args,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Tags: SelectContentTags: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		temp := &ContentTag{}
+		if err := queryRows.Scan(
+			&temp.ContentId,
+			&temp.Tag.Id,
+			&temp.Tag.CreatedAt,
+			&temp.Tag.UpdatedAt,
+			&temp.Tag.Name,
+			&temp.Tag.UserId,
+		); err != nil {
+			log.Printf("error: Dbs: Tags: SelectContentTags: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, temp)
+	}
+
+	return result, nil
+}
+
+func InsertTagIfNotExist(tx *sql.Tx, reqUser *User, name string) (*Tag, error) {
+	result := &Tag{}
+
+	query := `WITH insert_tag AS (
+		INSERT INTO tags (id, name, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id, created_at, updated_at, name, user_id
+	)
+	SELECT id, created_at, updated_at, name, user_id FROM insert_tag
+	UNION ALL
+	SELECT id, created_at, updated_at, name, user_id FROM tags
+	WHERE user_id = $3 AND name = $2`
+
+	err := tx.QueryRow(
+		query,
+		uuid.New(),
+		name,
+		reqUser.Id,
+	).Scan(
+		&result.Id,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.Name,
+		&result.UserId,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Tags: InsertTagIfNotExist: Scan: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func InsertContentTag(tx *sql.Tx, reqContent *Content, reqTag *Tag) error {
+	query := `INSERT INTO rel_content_tags (id, content_id, tags_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (content_id, tags_id) DO NOTHING`
+
+	if _, err := tx.Exec(
+		query,
+		uuid.New(),
+		reqContent.Id,
+		reqTag.Id,
+	); err != nil {
+		log.Printf("error: Dbs: Tags: InsertContentTag: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func DeleteContentTags(tx *sql.Tx, reqUser *User, reqContent *Content) error {
+	query := `DELETE FROM rel_content_tags rct
+	USING tags t
+	WHERE rct.tags_id = t.id
+	AND t.user_id = $1
+	AND rct.content_id = $2`
+
+	if _, err := tx.Exec(
+		query,
+		reqUser.Id,
+		reqContent.Id,
+	); err != nil {
+		log.Printf("error: Dbs: Tags: DeleteContentTags: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func DeleteUnusedTags(tx *sql.Tx, reqUser *User) error {
+	query := `DELETE FROM tags t
+	WHERE t.user_id = $1
+	AND NOT EXISTS (SELECT 1 FROM rel_content_tags rct WHERE rct.tags_id = t.id)`
+
+	if _, err := tx.Exec(
+		query,
+		reqUser.Id,
+	); err != nil {
+		log.Printf("error: Dbs: Tags: DeleteUnusedTags: Exec: %v", err)
+		return err
+	}
+
+	return nil
+}
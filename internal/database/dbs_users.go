@@ -1,11 +1,12 @@
 package database
 
 import (
+	"database/sql"
 	"errors"
 	"log"
 	"time"
 
-	"github.com/JustinLi007/whatdoing-server/internal/tokens"
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -41,36 +42,47 @@ func (p *Password) Validate(plainTextPassword string) (bool, error) {
 }
 
 type User struct {
-	Id        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	UpdatedAt time.Time `json:"-"`
-	Username  *string   `json:"username"`
-	Email     string    `json:"email"`
-	Password  Password  `json:"-"`
-	Role      string    `json:"role"`
+	Id         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+	Username   *string   `json:"username"`
+	Email      string    `json:"email"`
+	Password   Password  `json:"-"`
+	Role       string    `json:"role"`
+	ScoreScale int       `json:"score_scale"`
 }
 
 type DbsUsers interface {
 	CreateUser(user *User) (*User, error)
 	GetUserByEmailPassword(user *User) (*User, error)
 	GetUserById(id uuid.UUID) (*User, error)
-	AuthenticateWithJwt(jwt *tokens.Jwt) (*User, error)
+	UpdateSettings(user *User) (*User, error)
+	GetUsers() ([]*User, error)
+	UpdateRole(user *User) (*User, error)
+	BootstrapAdmin(email string) (*User, error)
 }
 
 type PgDbsUsers struct {
-	db DbService
+	db     DbService
+	dbsJwt DbsJwt
 }
 
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrLastAdmin   = errors.New("cannot remove the last admin")
+)
+
 var AnonymousUser = &User{}
 var dbsUsersInstance *PgDbsUsers
 
-func NewDbsUsers(db DbService) DbsUsers {
+func NewDbsUsers(db DbService, dbsJwt DbsJwt) DbsUsers {
 	if dbsUsersInstance != nil {
 		return dbsUsersInstance
 	}
 
 	newDbsUsers := &PgDbsUsers{
-		db: db,
+		db:     db,
+		dbsJwt: dbsJwt,
 	}
 	dbsUsersInstance = newDbsUsers
 
@@ -99,7 +111,7 @@ func (d *PgDbsUsers) CreateUser(user *User) (*User, error) {
 
 	query := `INSERT INTO users (id, email, password_hash)
 	VALUES ($1, $2, $3)
-	RETURNING id, created_at, updated_at, username, email, password_hash, role;`
+	RETURNING id, created_at, updated_at, username, email, password_hash, role, score_scale;`
 
 	err = tx.QueryRow(
 		query,
@@ -114,6 +126,7 @@ func (d *PgDbsUsers) CreateUser(user *User) (*User, error) {
 		&newUser.Email,
 		&newUser.Password.Hash,
 		&newUser.Role,
+		&newUser.ScoreScale,
 	)
 	if err != nil {
 		return nil, err
@@ -139,7 +152,7 @@ func (d *PgDbsUsers) GetUserByEmailPassword(user *User) (*User, error) {
 		Password: Password{},
 	}
 
-	query := `SELECT * FROM users
+	query := `SELECT id, created_at, updated_at, username, email, password_hash, role, score_scale FROM users
 	WHERE email = $1`
 
 	err := d.db.Conn().QueryRow(
@@ -153,6 +166,7 @@ func (d *PgDbsUsers) GetUserByEmailPassword(user *User) (*User, error) {
 		&existingUser.Email,
 		&existingUser.Password.Hash,
 		&existingUser.Role,
+		&existingUser.ScoreScale,
 	)
 	if err != nil {
 		return nil, err
@@ -175,7 +189,7 @@ func (d *PgDbsUsers) GetUserById(id uuid.UUID) (*User, error) {
 		Password: Password{},
 	}
 
-	query := `SELECT * FROM users
+	query := `SELECT id, created_at, updated_at, username, email, password_hash, role, score_scale FROM users
 	WHERE id = $1`
 
 	err := d.db.Conn().QueryRow(
@@ -189,6 +203,7 @@ func (d *PgDbsUsers) GetUserById(id uuid.UUID) (*User, error) {
 		&user.Email,
 		&user.Password.Hash,
 		&user.Role,
+		&user.ScoreScale,
 	)
 	if err != nil {
 		return nil, err
@@ -197,18 +212,48 @@ func (d *PgDbsUsers) GetUserById(id uuid.UUID) (*User, error) {
 	return user, nil
 }
 
-func (d *PgDbsUsers) AuthenticateWithJwt(jwt *tokens.Jwt) (*User, error) {
-	existingUser := &User{
+func (d *PgDbsUsers) UpdateSettings(user *User) (*User, error) {
+	if !ValidScoreScale(user.ScoreScale) {
+		log.Printf("error: DbsUsers UpdateSettings: %v", ErrInvalidScoreScale)
+		return nil, ErrInvalidScoreScale
+	}
+
+	updatedUser := &User{
 		Password: Password{},
 	}
 
-	existingJwt := &tokens.Jwt{
-		Token:        &tokens.Token{},
-		RefreshToken: &tokens.Token{},
+	query := `UPDATE users
+	SET updated_at = $2, score_scale = $3
+	WHERE id = $1
+	RETURNING id, created_at, updated_at, username, email, password_hash, role, score_scale`
+
+	err := d.db.Conn().QueryRow(
+		query,
+		user.Id,
+		time.Now(),
+		user.ScoreScale,
+	).Scan(
+		&updatedUser.Id,
+		&updatedUser.CreatedAt,
+		&updatedUser.UpdatedAt,
+		&updatedUser.Username,
+		&updatedUser.Email,
+		&updatedUser.Password.Hash,
+		&updatedUser.Role,
+		&updatedUser.ScoreScale,
+	)
+	if err != nil {
+		log.Printf("error: DbsUsers UpdateSettings: Scan: %v", err)
+		return nil, err
 	}
 
+	return updatedUser, nil
+}
+
+func (d *PgDbsUsers) GetUsers() ([]*User, error) {
 	tx, err := d.db.Conn().Begin()
 	if err != nil {
+		log.Printf("error: DbsUsers GetUsers: Conn: %v", err)
 		return nil, err
 	}
 	defer func() {
@@ -217,59 +262,294 @@ func (d *PgDbsUsers) AuthenticateWithJwt(jwt *tokens.Jwt) (*User, error) {
 			if err.Error() == "sql: transaction has already been committed or rolled back" {
 				return
 			}
-			log.Printf("error: dbsUsers AuthenticateByJwt Rollback: %v", err)
+			log.Printf("error: DbsUsers GetUsers: Rollback: %v", err)
 		}
 	}()
 
-	queryGetJwt := `SELECT * FROM jwt
-	WHERE token = $1`
+	dbUsers, err := SelectUsers(tx)
+	if err != nil {
+		log.Printf("error: DbsUsers GetUsers: SelectUsers: %v", err)
+		return nil, err
+	}
 
-	hashToValidate := tokens.HashFromPlainText(jwt.Token.PlainText)
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsUsers GetUsers: Commit: %v", err)
+		return nil, err
+	}
 
-	err = tx.QueryRow(
-		queryGetJwt,
-		hashToValidate[:],
+	return dbUsers, nil
+}
+
+// UpdateRole changes the role of user.Id. The last admin cannot be demoted,
+// the server would be left without anyone to manage it. Access tokens carry
+// the role, so a changed role signs the user out everywhere rather than
+// leave the old role in use until the tokens expire.
+func (d *PgDbsUsers) UpdateRole(user *User) (*User, error) {
+	if !auth.ValidRole(user.Role) {
+		log.Printf("error: DbsUsers UpdateRole: %v", ErrInvalidRole)
+		return nil, ErrInvalidRole
+	}
+
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsUsers UpdateRole: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsUsers UpdateRole: Rollback: %v", err)
+		}
+	}()
+
+	admins, err := SelectAdminIdsForUpdate(tx)
+	if err != nil {
+		log.Printf("error: DbsUsers UpdateRole: SelectAdminIdsForUpdate: %v", err)
+		return nil, err
+	}
+	if user.Role != auth.RoleAdmin && len(admins) == 1 && admins[0] == user.Id {
+		log.Printf("error: DbsUsers UpdateRole: %v", ErrLastAdmin)
+		return nil, ErrLastAdmin
+	}
+
+	role, err := SelectUserRole(tx, user.Id)
+	if err != nil {
+		log.Printf("error: DbsUsers UpdateRole: SelectUserRole: %v", err)
+		return nil, err
+	}
+
+	updatedUser, err := UpdateUserRole(tx, user)
+	if err != nil {
+		log.Printf("error: DbsUsers UpdateRole: UpdateUserRole: %v", err)
+		return nil, err
+	}
+
+	sessionIds := make([]uuid.UUID, 0)
+	if role != updatedUser.Role {
+		sessionIds, err = DeleteUserSessions(tx, user.Id)
+		if err != nil {
+			log.Printf("error: DbsUsers UpdateRole: DeleteUserSessions: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsUsers UpdateRole: Commit: %v", err)
+		return nil, err
+	}
+	d.dbsJwt.ForgetSessions(sessionIds...)
+
+	return updatedUser, nil
+}
+
+// BootstrapAdmin promotes the user with email while there is no admin at all.
+// It returns nil without error once an admin exists.
+func (d *PgDbsUsers) BootstrapAdmin(email string) (*User, error) {
+	tx, err := d.db.Conn().Begin()
+	if err != nil {
+		log.Printf("error: DbsUsers BootstrapAdmin: Conn: %v", err)
+		return nil, err
+	}
+	defer func() {
+		err := tx.Rollback()
+		if err != nil {
+			if err.Error() == "sql: transaction has already been committed or rolled back" {
+				return
+			}
+			log.Printf("error: DbsUsers BootstrapAdmin: Rollback: %v", err)
+		}
+	}()
+
+	admins, err := SelectAdminIdsForUpdate(tx)
+	if err != nil {
+		log.Printf("error: DbsUsers BootstrapAdmin: SelectAdminIdsForUpdate: %v", err)
+		return nil, err
+	}
+	if len(admins) > 0 {
+		return nil, nil
+	}
+
+	dbUser, err := UpdateUserRoleByEmail(tx, email, auth.RoleAdmin)
+	if err != nil {
+		log.Printf("error: DbsUsers BootstrapAdmin: UpdateUserRoleByEmail: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("error: DbsUsers BootstrapAdmin: Commit: %v", err)
+		return nil, err
+	}
+
+	return dbUser, nil
+}
+
+func SelectUsers(tx *sql.Tx) ([]*User, error) {
+	result := make([]*User, 0)
+
+	query := `SELECT id, created_at, updated_at, username, email, password_hash, role, score_scale FROM users
+	ORDER BY created_at ASC`
+
+	queryRows, err := tx.Query(query)
+	if err != nil {
+		log.Printf("error: Dbs: Users: SelectUsers: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		user := &User{
+			Password: Password{},
+		}
+		err := queryRows.Scan(
+			&user.Id,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.Username,
+			&user.Email,
+			&user.Password.Hash,
+			&user.Role,
+			&user.ScoreScale,
+		)
+		if err != nil {
+			log.Printf("error: Dbs: Users: SelectUsers: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, user)
+	}
+
+	return result, nil
+}
+
+// SelectAdminIdsForUpdate locks the admin rows so concurrent demotions cannot
+// both pass the last admin check.
+func SelectAdminIdsForUpdate(tx *sql.Tx) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0)
+
+	query := `SELECT id FROM users WHERE role = $1 FOR UPDATE`
+
+	queryRows, err := tx.Query(query, auth.RoleAdmin)
+	if err != nil {
+		log.Printf("error: Dbs: Users: SelectAdminIdsForUpdate: Query: %v", err)
+		return nil, err
+	}
+
+	for queryRows.Next() {
+		var id uuid.UUID
+		if err := queryRows.Scan(&id); err != nil {
+			log.Printf("error: Dbs: Users: SelectAdminIdsForUpdate: Scan: %v", err)
+			return nil, err
+		}
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+func UpdateUserRole(tx *sql.Tx, user *User) (*User, error) {
+	updatedUser := &User{
+		Password: Password{},
+	}
+
+	query := `UPDATE users
+	SET updated_at = $2, role = $3
+	WHERE id = $1
+	RETURNING id, created_at, updated_at, username, email, password_hash, role, score_scale`
+
+	err := tx.QueryRow(
+		query,
+		user.Id,
+		time.Now(),
+		user.Role,
 	).Scan(
-		&existingJwt.Id,
-		&existingJwt.CreatedAt,
-		&existingJwt.UpdatedAt,
-		&existingJwt.Token.Hash,
-		&existingJwt.RefreshToken.Hash,
-		&existingJwt.RefreshToken.Expiry,
-		&existingJwt.Scope,
-		&existingJwt.UserId,
+		&updatedUser.Id,
+		&updatedUser.CreatedAt,
+		&updatedUser.UpdatedAt,
+		&updatedUser.Username,
+		&updatedUser.Email,
+		&updatedUser.Password.Hash,
+		&updatedUser.Role,
+		&updatedUser.ScoreScale,
 	)
 	if err != nil {
+		log.Printf("error: Dbs: Users: UpdateUserRole: Scan: %v", err)
 		return nil, err
 	}
 
-	if !tokens.ValidateHash(existingJwt.Token.Hash, jwt.Token.PlainText) {
-		return nil, errors.New("error: dbsUsers AuthenticateByJwt: failed")
+	return updatedUser, nil
+}
+
+func UpdateUserRoleByEmail(tx *sql.Tx, email, role string) (*User, error) {
+	updatedUser := &User{
+		Password: Password{},
 	}
 
-	queryGetUser := `SELECT * FROM users
-	WHERE id = $1`
+	query := `UPDATE users
+	SET updated_at = $2, role = $3
+	WHERE email = $1
+	RETURNING id, created_at, updated_at, username, email, password_hash, role, score_scale`
 
-	err = tx.QueryRow(
-		queryGetUser,
-		existingJwt.UserId,
+	err := tx.QueryRow(
+		query,
+		email,
+		time.Now(),
+		role,
 	).Scan(
-		&existingUser.Id,
-		&existingUser.CreatedAt,
-		&existingUser.UpdatedAt,
-		&existingUser.Username,
-		&existingUser.Email,
-		&existingUser.Password.Hash,
-		&existingUser.Role,
+		&updatedUser.Id,
+		&updatedUser.CreatedAt,
+		&updatedUser.UpdatedAt,
+		&updatedUser.Username,
+		&updatedUser.Email,
+		&updatedUser.Password.Hash,
+		&updatedUser.Role,
+		&updatedUser.ScoreScale,
 	)
 	if err != nil {
+		log.Printf("error: Dbs: Users: UpdateUserRoleByEmail: Scan: %v", err)
 		return nil, err
 	}
 
-	err = tx.Commit()
+	return updatedUser, nil
+}
+
+func SelectScoreScale(tx *sql.Tx, reqUser *User) (int, error) {
+	var scale int
+
+	query := `SELECT score_scale FROM users WHERE id = $1`
+
+	err := tx.QueryRow(
+		query,
+		reqUser.Id,
+	).Scan(
+		&scale,
+	)
 	if err != nil {
-		return nil, err
+		log.Printf("error: Dbs: Users: SelectScoreScale: Scan: %v", err)
+		return 0, err
 	}
 
-	return existingUser, nil
+	return scale, nil
+}
+
+func SelectUserRole(tx *sql.Tx, userId uuid.UUID) (string, error) {
+	var role string
+
+	query := `SELECT role FROM users WHERE id = $1`
+
+	err := tx.QueryRow(
+		query,
+		userId,
+	).Scan(
+		&role,
+	)
+	if err != nil {
+		log.Printf("error: Dbs: Users: SelectUserRole: Scan: %v", err)
+		return "", err
+	}
+
+	return role, nil
 }
@@ -1,25 +1,32 @@
 package database
 
 import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JustinLi007/whatdoing-server/internal/algo"
 	"github.com/google/uuid"
 )
 
-func buildNamesMap(allNames []*AnimeAltName) map[uuid.UUID][]*AnimeName {
-	namesMap := make(map[uuid.UUID][]*AnimeName)
+func buildNamesMap(allNames []*ContentAltName) map[uuid.UUID][]*ContentName {
+	namesMap := make(map[uuid.UUID][]*ContentName)
 
 	for _, v := range allNames {
-		curId := v.AnimeId
+		curId := v.ContentId
 
 		_, ok := namesMap[curId]
 		if !ok {
-			namesMap[curId] = make([]*AnimeName, 0)
+			namesMap[curId] = make([]*ContentName, 0)
 		}
 
-		name := &AnimeName{
-			Id:        v.AnimeName.Id,
-			CreatedAt: v.AnimeName.CreatedAt,
-			UpdatedAt: v.AnimeName.UpdatedAt,
-			Name:      v.AnimeName.Name,
+		name := &ContentName{
+			Id:        v.ContentName.Id,
+			CreatedAt: v.ContentName.CreatedAt,
+			UpdatedAt: v.ContentName.UpdatedAt,
+			Name:      v.ContentName.Name,
+			Script:    v.ContentName.Script,
+			Language:  v.ContentName.Language,
 		}
 
 		namesMap[curId] = append(namesMap[curId], name)
@@ -27,3 +34,237 @@ func buildNamesMap(allNames []*AnimeAltName) map[uuid.UUID][]*AnimeName {
 
 	return namesMap
 }
+
+func buildGenresMap(allGenres []*ContentGenre) map[uuid.UUID][]string {
+	genresMap := make(map[uuid.UUID][]string)
+
+	for _, v := range allGenres {
+		genresMap[v.ContentId] = append(genresMap[v.ContentId], v.Genre.Name)
+	}
+
+	return genresMap
+}
+
+func buildTagsMap(allTags []*ContentTag) map[uuid.UUID][]string {
+	tagsMap := make(map[uuid.UUID][]string)
+
+	for _, v := range allTags {
+		tagsMap[v.ContentId] = append(tagsMap[v.ContentId], v.Tag.Name)
+	}
+
+	return tagsMap
+}
+
+func matchFacet(values []string, facet *Facet) bool {
+	have := make(map[string]bool)
+	for _, v := range values {
+		have[strings.ToLower(v)] = true
+	}
+
+	for _, v := range facet.Values {
+		if have[v] && !facet.MatchAll {
+			return true
+		}
+		if !have[v] && facet.MatchAll {
+			return false
+		}
+	}
+	return facet.MatchAll
+}
+
+func filterContentByFacets(content *Content, options *Options) bool {
+	if options.Genres != nil && !matchFacet(content.Genres, options.Genres) {
+		return false
+	}
+	if options.Tags != nil && !matchFacet(content.Tags, options.Tags) {
+		return false
+	}
+	return true
+}
+
+type FacetCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type Facets struct {
+	Genres []*FacetCount `json:"genres"`
+	Tags   []*FacetCount `json:"tags"`
+}
+
+// ContentFacets counts the genres and tags across contentList, most used
+// first.
+func ContentFacets(contentList []*Content) *Facets {
+	count := func(values func(*Content) []string) []*FacetCount {
+		counts := make(map[string]int)
+		for _, v := range contentList {
+			for _, name := range values(v) {
+				counts[name]++
+			}
+		}
+
+		result := make([]*FacetCount, 0, len(counts))
+		for name, n := range counts {
+			result = append(result, &FacetCount{
+				Name:  name,
+				Count: n,
+			})
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Count != result[j].Count {
+				return result[i].Count > result[j].Count
+			}
+			return result[i].Name < result[j].Name
+		})
+		return result
+	}
+
+	return &Facets{
+		Genres: count(func(c *Content) []string { return c.Genres }),
+		Tags:   count(func(c *Content) []string { return c.Tags }),
+	}
+}
+
+const (
+	MATCH_EXACT     = 1.0
+	MATCH_PREFIX    = 0.9
+	MATCH_SUBSTRING = 0.8
+	MATCH_FUZZY     = 0.7
+	// MATCH_ALT_NAME is taken off hits on an alternative name so the primary
+	// name wins within a tier without dropping into the next one.
+	MATCH_ALT_NAME = 0.05
+	// MATCH_ROMAJI is taken off hits found only by romanizing both sides.
+	MATCH_ROMAJI = 0.05
+	MATCH_EDITS  = 2
+)
+
+// Highlight marks the matched part of a name, Start inclusive and End
+// exclusive, counted in runes.
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type nameMatch struct {
+	score      float64
+	name       string
+	highlights []*Highlight
+}
+
+// matchName scores how well name matches the folded query: exact, then
+// prefix, then substring, then by edit distance. Failing that it compares the
+// romanized name with the romanized query, a tier lower. ok is false when
+// no rule matches. Highlights are mapped back onto the unfolded name.
+func matchName(name string, query string, romaji string) (*nameMatch, bool) {
+	folded, spans := algo.FoldSpans(name)
+	if match, ok := matchForm(folded, spans, query); ok {
+		match.name = name
+		return match, true
+	}
+
+	romanized, romajiSpans := algo.RomanizeSpans(folded)
+	for k, v := range romajiSpans {
+		romajiSpans[k] = algo.Span{
+			Start: spans[v.Start].Start,
+			End:   spans[v.End-1].End,
+		}
+	}
+	if match, ok := matchForm(romanized, romajiSpans, romaji); ok {
+		match.name = name
+		match.score -= MATCH_ROMAJI
+		return match, true
+	}
+
+	return nil, false
+}
+
+// matchForm matches query against form, one spelling of a name, with spans
+// mapping each rune of form onto the name.
+func matchForm(form string, spans []algo.Span, query string) (*nameMatch, bool) {
+	if query == "" || form == "" {
+		return nil, false
+	}
+
+	idx := algo.Kmp(form, query)
+	if idx != -1 {
+		queryLen := utf8.RuneCountInString(query)
+		match := &nameMatch{
+			highlights: []*Highlight{{
+				Start: spans[idx].Start,
+				End:   spans[idx+queryLen-1].End,
+			}},
+		}
+		switch {
+		case len(spans) == queryLen:
+			match.score = MATCH_EXACT
+		case idx == 0:
+			match.score = MATCH_PREFIX
+		default:
+			match.score = MATCH_SUBSTRING
+		}
+		return match, true
+	}
+
+	edits := algo.EditDistance(form, query)
+	if edits > MATCH_EDITS {
+		return nil, false
+	}
+	return &nameMatch{
+		score: MATCH_FUZZY - 0.1*float64(edits),
+	}, true
+}
+
+// rankContent scores every content against query by its best name, fills in
+// Score, MatchedName and Highlights, and sorts contentList by relevance. Ties
+// keep their current order. Content no rule matches, but the database search
+// did, keeps its database score below every ranked hit. The returned map
+// gives each content its position. Kanji have no romaji, a kanji title is
+// only found by romaji through a kana alternative name that reads it, which
+// then ranks like the title itself.
+func rankContent(contentList []*Content, query string) map[uuid.UUID]int {
+	query = algo.Fold(strings.TrimSpace(query))
+	romaji := algo.Romanize(query)
+
+	for _, v := range contentList {
+		best, ok := matchName(v.ContentName.Name, query, romaji)
+		for _, alt := range v.AlternativeNames {
+			match, altOk := matchName(alt.Name, query, romaji)
+			if !altOk {
+				continue
+			}
+			if !algo.IsReading(alt.Name, v.ContentName.Name) {
+				match.score -= MATCH_ALT_NAME
+			}
+			if !ok || match.score > best.score {
+				best, ok = match, true
+			}
+		}
+
+		if !ok {
+			score := 0.0
+			if v.Score != nil {
+				score = min(*v.Score, MATCH_FUZZY-0.1*MATCH_EDITS-MATCH_ALT_NAME-MATCH_ROMAJI) / 2
+			}
+			v.Score = &score
+			v.MatchedName = nil
+			v.Highlights = nil
+			continue
+		}
+
+		score := best.score
+		name := best.name
+		v.Score = &score
+		v.MatchedName = &name
+		v.Highlights = best.highlights
+	}
+
+	sort.SliceStable(contentList, func(i, j int) bool {
+		return *contentList[i].Score > *contentList[j].Score
+	})
+
+	ranks := make(map[uuid.UUID]int, len(contentList))
+	for k, v := range contentList {
+		ranks[v.Id] = k
+	}
+	return ranks
+}
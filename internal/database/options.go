@@ -1,8 +1,10 @@
 package database
 
 import (
+	"strconv"
 	"strings"
 
+	"github.com/JustinLi007/whatdoing-server/internal/algo"
 	"github.com/google/uuid"
 )
 
@@ -10,16 +12,27 @@ const (
 	STATUS_STARTED     = "started"
 	STATUS_NOT_STARTED = "not-started"
 	STATUS_COMPLETED   = "completed"
+	STATUS_PLANNED     = "planned"
+	STATUS_WATCHING    = "watching"
+	STATUS_ON_HOLD     = "on-hold"
+	STATUS_DROPPED     = "dropped"
+	STATUS_REWATCHING  = "rewatching"
 
 	SORT_ASC  = "ASC"
 	SORT_DESC = "DESC"
+
+	SORT_BY_NAME  = "name"
+	SORT_BY_SCORE = "score"
+
+	FACET_MATCH_ALL = "all"
+	FACET_MATCH_ANY = "any"
 )
 
 type ProgressId struct {
 	Id uuid.UUID
 }
 
-type AnimeId struct {
+type ContentId struct {
 	Id uuid.UUID
 }
 
@@ -29,28 +42,67 @@ type ProgressStatus struct {
 
 type Search struct {
 	SearchValue string
+	RomajiValue string
+}
+
+// searchArgs returns the search_content arguments for search, both nil
+// without one.
+func searchArgs(search *Search) (*string, *string) {
+	if search == nil {
+		return nil, nil
+	}
+	return &search.SearchValue, &search.RomajiValue
 }
 
 type Sort struct {
 	SortValue string
 }
 
+type Kind struct {
+	KindValue string
+}
+
+type Score struct {
+	ScoreValue int
+}
+
+type SortBy struct {
+	SortByValue string
+}
+
+type Facet struct {
+	Values   []string
+	MatchAll bool
+}
+
 type Options struct {
 	ProgressId      *ProgressId
-	AnimeId         *AnimeId
+	ContentId       *ContentId
 	Status          *ProgressStatus
 	Search          *Search
 	Sort            *Sort
+	Kind            *Kind
+	MinScore        *Score
+	MaxScore        *Score
+	SortBy          *SortBy
+	Genres          *Facet
+	Tags            *Facet
 	IgnoreInLibrary bool
 }
 
 func NewOptions() *Options {
 	options := &Options{
 		ProgressId:      nil,
-		AnimeId:         nil,
+		ContentId:       nil,
 		Status:          nil,
 		Search:          nil,
 		Sort:            nil,
+		Kind:            nil,
+		MinScore:        nil,
+		MaxScore:        nil,
+		SortBy:          nil,
+		Genres:          nil,
+		Tags:            nil,
 		IgnoreInLibrary: false,
 	}
 	return options
@@ -66,9 +118,9 @@ func WithProgressId(id uuid.UUID) OptionsFunc {
 	}
 }
 
-func WithAnimeId(id uuid.UUID) OptionsFunc {
+func WithContentId(id uuid.UUID) OptionsFunc {
 	return func(o *Options) {
-		o.AnimeId = &AnimeId{
+		o.ContentId = &ContentId{
 			Id: id,
 		}
 	}
@@ -90,6 +142,10 @@ func WithStatus(value string) OptionsFunc {
 			o.Status = &ProgressStatus{
 				StatusValue: STATUS_COMPLETED,
 			}
+		case STATUS_PLANNED, STATUS_WATCHING, STATUS_ON_HOLD, STATUS_DROPPED, STATUS_REWATCHING:
+			o.Status = &ProgressStatus{
+				StatusValue: status_value,
+			}
 		default:
 			// nothing
 		}
@@ -109,14 +165,15 @@ func WithIgnore(value string) OptionsFunc {
 }
 
 func WithSearch(value string) OptionsFunc {
-	search_value := strings.ToLower(strings.TrimSpace(value))
+	search_value := algo.Fold(strings.TrimSpace(value))
 	return func(o *Options) {
 		if search_value == "" {
 			return
 		}
 
 		o.Search = &Search{
-			SearchValue: value,
+			SearchValue: search_value,
+			RomajiValue: algo.Romanize(search_value),
 		}
 	}
 }
@@ -138,3 +195,100 @@ func WithSort(value string) OptionsFunc {
 		}
 	}
 }
+
+func WithKind(value string) OptionsFunc {
+	kind_value := strings.ToLower(strings.TrimSpace(value))
+	return func(o *Options) {
+		if !ValidKind(kind_value) {
+			return
+		}
+
+		o.Kind = &Kind{
+			KindValue: kind_value,
+		}
+	}
+}
+
+// WithMinScore and WithMaxScore filter on the score in the requesting user's
+// score scale.
+func WithMinScore(value string) OptionsFunc {
+	score_value, err := strconv.Atoi(strings.TrimSpace(value))
+	return func(o *Options) {
+		if err != nil {
+			return
+		}
+
+		o.MinScore = &Score{
+			ScoreValue: score_value,
+		}
+	}
+}
+
+func WithMaxScore(value string) OptionsFunc {
+	score_value, err := strconv.Atoi(strings.TrimSpace(value))
+	return func(o *Options) {
+		if err != nil {
+			return
+		}
+
+		o.MaxScore = &Score{
+			ScoreValue: score_value,
+		}
+	}
+}
+
+func WithSortBy(value string) OptionsFunc {
+	sort_by_value := strings.ToLower(strings.TrimSpace(value))
+	return func(o *Options) {
+		switch sort_by_value {
+		case SORT_BY_NAME, SORT_BY_SCORE:
+			o.SortBy = &SortBy{
+				SortByValue: sort_by_value,
+			}
+		default:
+			// nothing
+		}
+	}
+}
+
+// WithGenres and WithTags keep entries carrying every value, or any of them
+// when mode is FACET_MATCH_ANY.
+func WithGenres(values []string, mode string) OptionsFunc {
+	facet := newFacet(values, mode)
+	return func(o *Options) {
+		if facet == nil {
+			return
+		}
+
+		o.Genres = facet
+	}
+}
+
+func WithTags(values []string, mode string) OptionsFunc {
+	facet := newFacet(values, mode)
+	return func(o *Options) {
+		if facet == nil {
+			return
+		}
+
+		o.Tags = facet
+	}
+}
+
+func newFacet(values []string, mode string) *Facet {
+	facet_values := make([]string, 0)
+	for _, v := range values {
+		facet_value := strings.ToLower(strings.TrimSpace(v))
+		if facet_value != "" {
+			facet_values = append(facet_values, facet_value)
+		}
+	}
+	if len(facet_values) == 0 {
+		return nil
+	}
+
+	return &Facet{
+		Values:   facet_values,
+		MatchAll: strings.ToLower(strings.TrimSpace(mode)) != FACET_MATCH_ANY,
+	}
+}
@@ -3,15 +3,20 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
 	"github.com/JustinLi007/whatdoing-server/internal/database"
 	"github.com/JustinLi007/whatdoing-server/internal/tokens"
 	"github.com/JustinLi007/whatdoing-server/internal/utils"
+	"github.com/google/uuid"
 )
 
 type Middleware struct {
-	dbsUsers database.DbsUsers
-	dbsJwt   database.DbsJwt
+	dbsUsers          database.DbsUsers
+	dbsJwt            database.DbsJwt
+	dbsPersonalTokens database.DbsPersonalTokens
+	keys              *tokens.Keys
 }
 
 var allowedOrigins = map[string]bool{
@@ -20,14 +25,16 @@ var allowedOrigins = map[string]bool{
 
 var middlewareInstance *Middleware
 
-func NewMiddleware(dbsUsers database.DbsUsers, dbsJwt database.DbsJwt) *Middleware {
+func NewMiddleware(dbsUsers database.DbsUsers, dbsJwt database.DbsJwt, dbsPersonalTokens database.DbsPersonalTokens, keys *tokens.Keys) *Middleware {
 	if middlewareInstance != nil {
 		return middlewareInstance
 	}
 
 	newMiddleware := &Middleware{
-		dbsUsers: dbsUsers,
-		dbsJwt:   dbsJwt,
+		dbsUsers:          dbsUsers,
+		dbsJwt:            dbsJwt,
+		dbsPersonalTokens: dbsPersonalTokens,
+		keys:              keys,
 	}
 	middlewareInstance = newMiddleware
 
@@ -56,42 +63,139 @@ func (m *Middleware) Cors(next http.Handler) http.Handler {
 	})
 }
 
+// RequireJwt identifies the caller by the Authorization bearer token, falling
+// back to the session cookie. Callers that cannot be identified are anonymous.
 func (m *Middleware) RequireJwt(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		r = utils.SetUser(r, database.AnonymousUser)
 
-		cookie, err := r.Cookie("whatdoing-jwt")
+		plainText, ok := bearerToken(r)
+		if !ok {
+			cookie, err := r.Cookie("whatdoing-jwt")
+			if err != nil {
+				log.Printf("error: Middleware: RequireJwt: Cookie: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err = cookie.Valid()
+			if err != nil {
+				log.Printf("error: Middleware: RequireJwt: Cookie: Valid: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			plainText = cookie.Value
+		}
+
+		if tokens.IsPersonalToken(plainText) {
+			user, personalToken, err := m.dbsPersonalTokens.Authenticate(plainText)
+			if err != nil {
+				log.Printf("error: Middleware: RequireJwt: Authenticate: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims := &tokens.Claims{
+				Subject: user.Id.String(),
+				Scope:   strings.Join(personalToken.Scopes, " "),
+				Role:    user.Role,
+				Id:      personalToken.Id.String(),
+			}
+			r = utils.SetUser(r, user)
+			r = utils.SetClaims(r, claims)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := m.keys.Verify(plainText)
 		if err != nil {
-			log.Printf("error: Middleware: RequireJwt: Cookie: %v", err)
+			log.Printf("error: Middleware: RequireJwt: Verify: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if claims.Scope != tokens.ScopeAuthenticate {
+			log.Printf("error: Middleware: RequireJwt: unexpected scope: %v", claims.Scope)
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		err = cookie.Valid()
+		userId, err := uuid.Parse(claims.Subject)
 		if err != nil {
-			log.Printf("error: Middleware: RequireJwt: Cookie: Valid: %v", err)
+			log.Printf("error: Middleware: RequireJwt: Parse: %v", err)
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		jwtValidate := &tokens.Jwt{
-			Token: &tokens.Token{
-				PlainText: cookie.Value,
-			},
+		// the signature proves the token was issued, the session shows it
+		// was not revoked since
+		sessionId, err := uuid.Parse(claims.SessionId)
+		if err != nil {
+			log.Printf("error: Middleware: RequireJwt: Parse: %v", err)
+			next.ServeHTTP(w, r)
+			return
 		}
-		user, err := m.dbsUsers.AuthenticateWithJwt(jwtValidate)
+		active, err := m.dbsJwt.SessionActive(sessionId)
 		if err != nil {
-			log.Printf("error: Middleware: RequireJwt: AuthenticateWithJwt: %v", err)
+			log.Printf("error: Middleware: RequireJwt: SessionActive: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !active {
+			log.Printf("error: Middleware: RequireJwt: session revoked: %v", sessionId)
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// only the id and role are known here, handlers that need more of
+		// the user load it with GetUserById
+		user := &database.User{
+			Id:   userId,
+			Role: claims.Role,
+		}
 		r = utils.SetUser(r, user)
+		r = utils.SetClaims(r, claims)
 		next.ServeHTTP(w, r)
 		return
 	})
 }
 
+// RequireScope rejects callers whose token was not granted scope. Anonymous
+// callers have no token and are left to RequireUser.
+func (m *Middleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := utils.GetClaims(r)
+			if claims != nil && !tokens.HasScope(claims.Scope, scope) {
+				log.Printf("error: Middleware: RequireScope: missing scope: %v", scope)
+				utils.WriteJson(w, http.StatusForbidden, utils.Envelope{
+					"error": "token missing scope " + scope,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole rejects users whose role does not grant perm. The role comes
+// from the token, so a changed role applies once the token is refreshed.
+func (m *Middleware) RequireRole(perm auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := utils.GetUser(r)
+			if user == nil || !auth.Can(user.Role, perm) {
+				log.Printf("error: Middleware: RequireRole: missing permission: %v", perm)
+				utils.WriteJson(w, http.StatusForbidden, utils.Envelope{
+					"error": "forbidden",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (m *Middleware) RequireUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := utils.GetUser(r)
@@ -105,3 +209,12 @@ func (m *Middleware) RequireUser(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	plainText, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || plainText == "" {
+		return "", false
+	}
+	return plainText, true
+}
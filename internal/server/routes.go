@@ -1,6 +1,8 @@
 package server
 
 import (
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
+	"github.com/JustinLi007/whatdoing-server/internal/tokens"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -13,43 +15,112 @@ func (s *Server) RegisterRoutes() *chi.Mux {
 	r.Group(func(r chi.Router) {
 		r.Use(s.middleware.RequireJwt)
 		r.Use(s.middleware.RequireUser)
+		r.Use(s.middleware.RequireScope(tokens.ScopeAuthenticate))
 		r.Get("/users/session", s.handlerUsers.CheckSession)
 		r.Delete("/users/session", s.handlerUsers.Logout)
+		r.Put("/users/settings", s.handlerUsers.UpdateSettings)
+		r.Get("/users/sessions", s.handlerUsers.GetSessions)
+		r.Delete("/users/sessions", s.handlerUsers.RevokeOtherSessions)
+		r.Delete("/users/sessions/{sessionId}", s.handlerUsers.RevokeSession)
+		r.Get("/users/tokens", s.handlerPersonalTokens.GetTokens)
+		r.Post("/users/tokens", s.handlerPersonalTokens.CreateToken)
+		r.Delete("/users/tokens/{tokenId}", s.handlerPersonalTokens.DeleteToken)
+		r.Post("/device/approve", s.handlerDevice.Approve)
 	})
 
+	r.Post("/tokens/refresh", s.handlerJwt.RefreshJwt)
+	r.Post("/device/code", s.handlerDevice.RequestCode)
+	r.Post("/device/token", s.handlerDevice.Token)
+
+	r.Group(func(r chi.Router) {
+		r.Use(s.middleware.RequireJwt)
+		r.Use(s.middleware.RequireScope(tokens.ScopeCatalogRead))
+		r.Get("/content", s.handlerContent.GetAllContent)
+		r.Get("/content/{contentId}", s.handlerContent.GetContent)
+		r.Get("/content/{contentId}/revisions", s.handlerContentRevisions.GetRevisions)
+		r.Get("/search/suggest", s.handlerSearch.Suggest)
+	})
 	r.Group(func(r chi.Router) {
 		r.Use(s.middleware.RequireJwt)
 		r.Use(s.middleware.RequireUser)
-		r.Post("/tokens/refresh", s.handlerJwt.RefreshJwt)
+		r.Use(s.middleware.RequireScope(tokens.ScopeCatalogWrite))
+		r.With(s.middleware.RequireRole(auth.PermContentCreate)).Post("/content", s.handlerContent.NewContent)
+		r.Put("/content/{contentId}", s.handlerContent.UpdateContent)
+		r.Delete("/content", s.handlerContent.DeleteContent)
+		r.With(s.middleware.RequireRole(auth.PermContentEdit)).Put("/content/{contentId}/genres", s.handlerGenres.SetContentGenres)
+		r.With(s.middleware.RequireRole(auth.PermContentCreate)).Post("/altname/content", s.handlerContentAltNames.AddAltName)
+		r.Delete("/altname/content", s.handlerContentAltNames.DeleteAltNames)
+		r.With(s.middleware.RequireRole(auth.PermContentClaim)).Post("/content/{contentId}/claim", s.handlerContent.ClaimContent)
+		r.With(s.middleware.RequireRole(auth.PermContentMerge)).Post("/content/{contentId}/merge", s.handlerContentReviews.MergeDuplicate)
+		r.With(s.middleware.RequireRole(auth.PermContentRevert)).Post("/content/{contentId}/revisions/{revisionId}/revert", s.handlerContentRevisions.Revert)
 	})
-
-	r.Get("/anime/{contentId}", s.handlerAnime.GetAnime)
 	r.Group(func(r chi.Router) {
 		r.Use(s.middleware.RequireJwt)
-		r.Get("/anime", s.handlerAnime.GetAllAnime)
+		r.Use(s.middleware.RequireUser)
+		r.Use(s.middleware.RequireScope(tokens.ScopeCatalogWrite))
+		r.Use(s.middleware.RequireRole(auth.PermContentReview))
+		r.Get("/moderation/content", s.handlerContentReviews.GetQueue)
+		r.Post("/moderation/content/{contentId}/approve", s.handlerContentReviews.Approve)
+		r.Post("/moderation/content/{contentId}/reject", s.handlerContentReviews.Reject)
+		r.Post("/moderation/content/{contentId}/merge", s.handlerContentReviews.Merge)
 	})
+
 	r.Group(func(r chi.Router) {
 		r.Use(s.middleware.RequireJwt)
 		r.Use(s.middleware.RequireUser)
-		r.Post("/anime", s.handlerAnime.NewAnime)
-		r.Put("/anime/{contentId}", s.handlerAnime.UpdateAnime)
-		r.Delete("/anime", s.handlerAnime.DeleteAnime)
+		r.Use(s.middleware.RequireScope(tokens.ScopeAuthenticate))
+		r.Use(s.middleware.RequireRole(auth.PermUsersManage))
+		r.Get("/admin/users", s.handlerUsers.GetUsers)
+		r.Put("/admin/users/{userId}/role", s.handlerUsers.SetRole)
 	})
 
+	r.Get("/genres", s.handlerGenres.GetAllGenres)
+
 	r.Group(func(r chi.Router) {
 		r.Use(s.middleware.RequireJwt)
 		r.Use(s.middleware.RequireUser)
-		r.Post("/altname/anime", s.handlerAnimeAltNames.AddAltName)
-		r.Delete("/altname/anime", s.handlerAnimeAltNames.DeleteAltNames)
+		r.Use(s.middleware.RequireScope(tokens.ScopeProgressRead))
+		r.Get("/tags", s.handlerTags.GetAllTags)
+		r.Get("/progress/content", s.handlerProgressContent.GetProgress)
+		r.Get("/progress/content/{progressId}/history", s.handlerProgressContent.GetHistory)
+		r.Get("/next", s.handlerProgressContent.Next)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(s.middleware.RequireJwt)
+		r.Use(s.middleware.RequireUser)
+		r.Use(s.middleware.RequireScope(tokens.ScopeProgressWrite))
+		r.Put("/tags/content", s.handlerTags.SetContentTags)
+		r.Post("/progress/content", s.handlerProgressContent.AddToLibrary)
+		r.Put("/progress/content", s.handlerProgressContent.SetProgress)
+		r.Put("/progress/content/status", s.handlerProgressContent.SetStatus)
+		r.Put("/progress/content/review", s.handlerProgressContent.SetReview)
+		r.Put("/progress/content/dates", s.handlerProgressContent.SetDates)
+		r.Put("/progress/content/priority", s.handlerProgressContent.SetPriority)
+		r.Delete("/progress/content", s.handlerProgressContent.RemoveProgress)
+		r.Post("/progress/undo", s.handlerProgressContent.Undo)
 	})
 
+	r.Group(func(r chi.Router) {
+		r.Use(s.middleware.RequireJwt)
+		r.Use(s.middleware.RequireScope(tokens.ScopeListsRead))
+		r.Get("/lists/{listId}", s.handlerLists.GetList)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(s.middleware.RequireJwt)
+		r.Use(s.middleware.RequireUser)
+		r.Use(s.middleware.RequireScope(tokens.ScopeListsRead))
+		r.Get("/lists", s.handlerLists.GetLists)
+	})
 	r.Group(func(r chi.Router) {
 		r.Use(s.middleware.RequireJwt)
 		r.Use(s.middleware.RequireUser)
-		r.Post("/progress/anime", s.handlerProgressAnime.AddToLibrary)
-		r.Put("/progress/anime", s.handlerProgressAnime.SetProgress)
-		r.Delete("/progress/anime", s.handlerProgressAnime.RemoveProgress)
-		r.Get("/progress/anime", s.handlerProgressAnime.GetProgress)
+		r.Use(s.middleware.RequireScope(tokens.ScopeListsWrite))
+		r.Post("/lists", s.handlerLists.CreateList)
+		r.Put("/lists/{listId}", s.handlerLists.UpdateList)
+		r.Delete("/lists/{listId}", s.handlerLists.DeleteList)
+		r.Post("/lists/{listId}/items", s.handlerLists.AddItem)
+		r.Put("/lists/{listId}/items/{itemId}", s.handlerLists.MoveItem)
+		r.Delete("/lists/{listId}/items/{itemId}", s.handlerLists.RemoveItem)
 	})
 
 	return r
@@ -6,23 +6,34 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/JustinLi007/whatdoing-server/internal/api"
+	"github.com/JustinLi007/whatdoing-server/internal/auth"
 	"github.com/JustinLi007/whatdoing-server/internal/database"
 	"github.com/JustinLi007/whatdoing-server/internal/middleware"
+	"github.com/JustinLi007/whatdoing-server/internal/tokens"
 	"github.com/JustinLi007/whatdoing-server/migrations"
 )
 
 type Server struct {
-	port                 int
-	db                   database.DbService
-	middleware           *middleware.Middleware
-	handlerUsers         api.HandlerUsers
-	handlerJwt           api.HandlerJwt
-	handlerAnime         api.HandlerAnime
-	handlerAnimeAltNames api.HandlerAnimeAltNames
-	handlerProgressAnime api.HandlerProgressAnime
+	port                    int
+	db                      database.DbService
+	middleware              *middleware.Middleware
+	handlerUsers            api.HandlerUsers
+	handlerJwt              api.HandlerJwt
+	handlerContent          api.HandlerContent
+	handlerContentAltNames  api.HandlerContentAltNames
+	handlerContentRevisions api.HandlerContentRevisions
+	handlerContentReviews   api.HandlerContentReviews
+	handlerProgressContent  api.HandlerProgressContent
+	handlerGenres           api.HandlerGenres
+	handlerTags             api.HandlerTags
+	handlerLists            api.HandlerLists
+	handlerPersonalTokens   api.HandlerPersonalTokens
+	handlerDevice           api.HandlerDevice
+	handlerSearch           api.HandlerSearch
 }
 
 func NewServer(ctx context.Context) *http.Server {
@@ -36,34 +47,71 @@ func NewServer(ctx context.Context) *http.Server {
 		log.Fatalf("error: Server NewServer MigrateFS: %v", err)
 	}
 
+	keys, err := tokens.LoadKeys()
+	if err != nil {
+		log.Fatalf("error: Server NewServer LoadKeys: %v", err)
+	}
+
 	// dbs
-	dbsUsers := database.NewDbsUsers(db)
-	dbsJwt := database.NewDbsJwt(db)
-	dbsAnime := database.NewDbsAnime(db)
-	dbsRelUsersAnime := database.NewDbsUsersAnime(db)
-	dbsAnimeAltNames := database.NewDbsAnimeAltNames(db)
+	dbsJwt := database.NewDbsJwt(db, keys)
+	dbsUsers := database.NewDbsUsers(db, dbsJwt)
+	dbsSuggestions := database.NewDbsSuggestions(db)
+	dbsContent := database.NewDbsContent(db, dbsSuggestions)
+	dbsRelUsersContent := database.NewDbsUsersContent(db)
+	dbsContentAltNames := database.NewDbsContentAltNames(db, dbsSuggestions)
+	dbsContentRevisions := database.NewDbsContentRevisions(db, dbsSuggestions)
+	dbsContentReviews := database.NewDbsContentReviews(db, dbsSuggestions)
 	dbsUserLibrary := database.NewDbsUserLibrary(db)
-	dbsProgressAnime := database.NewDbsProgressAnime(db)
+	dbsProgressContent := database.NewDbsProgressContent(db)
+	dbsGenres := database.NewDbsGenres(db)
+	dbsTags := database.NewDbsTags(db)
+	dbsLists := database.NewDbsLists(db)
+	dbsPersonalTokens := database.NewDbsPersonalTokens(db)
+	dbsDeviceCodes := database.NewDbsDeviceCodes(db)
+
+	if err := dbsSuggestions.Load(); err != nil {
+		log.Fatalf("error: Server NewServer Load: %v", err)
+	}
+
+	if email := os.Getenv(auth.EnvAdminEmail); email != "" {
+		BootstrapAdmin(dbsUsers, email)
+	}
 
 	// handlers
 	handlerUsers := api.NewHandlerUsers(dbsUsers, dbsJwt)
 	handlerJwt := api.NewHandlerJwt(dbsJwt)
-	handlerAnime := api.NewHandlerAnime(dbsAnime, dbsRelUsersAnime)
-	handlerAnimeAltNames := api.NewHandlerAnimeAltNames(dbsAnimeAltNames)
-	handlerProgressAnime := api.NewHandlerProgressAnime(dbsUserLibrary, dbsProgressAnime)
+	handlerContent := api.NewHandlerContent(dbsContent, dbsRelUsersContent)
+	handlerContentAltNames := api.NewHandlerContentAltNames(dbsContentAltNames, dbsRelUsersContent)
+	handlerContentRevisions := api.NewHandlerContentRevisions(dbsContentRevisions)
+	handlerContentReviews := api.NewHandlerContentReviews(dbsContentReviews)
+	handlerProgressContent := api.NewHandlerProgressContent(dbsUserLibrary, dbsProgressContent)
+	handlerGenres := api.NewHandlerGenres(dbsGenres)
+	handlerTags := api.NewHandlerTags(dbsTags)
+	handlerLists := api.NewHandlerLists(dbsLists)
+	handlerPersonalTokens := api.NewHandlerPersonalTokens(dbsPersonalTokens)
+	handlerDevice := api.NewHandlerDevice(dbsDeviceCodes, dbsJwt)
+	handlerSearch := api.NewHandlerSearch(dbsSuggestions)
 
 	// middleware
-	middleware := middleware.NewMiddleware(dbsUsers, dbsJwt)
+	middleware := middleware.NewMiddleware(dbsUsers, dbsJwt, dbsPersonalTokens, keys)
 
 	newServer := Server{
-		port:                 8000,
-		db:                   db,
-		middleware:           middleware,
-		handlerUsers:         handlerUsers,
-		handlerJwt:           handlerJwt,
-		handlerAnime:         handlerAnime,
-		handlerAnimeAltNames: handlerAnimeAltNames,
-		handlerProgressAnime: handlerProgressAnime,
+		port:                    8000,
+		db:                      db,
+		middleware:              middleware,
+		handlerUsers:            handlerUsers,
+		handlerJwt:              handlerJwt,
+		handlerContent:          handlerContent,
+		handlerContentAltNames:  handlerContentAltNames,
+		handlerContentRevisions: handlerContentRevisions,
+		handlerContentReviews:   handlerContentReviews,
+		handlerProgressContent:  handlerProgressContent,
+		handlerGenres:           handlerGenres,
+		handlerTags:             handlerTags,
+		handlerLists:            handlerLists,
+		handlerPersonalTokens:   handlerPersonalTokens,
+		handlerDevice:           handlerDevice,
+		handlerSearch:           handlerSearch,
 	}
 
 	mux := newServer.RegisterRoutes()
@@ -77,10 +125,27 @@ func NewServer(ctx context.Context) *http.Server {
 	}
 
 	go RoutineRemoveExpiredJwt(ctx, dbsJwt)
+	go RoutineRemoveExpiredDeviceCodes(ctx, dbsDeviceCodes)
 
 	return server
 }
 
+// BootstrapAdmin makes the first admin. The account must already exist, so a
+// fresh server is set up by signing up and restarting with the email set.
+func BootstrapAdmin(dbsUsers database.DbsUsers, email string) {
+	dbUser, err := dbsUsers.BootstrapAdmin(email)
+	if err == sql.ErrNoRows {
+		log.Printf("warning: Server: BootstrapAdmin: no user with email %v, sign up and restart", email)
+		return
+	} else if err != nil {
+		log.Printf("error: Server: BootstrapAdmin: %v", err)
+		return
+	}
+	if dbUser != nil {
+		log.Printf("info: Server: BootstrapAdmin: %v is now admin", dbUser.Email)
+	}
+}
+
 func RoutineRemoveExpiredJwt(ctx context.Context, dbsJwt database.DbsJwt) {
 	ticker := time.NewTicker(time.Hour)
 	for {
@@ -98,3 +163,21 @@ func RoutineRemoveExpiredJwt(ctx context.Context, dbsJwt database.DbsJwt) {
 		}
 	}
 }
+
+func RoutineRemoveExpiredDeviceCodes(ctx context.Context, dbsDeviceCodes database.DbsDeviceCodes) {
+	ticker := time.NewTicker(time.Hour)
+	for {
+		select {
+		case <-ticker.C:
+			log.Printf("info: Server: Routine: RoutineRemoveExpiredDeviceCodes: Execute")
+			err := dbsDeviceCodes.DeleteExpired()
+			if err != nil {
+				log.Printf("error: Server: Routine: RoutineRemoveExpiredDeviceCodes: DeleteExpired: %v", err)
+			}
+		case <-ctx.Done():
+			log.Printf("info: Server: Routine: RoutineRemoveExpiredDeviceCodes: Stop")
+			ticker.Stop()
+			return
+		}
+	}
+}
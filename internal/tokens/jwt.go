@@ -1,10 +1,16 @@
 package tokens
 
 import (
+	"crypto/ed25519"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,16 +18,35 @@ import (
 
 const (
 	ScopeAuthenticate = "authentication"
+
+	Issuer = "whatdoing"
+
+	// PersonalTokenPrefix tells personal access tokens apart from jwts in the
+	// Authorization header.
+	PersonalTokenPrefix = "wdp_"
+
+	// access tokens are checked against their session on use, so logging out
+	// or revoking the session ends them before they expire
+	TtlToken   = time.Minute * 15
+	TtlRefresh = time.Hour * 24
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("expired token")
 )
 
 type Jwt struct {
-	Id           uuid.UUID `json:"-"`
-	CreatedAt    time.Time `json:"-"`
-	UpdatedAt    time.Time `json:"-"`
-	Token        *Token    `json:"token"`
-	RefreshToken *Token    `json:"refresh_token"`
-	UserId       uuid.UUID `json:"-"`
-	Scope        string    `json:"-"`
+	Id           uuid.UUID  `json:"-"`
+	CreatedAt    time.Time  `json:"-"`
+	UpdatedAt    time.Time  `json:"-"`
+	Jti          uuid.UUID  `json:"-"`
+	FamilyId     uuid.UUID  `json:"-"`
+	RotatedAt    *time.Time `json:"-"`
+	Token        *Token     `json:"token"`
+	RefreshToken *Token     `json:"refresh_token"`
+	UserId       uuid.UUID  `json:"-"`
+	Scope        string     `json:"-"`
 }
 
 type Token struct {
@@ -30,13 +55,34 @@ type Token struct {
 	Expiry    time.Time `json:"expiry"`
 }
 
-func GenerateJwt(userId uuid.UUID, ttl_token, ttl_refresh time.Duration, scope string) (*Jwt, error) {
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Scope     string `json:"scope"`
+	Role      string `json:"role"`
+	SessionId string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Id        string `json:"jti"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// GenerateJwt signs a new access token for the user and pairs it with an
+// opaque refresh token, only the latter is meant to be stored.
+func GenerateJwt(keys *Keys, userId, sessionId uuid.UUID, role string, ttl_token, ttl_refresh time.Duration, scope string) (*Jwt, error) {
 	jwt := &Jwt{
-		UserId: userId,
-		Scope:  scope,
+		Jti:      uuid.New(),
+		FamilyId: sessionId,
+		UserId:   userId,
+		Scope:    scope,
 	}
 
-	token, err := GenerateToken(ttl_token)
+	token, err := GenerateAccessToken(keys, jwt.Jti, userId, sessionId, role, ttl_token, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +97,124 @@ func GenerateJwt(userId uuid.UUID, ttl_token, ttl_refresh time.Duration, scope s
 	return jwt, nil
 }
 
+func GenerateAccessToken(keys *Keys, jti, userId, sessionId uuid.UUID, role string, ttl time.Duration, scope string) (*Token, error) {
+	now := time.Now()
+	token := &Token{
+		Expiry: now.Add(ttl),
+	}
+
+	claims := &Claims{
+		Issuer:    Issuer,
+		Subject:   userId.String(),
+		Scope:     scope,
+		Role:      role,
+		SessionId: sessionId.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: token.Expiry.Unix(),
+		Id:        jti.String(),
+	}
+	signed, err := keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	token.PlainText = signed
+
+	return token, nil
+}
+
+func (k *Keys) Sign(claims *Claims) (string, error) {
+	headerJson, err := json.Marshal(&header{
+		Alg: k.signing.Alg,
+		Typ: "JWT",
+		Kid: k.signing.Id,
+	})
+	if err != nil {
+		return "", err
+	}
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJson) + "." + base64.RawURLEncoding.EncodeToString(claimsJson)
+
+	var signature []byte
+	switch k.signing.Alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.signing.secret)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case AlgEdDSA:
+		signature = ed25519.Sign(k.signing.privateKey, []byte(signingInput))
+	default:
+		return "", ErrUnknownKey
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature against the key named by kid and that the token
+// has not expired. The alg in the header must match the key's own alg.
+func (k *Keys) Verify(plainText string) (*Claims, error) {
+	parts := strings.Split(plainText, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var h header
+	if err := json.Unmarshal(headerJson, &h); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := k.keys[h.Kid]
+	if !ok || key.Alg != h.Alg {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	switch key.Alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, ErrInvalidToken
+		}
+	case AlgEdDSA:
+		if !ed25519.Verify(key.publicKey, []byte(signingInput), signature) {
+			return nil, ErrInvalidToken
+		}
+	default:
+		return nil, ErrInvalidToken
+	}
+
+	claimsJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(claimsJson, claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Issuer != Issuer {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
 func GenerateToken(ttl time.Duration) (*Token, error) {
 	token := &Token{
 		Expiry: time.Now().Add(ttl),
@@ -69,6 +233,27 @@ func GenerateToken(ttl time.Duration) (*Token, error) {
 	return token, nil
 }
 
+func GeneratePersonalToken(ttl *time.Duration) (*Token, error) {
+	token, err := GenerateToken(0)
+	if err != nil {
+		return nil, err
+	}
+
+	token.PlainText = PersonalTokenPrefix + token.PlainText
+	hash := HashFromPlainText(token.PlainText)
+	token.Hash = hash[:]
+	token.Expiry = time.Time{}
+	if ttl != nil {
+		token.Expiry = time.Now().Add(*ttl)
+	}
+
+	return token, nil
+}
+
+func IsPersonalToken(plainText string) bool {
+	return strings.HasPrefix(plainText, PersonalTokenPrefix)
+}
+
 func ValidateHash(secret []byte, plainText string) bool {
 	hash := HashFromPlainText(plainText)
 	if len(secret) == len(hash) && subtle.ConstantTimeCompare(secret, hash[:]) == 1 {
@@ -81,3 +266,45 @@ func HashFromPlainText(plainText string) [32]byte {
 	hash := sha256.Sum256([]byte(plainText))
 	return hash
 }
+
+// userCodeAlphabet leaves out vowels and look-alike characters so codes are
+// easy to type and never spell words.
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const UserCodeLength = 8
+
+func GenerateUserCode() (string, error) {
+	// bytes past the last full multiple of the alphabet are skipped to keep
+	// every character equally likely
+	limit := 256 - 256%len(userCodeAlphabet)
+
+	code := make([]byte, 0, UserCodeLength)
+	b := make([]byte, 1)
+	for len(code) < UserCodeLength {
+		_, err := rand.Read(b)
+		if err != nil {
+			return "", err
+		}
+		if int(b[0]) >= limit {
+			continue
+		}
+		code = append(code, userCodeAlphabet[int(b[0])%len(userCodeAlphabet)])
+	}
+
+	return string(code), nil
+}
+
+// NormalizeUserCode undoes the formatting a user may type around a code.
+func NormalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return code
+}
+
+func FormatUserCode(code string) string {
+	if len(code) != UserCodeLength {
+		return code
+	}
+	return code[:UserCodeLength/2] + "-" + code[UserCodeLength/2:]
+}
@@ -0,0 +1,276 @@
+package tokens
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testHS256Key(t *testing.T, kid string) *Key {
+	t.Helper()
+	key, err := NewHS256Key(kid, bytes.Repeat([]byte(kid[:1]), minSecretLen))
+	if err != nil {
+		t.Fatalf("NewHS256Key: %v", err)
+	}
+	return key
+}
+
+func testEdDSAKey(t *testing.T, kid string) *Key {
+	t.Helper()
+	key, err := NewEdDSAKey(kid, bytes.Repeat([]byte(kid[:1]), 32))
+	if err != nil {
+		t.Fatalf("NewEdDSAKey: %v", err)
+	}
+	return key
+}
+
+func testKeys(t *testing.T, signingKid string, keys ...*Key) *Keys {
+	t.Helper()
+	result, err := NewKeys(signingKid, keys...)
+	if err != nil {
+		t.Fatalf("NewKeys: %v", err)
+	}
+	return result
+}
+
+func testClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		Issuer:    Issuer,
+		Subject:   uuid.New().String(),
+		Scope:     ScopeAuthenticate,
+		Role:      "user",
+		SessionId: uuid.New().String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(TtlToken).Unix(),
+		Id:        uuid.New().String(),
+	}
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hs256(secret []byte, signingInput string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *Key
+	}{
+		{"HS256", testHS256Key(t, "hs")},
+		{"EdDSA", testEdDSAKey(t, "ed")},
+	}
+
+	for _, v := range tests {
+		keys := testKeys(t, v.key.Id, v.key)
+		claims := testClaims()
+
+		signed, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", v.name, err)
+		}
+		result, err := keys.Verify(signed)
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", v.name, err)
+		}
+		if *result != *claims {
+			t.Errorf("%s: Verify = %+v, want %+v", v.name, result, claims)
+		}
+	}
+}
+
+func TestGenerateAccessToken(t *testing.T) {
+	keys := testKeys(t, "ed", testEdDSAKey(t, "ed"))
+	jti, userId, sessionId := uuid.New(), uuid.New(), uuid.New()
+
+	token, err := GenerateAccessToken(keys, jti, userId, sessionId, "moderator", TtlToken, ScopeAuthenticate)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	claims, err := keys.Verify(token.PlainText)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Id != jti.String() || claims.Subject != userId.String() || claims.SessionId != sessionId.String() || claims.Role != "moderator" {
+		t.Errorf("Verify = %+v", claims)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	for _, key := range []*Key{testHS256Key(t, "hs"), testEdDSAKey(t, "ed")} {
+		keys := testKeys(t, key.Id, key)
+		signed, err := keys.Sign(testClaims())
+		if err != nil {
+			t.Fatalf("%s: Sign: %v", key.Alg, err)
+		}
+		parts := strings.Split(signed, ".")
+
+		claims := testClaims()
+		claims.Role = "admin"
+		payload := parts[0] + "." + encodeSegment(t, claims) + "." + parts[2]
+		if _, err := keys.Verify(payload); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: tampered payload: Verify err = %v, want ErrInvalidToken", key.Alg, err)
+		}
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Fatalf("%s: DecodeString: %v", key.Alg, err)
+		}
+		signature[0] ^= 1
+		tampered := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+		if _, err := keys.Verify(tampered); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: tampered signature: Verify err = %v, want ErrInvalidToken", key.Alg, err)
+		}
+	}
+}
+
+func TestVerifyAlgMismatch(t *testing.T) {
+	hsKey := testHS256Key(t, "hs")
+	edKey := testEdDSAKey(t, "ed")
+	keys := testKeys(t, edKey.Id, hsKey, edKey)
+	claims := encodeSegment(t, testClaims())
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"none", func() string {
+			h := encodeSegment(t, &header{Alg: "none", Typ: "JWT", Kid: edKey.Id})
+			return h + "." + claims + "."
+		}},
+		{"none without kid", func() string {
+			h := encodeSegment(t, &header{Alg: "none", Typ: "JWT"})
+			return h + "." + claims + "."
+		}},
+		{"HS256 against EdDSA key", func() string {
+			// the public key is known to anyone, it must not work as a secret
+			h := encodeSegment(t, &header{Alg: AlgHS256, Typ: "JWT", Kid: edKey.Id})
+			return h + "." + claims + "." + hs256(edKey.publicKey, h+"."+claims)
+		}},
+		{"EdDSA against HS256 key", func() string {
+			h := encodeSegment(t, &header{Alg: AlgEdDSA, Typ: "JWT", Kid: hsKey.Id})
+			return h + "." + claims + "." + hs256(hsKey.secret, h+"."+claims)
+		}},
+	}
+
+	for _, v := range tests {
+		if _, err := keys.Verify(v.token()); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify err = %v, want ErrInvalidToken", v.name, err)
+		}
+	}
+}
+
+func TestVerifyKid(t *testing.T) {
+	oldKey := testHS256Key(t, "old")
+	newKey := testEdDSAKey(t, "new")
+
+	signed, err := testKeys(t, oldKey.Id, oldKey).Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// kept for verification after the signing key moved on
+	if _, err := testKeys(t, newKey.Id, oldKey, newKey).Verify(signed); err != nil {
+		t.Errorf("rotated key kept: Verify err = %v", err)
+	}
+
+	// rotated out
+	if _, err := testKeys(t, newKey.Id, newKey).Verify(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("rotated key dropped: Verify err = %v, want ErrInvalidToken", err)
+	}
+
+	// same secret under another kid
+	unknown := testHS256Key(t, "other")
+	unknown.secret = oldKey.secret
+	if _, err := testKeys(t, unknown.Id, unknown).Verify(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unknown kid: Verify err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	keys := testKeys(t, "hs", testHS256Key(t, "hs"))
+
+	expired := testClaims()
+	expired.IssuedAt = time.Now().Add(-2 * TtlToken).Unix()
+	expired.ExpiresAt = time.Now().Add(-TtlToken).Unix()
+	signed, err := keys.Sign(expired)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := keys.Verify(signed); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expired: Verify err = %v, want ErrExpiredToken", err)
+	}
+
+	missingExp := testClaims()
+	missingExp.ExpiresAt = 0
+	signed, err = keys.Sign(missingExp)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := keys.Verify(signed); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("missing exp: Verify err = %v, want ErrExpiredToken", err)
+	}
+
+	wrongIssuer := testClaims()
+	wrongIssuer.Issuer = "someone-else"
+	signed, err = keys.Sign(wrongIssuer)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := keys.Verify(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong issuer: Verify err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	key := testHS256Key(t, "hs")
+	keys := testKeys(t, key.Id, key)
+	signed, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parts := strings.Split(signed, ".")
+
+	h := encodeSegment(t, &header{Alg: AlgHS256, Typ: "JWT", Kid: key.Id})
+	badClaims := "!!!"
+	badHeader := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"one segment", parts[0]},
+		{"two segments", parts[0] + "." + parts[1]},
+		{"four segments", signed + "." + parts[2]},
+		{"header base64", "!!!." + parts[1] + "." + parts[2]},
+		{"header json", badHeader + "." + parts[1] + "." + hs256(key.secret, badHeader+"."+parts[1])},
+		{"claims base64", h + "." + badClaims + "." + hs256(key.secret, h+"."+badClaims)},
+		{"signature base64", parts[0] + "." + parts[1] + ".!!!"},
+		{"padded base64", parts[0] + "=." + parts[1] + "." + parts[2]},
+	}
+
+	for _, v := range tests {
+		if _, err := keys.Verify(v.token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify err = %v, want ErrInvalidToken", v.name, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+
+	// EnvKeys lists the keys as comma separated kid:alg:base64 entries, HS256
+	// takes the secret and EdDSA the ed25519 seed.
+	EnvKeys = "WHATDOING_JWT_KEYS"
+	// EnvSigningKid picks the key new tokens are signed with, the others are
+	// only kept to verify tokens signed before a rotation.
+	EnvSigningKid = "WHATDOING_JWT_KID"
+
+	minSecretLen = 32
+)
+
+var ErrUnknownKey = errors.New("unknown key")
+
+type Key struct {
+	Id         string
+	Alg        string
+	secret     []byte
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+type Keys struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+func NewHS256Key(kid string, secret []byte) (*Key, error) {
+	if len(secret) < minSecretLen {
+		return nil, fmt.Errorf("key %v: secret shorter than %d bytes", kid, minSecretLen)
+	}
+	return &Key{
+		Id:     kid,
+		Alg:    AlgHS256,
+		secret: secret,
+	}, nil
+}
+
+func NewEdDSAKey(kid string, seed []byte) (*Key, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("key %v: seed must be %d bytes", kid, ed25519.SeedSize)
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	return &Key{
+		Id:         kid,
+		Alg:        AlgEdDSA,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+func NewKeys(signingKid string, keys ...*Key) (*Keys, error) {
+	result := &Keys{
+		keys: make(map[string]*Key),
+	}
+	for _, v := range keys {
+		if _, ok := result.keys[v.Id]; ok {
+			return nil, fmt.Errorf("duplicate key id %v", v.Id)
+		}
+		result.keys[v.Id] = v
+	}
+
+	signing, ok := result.keys[signingKid]
+	if !ok {
+		return nil, fmt.Errorf("signing key %v: %w", signingKid, ErrUnknownKey)
+	}
+	result.signing = signing
+
+	return result, nil
+}
+
+// LoadKeys reads the keys from the environment. Without any configured keys a
+// random HS256 key is generated, so tokens do not survive a restart.
+func LoadKeys() (*Keys, error) {
+	env := strings.TrimSpace(os.Getenv(EnvKeys))
+	if env == "" {
+		log.Printf("warning: Tokens: LoadKeys: %v not set, using a random key", EnvKeys)
+		secret := make([]byte, minSecretLen)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		key, err := NewHS256Key("dev", secret)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeys(key.Id, key)
+	}
+
+	keys := make([]*Key, 0)
+	for _, entry := range strings.Split(env, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("%v: malformed entry, expected kid:alg:base64", EnvKeys)
+		}
+
+		material, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%v: key %v: %w", EnvKeys, parts[0], err)
+		}
+
+		var key *Key
+		switch parts[1] {
+		case AlgHS256:
+			key, err = NewHS256Key(parts[0], material)
+		case AlgEdDSA:
+			key, err = NewEdDSAKey(parts[0], material)
+		default:
+			err = fmt.Errorf("key %v: unsupported alg %v", parts[0], parts[1])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", EnvKeys, err)
+		}
+		keys = append(keys, key)
+	}
+
+	signingKid := os.Getenv(EnvSigningKid)
+	if signingKid == "" {
+		signingKid = keys[0].Id
+	}
+
+	return NewKeys(signingKid, keys...)
+}
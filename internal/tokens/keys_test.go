@@ -0,0 +1,100 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestLoadKeys(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("s"), minSecretLen))
+	seed := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("e"), 32))
+
+	t.Setenv(EnvKeys, "old:HS256:"+secret+", new:EdDSA:"+seed)
+	t.Setenv(EnvSigningKid, "new")
+
+	keys, err := LoadKeys()
+	if err != nil {
+		t.Fatalf("LoadKeys: %v", err)
+	}
+	if keys.signing.Id != "new" || keys.signing.Alg != AlgEdDSA {
+		t.Errorf("LoadKeys signing key = %v %v, want new EdDSA", keys.signing.Id, keys.signing.Alg)
+	}
+	if old, ok := keys.keys["old"]; !ok || old.Alg != AlgHS256 {
+		t.Errorf("LoadKeys did not keep old HS256 key")
+	}
+
+	signed, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := keys.Verify(signed); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	t.Setenv(EnvSigningKid, "")
+	keys, err = LoadKeys()
+	if err != nil {
+		t.Fatalf("LoadKeys without kid: %v", err)
+	}
+	if keys.signing.Id != "old" {
+		t.Errorf("LoadKeys without kid signing key = %v, want the first entry", keys.signing.Id)
+	}
+}
+
+func TestLoadKeysRandom(t *testing.T) {
+	t.Setenv(EnvKeys, "")
+
+	keys, err := LoadKeys()
+	if err != nil {
+		t.Fatalf("LoadKeys: %v", err)
+	}
+	signed, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := keys.Verify(signed); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	other, err := LoadKeys()
+	if err != nil {
+		t.Fatalf("LoadKeys: %v", err)
+	}
+	if _, err := other.Verify(signed); err == nil {
+		t.Errorf("a second random key verified the first one's token")
+	}
+}
+
+func TestLoadKeysInvalid(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("s"), minSecretLen))
+	short := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("s"), minSecretLen-1))
+	seed := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("e"), 32))
+	badSeed := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("e"), 31))
+
+	tests := []struct {
+		name string
+		keys string
+		kid  string
+	}{
+		{"missing part", "a:HS256", ""},
+		{"extra part", "a:HS256:" + secret + ":x", ""},
+		{"empty kid", ":HS256:" + secret, ""},
+		{"bad base64", "a:HS256:!!!", ""},
+		{"unsupported alg", "a:RS256:" + secret, ""},
+		{"none alg", "a:none:" + secret, ""},
+		{"short secret", "a:HS256:" + short, ""},
+		{"bad seed", "a:EdDSA:" + badSeed, ""},
+		{"duplicate kid", "a:HS256:" + secret + ",a:EdDSA:" + seed, ""},
+		{"unknown signing kid", "a:HS256:" + secret, "b"},
+	}
+
+	for _, v := range tests {
+		t.Setenv(EnvKeys, v.keys)
+		t.Setenv(EnvSigningKid, v.kid)
+		if _, err := LoadKeys(); err == nil {
+			t.Errorf("%s: LoadKeys(%q) succeeded", v.name, strings.TrimSpace(v.keys))
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package tokens
+
+import (
+	"strings"
+)
+
+// Scopes a personal access token can be granted. Session tokens carry
+// ScopeAuthenticate instead, which passes every scope check.
+const (
+	ScopeCatalogRead   = "catalog:read"
+	ScopeCatalogWrite  = "catalog:write"
+	ScopeProgressRead  = "progress:read"
+	ScopeProgressWrite = "progress:write"
+	ScopeListsRead     = "lists:read"
+	ScopeListsWrite    = "lists:write"
+)
+
+var grantableScopes = map[string]bool{
+	ScopeCatalogRead:   true,
+	ScopeCatalogWrite:  true,
+	ScopeProgressRead:  true,
+	ScopeProgressWrite: true,
+	ScopeListsRead:     true,
+	ScopeListsWrite:    true,
+}
+
+func ValidScope(scope string) bool {
+	return grantableScopes[scope]
+}
+
+// HasScope reports whether the space separated scopes grant want. Only a
+// session grants ScopeAuthenticate, which account management is limited to.
+func HasScope(scopes, want string) bool {
+	for _, v := range strings.Fields(scopes) {
+		if v == ScopeAuthenticate || v == want {
+			return true
+		}
+	}
+	return false
+}
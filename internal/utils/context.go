@@ -5,11 +5,13 @@ import (
 	"net/http"
 
 	"github.com/JustinLi007/whatdoing-server/internal/database"
+	"github.com/JustinLi007/whatdoing-server/internal/tokens"
 )
 
 type ContextKey string
 
 const UserKey = ContextKey("user")
+const ClaimsKey = ContextKey("claims")
 
 func SetUser(r *http.Request, user *database.User) *http.Request {
 	ctx := context.WithValue(r.Context(), UserKey, user)
@@ -23,3 +25,16 @@ func GetUser(r *http.Request) *database.User {
 	}
 	return user
 }
+
+func SetClaims(r *http.Request, claims *tokens.Claims) *http.Request {
+	ctx := context.WithValue(r.Context(), ClaimsKey, claims)
+	return r.WithContext(ctx)
+}
+
+func GetClaims(r *http.Request) *tokens.Claims {
+	claims, ok := r.Context().Value(ClaimsKey).(*tokens.Claims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE anime RENAME TO content;
+ALTER TABLE content RENAME COLUMN anime_names_id TO content_names_id;
+ALTER TABLE content RENAME CONSTRAINT fk_anime_names_id TO fk_content_names_id;
+ALTER TABLE content ADD CONSTRAINT valid_kind CHECK ( kind IN ('anime', 'book', 'game', 'show', 'movie', 'manga') );
+CREATE INDEX IF NOT EXISTS idx_content_kind ON content (kind);
+
+ALTER TABLE anime_names RENAME TO content_names;
+
+ALTER TABLE rel_anime_anime_names RENAME TO rel_content_content_names;
+ALTER TABLE rel_content_content_names RENAME COLUMN anime_id TO content_id;
+ALTER TABLE rel_content_content_names RENAME COLUMN anime_names_id TO content_names_id;
+ALTER TABLE rel_content_content_names RENAME CONSTRAINT fk_anime_id TO fk_content_id;
+ALTER TABLE rel_content_content_names RENAME CONSTRAINT fk_anime_names_id TO fk_content_names_id;
+
+ALTER TABLE rel_users_anime RENAME TO rel_users_content;
+ALTER TABLE rel_users_content RENAME COLUMN anime_id TO content_id;
+ALTER TABLE rel_users_content RENAME CONSTRAINT fk_anime_id TO fk_content_id;
+
+ALTER TABLE progress_anime RENAME TO progress_content;
+ALTER TABLE progress_content RENAME COLUMN anime_id TO content_id;
+ALTER TABLE progress_content RENAME CONSTRAINT fk_anime_id TO fk_content_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE progress_content RENAME CONSTRAINT fk_content_id TO fk_anime_id;
+ALTER TABLE progress_content RENAME COLUMN content_id TO anime_id;
+ALTER TABLE progress_content RENAME TO progress_anime;
+
+ALTER TABLE rel_users_content RENAME CONSTRAINT fk_content_id TO fk_anime_id;
+ALTER TABLE rel_users_content RENAME COLUMN content_id TO anime_id;
+ALTER TABLE rel_users_content RENAME TO rel_users_anime;
+
+ALTER TABLE rel_content_content_names RENAME CONSTRAINT fk_content_names_id TO fk_anime_names_id;
+ALTER TABLE rel_content_content_names RENAME CONSTRAINT fk_content_id TO fk_anime_id;
+ALTER TABLE rel_content_content_names RENAME COLUMN content_names_id TO anime_names_id;
+ALTER TABLE rel_content_content_names RENAME COLUMN content_id TO anime_id;
+ALTER TABLE rel_content_content_names RENAME TO rel_anime_anime_names;
+
+ALTER TABLE content_names RENAME TO anime_names;
+
+DROP INDEX IF EXISTS idx_content_kind;
+ALTER TABLE content DROP CONSTRAINT valid_kind;
+ALTER TABLE content RENAME CONSTRAINT fk_content_names_id TO fk_anime_names_id;
+ALTER TABLE content RENAME COLUMN content_names_id TO anime_names_id;
+ALTER TABLE content RENAME TO anime;
+-- +goose StatementEnd
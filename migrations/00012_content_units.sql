@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE content RENAME COLUMN episodes TO total;
+ALTER TABLE content ADD COLUMN unit TEXT NOT NULL DEFAULT 'episodes';
+ALTER TABLE content ADD COLUMN seasons INT DEFAULT NULL;
+ALTER TABLE content ADD CONSTRAINT valid_unit CHECK ( unit IN ('episodes', 'pages', 'chapters', 'hours', 'percent', 'minutes') );
+ALTER TABLE content ADD CONSTRAINT total_gt_zero CHECK ( total IS NULL OR total >= 0 );
+ALTER TABLE content ADD CONSTRAINT seasons_gt_zero CHECK ( seasons IS NULL OR seasons > 0 );
+
+ALTER TABLE progress_content RENAME COLUMN episode TO value;
+ALTER TABLE progress_content ADD COLUMN season INT DEFAULT NULL;
+ALTER TABLE progress_content ADD CONSTRAINT season_gt_zero CHECK ( season IS NULL OR season > 0 );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE progress_content DROP CONSTRAINT season_gt_zero;
+ALTER TABLE progress_content DROP COLUMN season;
+ALTER TABLE progress_content RENAME COLUMN value TO episode;
+
+ALTER TABLE content DROP CONSTRAINT seasons_gt_zero;
+ALTER TABLE content DROP CONSTRAINT total_gt_zero;
+ALTER TABLE content DROP CONSTRAINT valid_unit;
+ALTER TABLE content DROP COLUMN seasons;
+ALTER TABLE content DROP COLUMN unit;
+ALTER TABLE content RENAME COLUMN total TO episodes;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE progress_content ADD COLUMN status TEXT DEFAULT NULL;
+ALTER TABLE progress_content ADD CONSTRAINT valid_status CHECK ( status IS NULL OR status IN ('planned', 'watching', 'completed', 'on-hold', 'dropped', 'rewatching') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE progress_content DROP CONSTRAINT valid_status;
+ALTER TABLE progress_content DROP COLUMN status;
+-- +goose StatementEnd
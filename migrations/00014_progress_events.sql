@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS progress_events (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  action TEXT NOT NULL,
+  CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'removed') ),
+  before JSONB DEFAULT NULL,
+  after JSONB DEFAULT NULL,
+  progress_id UUID NOT NULL,
+  content_id UUID NOT NULL,
+  CONSTRAINT fk_content_id FOREIGN KEY (content_id) REFERENCES content (id) ON DELETE CASCADE,
+  user_id UUID NOT NULL,
+  CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_progress_events_progress_id ON progress_events (progress_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_progress_events_user_id ON progress_events (user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE progress_events;
+-- +goose StatementEnd
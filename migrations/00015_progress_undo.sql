@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE progress_events ADD COLUMN reverts_event_id UUID DEFAULT NULL;
+ALTER TABLE progress_events ADD CONSTRAINT fk_reverts_event_id FOREIGN KEY (reverts_event_id) REFERENCES progress_events (id) ON DELETE CASCADE;
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'removed', 'undo') );
+CREATE UNIQUE INDEX IF NOT EXISTS idx_progress_events_reverts_event_id ON progress_events (reverts_event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_progress_events_reverts_event_id;
+DELETE FROM progress_events WHERE action = 'undo';
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'removed') );
+ALTER TABLE progress_events DROP CONSTRAINT fk_reverts_event_id;
+ALTER TABLE progress_events DROP COLUMN reverts_event_id;
+-- +goose StatementEnd
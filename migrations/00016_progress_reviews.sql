@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN score_scale INT NOT NULL DEFAULT 10;
+ALTER TABLE users ADD CONSTRAINT valid_score_scale CHECK ( score_scale IN (10, 100) );
+ALTER TABLE progress_content ADD COLUMN score INT DEFAULT NULL;
+ALTER TABLE progress_content ADD CONSTRAINT valid_score CHECK ( score IS NULL OR (score >= 1 AND score <= 100) );
+ALTER TABLE progress_content ADD COLUMN review TEXT DEFAULT NULL;
+ALTER TABLE progress_content ADD COLUMN review_public BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE progress_content ADD COLUMN notes TEXT DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS idx_progress_content_public_reviews ON progress_content (content_id) WHERE review_public;
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'review', 'removed', 'undo') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM progress_events WHERE action = 'review';
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'removed', 'undo') );
+DROP INDEX IF EXISTS idx_progress_content_public_reviews;
+ALTER TABLE progress_content DROP COLUMN notes;
+ALTER TABLE progress_content DROP COLUMN review_public;
+ALTER TABLE progress_content DROP COLUMN review;
+ALTER TABLE progress_content DROP CONSTRAINT valid_score;
+ALTER TABLE progress_content DROP COLUMN score;
+ALTER TABLE users DROP CONSTRAINT valid_score_scale;
+ALTER TABLE users DROP COLUMN score_scale;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE progress_content ADD COLUMN started_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+ALTER TABLE progress_content ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+ALTER TABLE progress_content ADD CONSTRAINT valid_dates CHECK ( started_at IS NULL OR completed_at IS NULL OR started_at <= completed_at );
+ALTER TABLE progress_content ADD COLUMN repeat_count INT NOT NULL DEFAULT 0;
+ALTER TABLE progress_content ADD CONSTRAINT valid_repeat_count CHECK ( repeat_count >= 0 );
+UPDATE progress_content p
+SET started_at = p.updated_at
+WHERE p.value > 0;
+UPDATE progress_content p
+SET completed_at = p.updated_at
+FROM content c
+WHERE p.content_id = c.id
+AND ((p.status IS NULL AND c.total IS NOT NULL AND p.value >= c.total) OR p.status = 'completed');
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'review', 'dates', 'removed', 'undo') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM progress_events WHERE action = 'dates';
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'review', 'removed', 'undo') );
+ALTER TABLE progress_content DROP CONSTRAINT valid_repeat_count;
+ALTER TABLE progress_content DROP COLUMN repeat_count;
+ALTER TABLE progress_content DROP CONSTRAINT valid_dates;
+ALTER TABLE progress_content DROP COLUMN completed_at;
+ALTER TABLE progress_content DROP COLUMN started_at;
+-- +goose StatementEnd
@@ -0,0 +1,85 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS genres (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  name TEXT UNIQUE NOT NULL
+);
+
+INSERT INTO genres (id, name) VALUES
+  (gen_random_uuid(), 'Action'),
+  (gen_random_uuid(), 'Adventure'),
+  (gen_random_uuid(), 'Comedy'),
+  (gen_random_uuid(), 'Drama'),
+  (gen_random_uuid(), 'Fantasy'),
+  (gen_random_uuid(), 'Horror'),
+  (gen_random_uuid(), 'Mystery'),
+  (gen_random_uuid(), 'Romance'),
+  (gen_random_uuid(), 'Sci-Fi'),
+  (gen_random_uuid(), 'Slice of Life'),
+  (gen_random_uuid(), 'Sports'),
+  (gen_random_uuid(), 'Thriller'),
+  (gen_random_uuid(), 'Documentary'),
+  (gen_random_uuid(), 'Non-Fiction'),
+  (gen_random_uuid(), 'Strategy'),
+  (gen_random_uuid(), 'Puzzle'),
+  (gen_random_uuid(), 'RPG');
+
+CREATE TABLE IF NOT EXISTS rel_content_genres (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  content_id UUID NOT NULL,
+  CONSTRAINT fk_content_id
+  FOREIGN KEY (content_id)
+  REFERENCES content (id)
+  ON DELETE CASCADE,
+  genres_id UUID NOT NULL,
+  CONSTRAINT fk_genres_id
+  FOREIGN KEY (genres_id)
+  REFERENCES genres (id)
+  ON DELETE CASCADE,
+  UNIQUE(content_id, genres_id)
+);
+CREATE INDEX IF NOT EXISTS idx_rel_content_genres_genres_id ON rel_content_genres (genres_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  name TEXT NOT NULL,
+  user_id UUID NOT NULL,
+  CONSTRAINT fk_user_id
+  FOREIGN KEY (user_id)
+  REFERENCES users (id)
+  ON DELETE CASCADE,
+  UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS rel_content_tags (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  content_id UUID NOT NULL,
+  CONSTRAINT fk_content_id
+  FOREIGN KEY (content_id)
+  REFERENCES content (id)
+  ON DELETE CASCADE,
+  tags_id UUID NOT NULL,
+  CONSTRAINT fk_tags_id
+  FOREIGN KEY (tags_id)
+  REFERENCES tags (id)
+  ON DELETE CASCADE,
+  UNIQUE(content_id, tags_id)
+);
+CREATE INDEX IF NOT EXISTS idx_rel_content_tags_tags_id ON rel_content_tags (tags_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE rel_content_tags;
+DROP TABLE tags;
+DROP TABLE rel_content_genres;
+DROP TABLE genres;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS lists (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  name TEXT NOT NULL,
+  description TEXT DEFAULT NULL,
+  public BOOLEAN NOT NULL DEFAULT FALSE,
+  user_id UUID NOT NULL,
+  CONSTRAINT fk_user_id
+  FOREIGN KEY (user_id)
+  REFERENCES users (id)
+  ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_lists_user_id ON lists (user_id);
+
+CREATE TABLE IF NOT EXISTS list_items (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  rank TEXT COLLATE "C" NOT NULL,
+  list_id UUID NOT NULL,
+  CONSTRAINT fk_list_id
+  FOREIGN KEY (list_id)
+  REFERENCES lists (id)
+  ON DELETE CASCADE,
+  content_id UUID NOT NULL,
+  CONSTRAINT fk_content_id
+  FOREIGN KEY (content_id)
+  REFERENCES content (id)
+  ON DELETE CASCADE,
+  UNIQUE(list_id, content_id),
+  UNIQUE(list_id, rank)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE list_items;
+DROP TABLE lists;
+-- +goose StatementEnd
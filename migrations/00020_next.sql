@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE content ADD COLUMN unit_minutes INT DEFAULT NULL;
+ALTER TABLE content ADD CONSTRAINT valid_unit_minutes CHECK ( unit_minutes IS NULL OR unit_minutes > 0 );
+ALTER TABLE progress_content ADD COLUMN priority INT NOT NULL DEFAULT 0;
+ALTER TABLE progress_content ADD CONSTRAINT valid_priority CHECK ( priority >= 0 AND priority <= 3 );
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'review', 'dates', 'priority', 'removed', 'undo') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM progress_events WHERE action = 'priority';
+ALTER TABLE progress_events DROP CONSTRAINT valid_action;
+ALTER TABLE progress_events ADD CONSTRAINT valid_action CHECK ( action IN ('added', 'progress', 'status', 'review', 'dates', 'removed', 'undo') );
+ALTER TABLE progress_content DROP CONSTRAINT valid_priority;
+ALTER TABLE progress_content DROP COLUMN priority;
+ALTER TABLE content DROP CONSTRAINT valid_unit_minutes;
+ALTER TABLE content DROP COLUMN unit_minutes;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+DELETE FROM jwt;
+ALTER TABLE jwt DROP COLUMN token;
+ALTER TABLE jwt ADD COLUMN jti UUID NOT NULL UNIQUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM jwt;
+ALTER TABLE jwt DROP COLUMN jti;
+ALTER TABLE jwt ADD COLUMN token BYTEA NOT NULL UNIQUE;
+-- +goose StatementEnd
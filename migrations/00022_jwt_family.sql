@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE jwt ADD COLUMN family_id UUID;
+UPDATE jwt SET family_id = id;
+ALTER TABLE jwt ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE jwt ADD COLUMN rotated_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS idx_jwt_family_id ON jwt (family_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM jwt WHERE rotated_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_jwt_family_id;
+ALTER TABLE jwt DROP COLUMN rotated_at;
+ALTER TABLE jwt DROP COLUMN family_id;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE jwt ADD COLUMN device_name TEXT DEFAULT NULL;
+ALTER TABLE jwt ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE jwt ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE jwt ADD COLUMN signed_in_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE jwt ADD COLUMN last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_jwt_user_id ON jwt (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_jwt_user_id;
+ALTER TABLE jwt DROP COLUMN last_used_at;
+ALTER TABLE jwt DROP COLUMN signed_in_at;
+ALTER TABLE jwt DROP COLUMN ip_address;
+ALTER TABLE jwt DROP COLUMN user_agent;
+ALTER TABLE jwt DROP COLUMN device_name;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS personal_tokens (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  name TEXT NOT NULL,
+  token_hash BYTEA NOT NULL UNIQUE,
+  scopes TEXT NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  last_used_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  user_id UUID NOT NULL,
+  CONSTRAINT fk_user_id
+  FOREIGN KEY (user_id)
+  REFERENCES users (id)
+  ON DELETE CASCADE,
+  UNIQUE(user_id, name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE personal_tokens;
+-- +goose StatementEnd
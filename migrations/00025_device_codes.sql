@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS device_codes (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  device_code BYTEA NOT NULL UNIQUE,
+  user_code TEXT NOT NULL UNIQUE,
+  client_name TEXT DEFAULT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  CONSTRAINT valid_status CHECK ( status IN ('pending', 'approved', 'denied') ),
+  poll_interval INT NOT NULL,
+  last_polled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  user_id UUID DEFAULT NULL,
+  CONSTRAINT fk_user_id
+  FOREIGN KEY (user_id)
+  REFERENCES users (id)
+  ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE device_codes;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE users SET role = 'regular' WHERE role NOT IN ('regular', 'moderator', 'admin');
+ALTER TABLE users ADD CONSTRAINT valid_role CHECK ( role IN ('regular', 'moderator', 'admin') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT valid_role;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+DELETE FROM rel_users_content a
+USING rel_users_content b
+WHERE a.content_id = b.content_id
+AND (a.created_at, a.id) > (b.created_at, b.id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rel_users_content_content_id ON rel_users_content (content_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_rel_users_content_content_id;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS content_revisions (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  action TEXT NOT NULL,
+  CONSTRAINT valid_action CHECK ( action IN ('created', 'updated', 'alt_names', 'reverted') ),
+  before JSONB DEFAULT NULL,
+  after JSONB NOT NULL,
+  reverts_revision_id UUID DEFAULT NULL,
+  CONSTRAINT fk_reverts_revision_id FOREIGN KEY (reverts_revision_id) REFERENCES content_revisions (id) ON DELETE SET NULL,
+  content_id UUID NOT NULL,
+  CONSTRAINT fk_content_id FOREIGN KEY (content_id) REFERENCES content (id) ON DELETE CASCADE,
+  user_id UUID DEFAULT NULL,
+  CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_content_revisions_content_id ON content_revisions (content_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE content_revisions;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE content ADD COLUMN status TEXT NOT NULL DEFAULT 'approved';
+ALTER TABLE content ADD CONSTRAINT valid_status CHECK ( status IN ('pending', 'approved', 'rejected', 'merged') );
+ALTER TABLE content ADD COLUMN review_reason TEXT DEFAULT NULL;
+ALTER TABLE content ADD COLUMN reviewed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+ALTER TABLE content ADD COLUMN reviewed_by UUID DEFAULT NULL;
+ALTER TABLE content ADD CONSTRAINT fk_reviewed_by FOREIGN KEY (reviewed_by) REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE content ADD COLUMN merged_into_id UUID DEFAULT NULL;
+ALTER TABLE content ADD CONSTRAINT fk_merged_into_id FOREIGN KEY (merged_into_id) REFERENCES content (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_content_pending ON content (created_at) WHERE status = 'pending';
+
+ALTER TABLE content_revisions DROP CONSTRAINT valid_action;
+ALTER TABLE content_revisions ADD CONSTRAINT valid_action CHECK ( action IN ('created', 'updated', 'alt_names', 'reverted', 'merged') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM content_revisions WHERE action = 'merged';
+ALTER TABLE content_revisions DROP CONSTRAINT valid_action;
+ALTER TABLE content_revisions ADD CONSTRAINT valid_action CHECK ( action IN ('created', 'updated', 'alt_names', 'reverted') );
+
+DELETE FROM content WHERE status <> 'approved';
+DROP INDEX IF EXISTS idx_content_pending;
+ALTER TABLE content DROP CONSTRAINT fk_merged_into_id;
+ALTER TABLE content DROP COLUMN merged_into_id;
+ALTER TABLE content DROP CONSTRAINT fk_reviewed_by;
+ALTER TABLE content DROP COLUMN reviewed_by;
+ALTER TABLE content DROP COLUMN reviewed_at;
+ALTER TABLE content DROP COLUMN review_reason;
+ALTER TABLE content DROP CONSTRAINT valid_status;
+ALTER TABLE content DROP COLUMN status;
+-- +goose StatementEnd
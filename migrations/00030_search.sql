@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS fuzzystrmatch;
+
+ALTER TABLE content_names ADD COLUMN name_tsv TSVECTOR GENERATED ALWAYS AS ( to_tsvector('simple', name) ) STORED;
+CREATE INDEX IF NOT EXISTS idx_content_names_name_trgm ON content_names USING GIN (lower(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_content_names_name_length ON content_names (char_length(name));
+CREATE INDEX IF NOT EXISTS idx_content_names_name_tsv ON content_names USING GIN (name_tsv);
+CREATE INDEX IF NOT EXISTS idx_rel_content_content_names_content_names_id ON rel_content_content_names (content_names_id);
+
+-- search_content matches content whose primary or alternative name contains
+-- query or is within 2 edits of it, ignoring case. score is the best trigram
+-- similarity or full text rank over the matching names.
+CREATE OR REPLACE FUNCTION search_content(query TEXT)
+RETURNS TABLE (content_id UUID, score DOUBLE PRECISION)
+LANGUAGE sql STABLE STRICT
+AS $$
+  WITH search_names AS (
+    SELECT n.id, GREATEST(
+      similarity(lower(n.name), lower(query)),
+      ts_rank(n.name_tsv, plainto_tsquery('simple', query))
+    )::float8 AS score
+    FROM content_names n
+    WHERE lower(n.name) LIKE '%' || replace(replace(replace(lower(query), '\', '\\'), '%', '\%'), '_', '\_') || '%'
+    OR (
+      char_length(n.name) BETWEEN char_length(query) - 2 AND char_length(query) + 2
+      AND CASE
+        WHEN char_length(query) <= 253 THEN levenshtein_less_equal(lower(n.name), lower(query), 2)
+        ELSE 3
+      END <= 2
+    )
+  )
+  SELECT hit.content_id, MAX(hit.score)
+  FROM (
+    SELECT c.id AS content_id, s.score
+    FROM content c
+    JOIN search_names s ON c.content_names_id = s.id
+    UNION ALL
+    SELECT alt.content_id, s.score
+    FROM rel_content_content_names alt
+    JOIN search_names s ON alt.content_names_id = s.id
+  ) hit
+  GROUP BY hit.content_id
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION IF EXISTS search_content(TEXT);
+DROP INDEX IF EXISTS idx_rel_content_content_names_content_names_id;
+DROP INDEX IF EXISTS idx_content_names_name_tsv;
+DROP INDEX IF EXISTS idx_content_names_name_length;
+DROP INDEX IF EXISTS idx_content_names_name_trgm;
+ALTER TABLE content_names DROP COLUMN name_tsv;
+-- +goose StatementEnd
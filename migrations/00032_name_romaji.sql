@@ -0,0 +1,112 @@
+-- +goose Up
+-- +goose StatementBegin
+-- romaji holds algo.Romanize(search_name) and script algo.Script(name), both
+-- filled in by the server like search_name.
+ALTER TABLE content_names ADD COLUMN romaji TEXT;
+ALTER TABLE content_names ADD COLUMN script TEXT;
+ALTER TABLE rel_content_content_names ADD COLUMN language TEXT;
+
+DROP INDEX IF EXISTS idx_content_names_search_name_null;
+CREATE INDEX IF NOT EXISTS idx_content_names_unfolded ON content_names (id) WHERE search_name IS NULL OR romaji IS NULL;
+CREATE INDEX IF NOT EXISTS idx_content_names_romaji_trgm ON content_names USING GIN (romaji gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_content_names_romaji_length ON content_names (char_length(romaji));
+
+DROP FUNCTION IF EXISTS search_content(TEXT);
+
+-- search_content matches content whose primary or alternative name contains
+-- query or is within 2 edits of it, or whose romanized name does the same
+-- for romaji_query. query must already be folded with algo.Fold and
+-- romaji_query romanized from it with algo.Romanize. score is the best
+-- trigram similarity or full text rank over the matching names.
+CREATE OR REPLACE FUNCTION search_content(query TEXT, romaji_query TEXT)
+RETURNS TABLE (content_id UUID, score DOUBLE PRECISION)
+LANGUAGE sql STABLE STRICT
+AS $$
+  WITH search_names AS (
+    SELECT n.id, GREATEST(
+      similarity(n.search_name, query),
+      similarity(n.romaji, romaji_query),
+      ts_rank(n.name_tsv, plainto_tsquery('simple', query))
+    )::float8 AS score
+    FROM content_names n
+    WHERE n.search_name LIKE '%' || replace(replace(replace(query, '\', '\\'), '%', '\%'), '_', '\_') || '%'
+    OR (
+      char_length(n.search_name) BETWEEN char_length(query) - 2 AND char_length(query) + 2
+      AND CASE
+        WHEN char_length(query) <= 253 THEN levenshtein_less_equal(n.search_name, query, 2)
+        ELSE 3
+      END <= 2
+    )
+    OR (
+      romaji_query <> ''
+      AND (
+        n.romaji LIKE '%' || replace(replace(replace(romaji_query, '\', '\\'), '%', '\%'), '_', '\_') || '%'
+        OR (
+          char_length(n.romaji) BETWEEN char_length(romaji_query) - 2 AND char_length(romaji_query) + 2
+          AND CASE
+            WHEN char_length(romaji_query) <= 253 THEN levenshtein_less_equal(n.romaji, romaji_query, 2)
+            ELSE 3
+          END <= 2
+        )
+      )
+    )
+  )
+  SELECT hit.content_id, MAX(hit.score)
+  FROM (
+    SELECT c.id AS content_id, s.score
+    FROM content c
+    JOIN search_names s ON c.content_names_id = s.id
+    UNION ALL
+    SELECT alt.content_id, s.score
+    FROM rel_content_content_names alt
+    JOIN search_names s ON alt.content_names_id = s.id
+  ) hit
+  GROUP BY hit.content_id
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION IF EXISTS search_content(TEXT, TEXT);
+
+CREATE OR REPLACE FUNCTION search_content(query TEXT)
+RETURNS TABLE (content_id UUID, score DOUBLE PRECISION)
+LANGUAGE sql STABLE STRICT
+AS $$
+  WITH search_names AS (
+    SELECT n.id, GREATEST(
+      similarity(n.search_name, query),
+      ts_rank(n.name_tsv, plainto_tsquery('simple', query))
+    )::float8 AS score
+    FROM content_names n
+    WHERE n.search_name LIKE '%' || replace(replace(replace(query, '\', '\\'), '%', '\%'), '_', '\_') || '%'
+    OR (
+      char_length(n.search_name) BETWEEN char_length(query) - 2 AND char_length(query) + 2
+      AND CASE
+        WHEN char_length(query) <= 253 THEN levenshtein_less_equal(n.search_name, query, 2)
+        ELSE 3
+      END <= 2
+    )
+  )
+  SELECT hit.content_id, MAX(hit.score)
+  FROM (
+    SELECT c.id AS content_id, s.score
+    FROM content c
+    JOIN search_names s ON c.content_names_id = s.id
+    UNION ALL
+    SELECT alt.content_id, s.score
+    FROM rel_content_content_names alt
+    JOIN search_names s ON alt.content_names_id = s.id
+  ) hit
+  GROUP BY hit.content_id
+$$;
+
+DROP INDEX IF EXISTS idx_content_names_romaji_length;
+DROP INDEX IF EXISTS idx_content_names_romaji_trgm;
+DROP INDEX IF EXISTS idx_content_names_unfolded;
+CREATE INDEX IF NOT EXISTS idx_content_names_search_name_null ON content_names (id) WHERE search_name IS NULL;
+
+ALTER TABLE rel_content_content_names DROP COLUMN language;
+ALTER TABLE content_names DROP COLUMN script;
+ALTER TABLE content_names DROP COLUMN romaji;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- content_id no longer references content, so the history of a deleted entry
+-- outlives it. The revision that deleted an entry has no after.
+ALTER TABLE content_revisions DROP CONSTRAINT fk_content_id;
+ALTER TABLE content_revisions ALTER COLUMN after DROP NOT NULL;
+ALTER TABLE content_revisions DROP CONSTRAINT valid_action;
+ALTER TABLE content_revisions ADD CONSTRAINT valid_action CHECK ( action IN ('created', 'updated', 'alt_names', 'reverted', 'merged', 'deleted') );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM content_revisions WHERE action = 'deleted' OR content_id NOT IN (SELECT id FROM content);
+ALTER TABLE content_revisions DROP CONSTRAINT valid_action;
+ALTER TABLE content_revisions ADD CONSTRAINT valid_action CHECK ( action IN ('created', 'updated', 'alt_names', 'reverted', 'merged') );
+ALTER TABLE content_revisions ALTER COLUMN after SET NOT NULL;
+ALTER TABLE content_revisions ADD CONSTRAINT fk_content_id FOREIGN KEY (content_id) REFERENCES content (id) ON DELETE CASCADE;
+-- +goose StatementEnd
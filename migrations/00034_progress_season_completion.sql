@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- shows count episodes per season, so entries stamped completed before their
+-- last season lose the stamp.
+UPDATE progress_content p
+SET completed_at = NULL
+FROM content c
+WHERE p.content_id = c.id
+AND p.status IS NULL
+AND p.completed_at IS NOT NULL
+AND c.seasons IS NOT NULL
+AND COALESCE(p.season, 1) < c.seasons;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 1;
+-- +goose StatementEnd